@@ -0,0 +1,274 @@
+package amm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultLevelBps = 10
+	defaultLevels   = 50
+)
+
+var ErrInsufficientLiquidity = errors.New("not enough liquidity across venues")
+
+// BookLevel is liquidity available at a price, in UI amounts of the base token priced in the quote token
+type BookLevel struct {
+	// Venue is the OpenBook market or the address of the pool the level is on
+	Venue string
+	Price float64
+	Size  float64
+}
+
+// VenueFill is the part of an execution taken from one venue
+type VenueFill struct {
+	Venue string
+	Size  float64
+	Cost  float64
+}
+
+// AveragePrice is the quote paid or received per base token on the venue
+func (f VenueFill) AveragePrice() float64 {
+	if f.Size == 0 {
+		return 0
+	}
+	return f.Cost / f.Size
+}
+
+// BookExecution is the best way to take size from a side of the consolidated book, split across venues
+type BookExecution struct {
+	Side pb.Side
+	Size float64
+	Cost float64
+
+	// Fills are ordered by size, largest first
+	Fills []VenueFill
+}
+
+// AveragePrice is the quote paid or received per base token across venues
+func (e BookExecution) AveragePrice() float64 {
+	if e.Size == 0 {
+		return 0
+	}
+	return e.Cost / e.Size
+}
+
+type ConsolidatedBookOpts struct {
+	// Market is the OpenBook market as named in GetMarketDepthsStream responses
+	Market string
+
+	// Base and Quote are the mints or symbols of the pair, used to orient the pools
+	Base  string
+	Quote string
+
+	// Pools are the addresses of the engine's pools trading the pair
+	Pools []string
+
+	// LevelBps is the price step between the synthetic levels of a pool. Defaults to 10.
+	LevelBps float64
+
+	// Levels is the number of synthetic levels per pool and side. Defaults to 50.
+	Levels int
+}
+
+// ConsolidatedBook combines an OpenBook market's depth with the liquidity of Raydium pools trading the same pair.
+// Each pool is expressed as synthetic levels: the base amount that moves the pool's price by one more LevelBps step,
+// at the average price of that move including the pool's fee. Pool reserves are read from the QuoteEngine, so it
+// should follow GetPoolReservesStream.
+type ConsolidatedBook struct {
+	mutex  sync.RWMutex
+	engine *QuoteEngine
+	opts   ConsolidatedBookOpts
+
+	slot int64
+	bids []BookLevel
+	asks []BookLevel
+}
+
+// NewConsolidatedBook creates a book of the market and the engine's pools
+func NewConsolidatedBook(engine *QuoteEngine, opts ConsolidatedBookOpts) *ConsolidatedBook {
+	if opts.LevelBps == 0 {
+		opts.LevelBps = defaultLevelBps
+	}
+	if opts.Levels == 0 {
+		opts.Levels = defaultLevels
+	}
+	return &ConsolidatedBook{engine: engine, opts: opts}
+}
+
+// UpdateDepth replaces the OpenBook levels, returning false if the depth is of another market or older than the
+// current levels
+func (b *ConsolidatedBook) UpdateDepth(depth *pb.GetMarketDepthResponse, slot int64) bool {
+	if depth.Market != b.opts.Market && depth.MarketAddress != b.opts.Market {
+		return false
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if slot < b.slot {
+		return false
+	}
+	b.bids = depthLevels(b.opts.Market, depth.Bids)
+	b.asks = depthLevels(b.opts.Market, depth.Asks)
+	b.slot = slot
+	return true
+}
+
+// FollowDepth applies the updates of a GetMarketDepthsStream stream until ctx is canceled or the stream ends
+func (b *ConsolidatedBook) FollowDepth(ctx context.Context, stream connections.Streamer[*pb.GetMarketDepthsStreamResponse]) {
+	ch := stream.Channel(100)
+	go func() {
+		for {
+			select {
+			case response, ok := <-ch:
+				if !ok {
+					log.Warn("market depths stream closed, consolidated book will use stale depth")
+					return
+				}
+				if response.Data == nil {
+					continue
+				}
+				b.UpdateDepth(response.Data, response.Slot)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Levels returns the levels of a side across venues, best price first
+func (b *ConsolidatedBook) Levels(side pb.Side) []BookLevel {
+	b.mutex.RLock()
+	var levels []BookLevel
+	if side == pb.Side_S_BID {
+		levels = append(levels, b.bids...)
+	} else {
+		levels = append(levels, b.asks...)
+	}
+	b.mutex.RUnlock()
+
+	for _, address := range b.opts.Pools {
+		pool, ok := b.engine.Pool(address)
+		if !ok {
+			continue
+		}
+		poolLevels, err := b.poolLevels(pool, side)
+		if err != nil {
+			log.Debugf("skipping pool %v in consolidated book: %v", address, err)
+			continue
+		}
+		levels = append(levels, poolLevels...)
+	}
+
+	sort.SliceStable(levels, func(i, j int) bool {
+		if side == pb.Side_S_BID {
+			return levels[i].Price > levels[j].Price
+		}
+		return levels[i].Price < levels[j].Price
+	})
+	return levels
+}
+
+// BestExecution returns the cheapest split of taking size base tokens from a side of the book, e.g. the asks for a
+// buy. It returns ErrInsufficientLiquidity with the execution of the available size if the side is too thin. Pool
+// fills are accurate to a level; quote them with the engine before submitting.
+func (b *ConsolidatedBook) BestExecution(side pb.Side, size float64) (BookExecution, error) {
+	execution := BookExecution{Side: side}
+	byVenue := make(map[string]*VenueFill)
+	for _, level := range b.Levels(side) {
+		take := math.Min(level.Size, size-execution.Size)
+		if take <= 0 {
+			break
+		}
+
+		fill, ok := byVenue[level.Venue]
+		if !ok {
+			fill = &VenueFill{Venue: level.Venue}
+			byVenue[level.Venue] = fill
+		}
+		fill.Size += take
+		fill.Cost += take * level.Price
+		execution.Size += take
+		execution.Cost += take * level.Price
+	}
+
+	for _, fill := range byVenue {
+		execution.Fills = append(execution.Fills, *fill)
+	}
+	sort.Slice(execution.Fills, func(i, j int) bool {
+		if execution.Fills[i].Size != execution.Fills[j].Size {
+			return execution.Fills[i].Size > execution.Fills[j].Size
+		}
+		return execution.Fills[i].Venue < execution.Fills[j].Venue
+	})
+
+	if execution.Size < size {
+		return execution, fmt.Errorf("%w: %v of %v available", ErrInsufficientLiquidity, execution.Size, size)
+	}
+	return execution, nil
+}
+
+// poolLevels slices the pool's constant product curve into levels LevelBps apart. Taking asks buys base with quote,
+// raising the price; taking bids sells base for quote, lowering it. The fee is charged on the input token.
+func (b *ConsolidatedBook) poolLevels(pool Pool, side pb.Side) ([]BookLevel, error) {
+	base, baseReserves, quote, quoteReserves, err := pool.sides(b.opts.Base)
+	if err != nil {
+		return nil, err
+	}
+	if !quote.matches(b.opts.Quote) {
+		return nil, fmt.Errorf("%w: %v", ErrUnknownToken, b.opts.Quote)
+	}
+	if baseReserves == 0 || quoteReserves == 0 {
+		return nil, ErrEmptyReserves
+	}
+
+	x, y := base.toUI(baseReserves), quote.toUI(quoteReserves)
+	k := x * y
+	spot := y / x
+	keep := 1 - float64(pool.feeRate())/FeeRateDenominator
+
+	levels := make([]BookLevel, 0, b.opts.Levels)
+	for i := 1; i <= b.opts.Levels; i++ {
+		step := float64(i) * b.opts.LevelBps / 10_000
+		var size, cost float64
+		if side == pb.Side_S_BID {
+			if step >= 1 {
+				break
+			}
+			price := spot * (1 - step)
+			nextX, nextY := math.Sqrt(k/price), math.Sqrt(k*price)
+			size, cost = (nextX-x)/keep, y-nextY
+			x, y = nextX, nextY
+		} else {
+			price := spot * (1 + step)
+			nextX, nextY := math.Sqrt(k/price), math.Sqrt(k*price)
+			size, cost = x-nextX, (nextY-y)/keep
+			x, y = nextX, nextY
+		}
+		if size <= 0 {
+			continue
+		}
+		levels = append(levels, BookLevel{Venue: pool.Address, Price: cost / size, Size: size})
+	}
+	return levels, nil
+}
+
+func depthLevels(venue string, items []*pb.MarketDepthItem) []BookLevel {
+	levels := make([]BookLevel, 0, len(items))
+	for _, item := range items {
+		if item.Size <= 0 {
+			continue
+		}
+		levels = append(levels, BookLevel{Venue: venue, Price: item.Price, Size: item.Size})
+	}
+	return levels
+}
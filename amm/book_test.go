@@ -0,0 +1,42 @@
+package amm
+
+import (
+	"testing"
+
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsolidatedBook(t *testing.T) {
+	engine := NewQuoteEngine(
+		Pool{Address: "pool1", Kind: PoolAMM, Token1: sol, Token2: usdc, Token1Reserves: 1_000e9, Token2Reserves: 150_000e6},
+	)
+	book := NewConsolidatedBook(engine, ConsolidatedBookOpts{Market: "SOL/USDC", Base: "SOL", Quote: "USDC", Pools: []string{"pool1"}})
+
+	require.True(t, book.UpdateDepth(&pb.GetMarketDepthResponse{
+		Market: "SOL/USDC",
+		Asks:   []*pb.MarketDepthItem{{Price: 150.2, Size: 5}, {Price: 151, Size: 100}},
+	}, 10))
+	require.False(t, book.UpdateDepth(&pb.GetMarketDepthResponse{Market: "SOL/USDC"}, 9))
+
+	// the best ask is on OpenBook, then the pool is cheaper until its price passes 151
+	execution, err := book.BestExecution(pb.Side_S_ASK, 10)
+	require.NoError(t, err)
+	require.Equal(t, 10.0, execution.Size)
+	require.Equal(t, 2, len(execution.Fills))
+	require.Equal(t, "SOL/USDC", execution.Fills[0].Venue)
+	require.Equal(t, "pool1", execution.Fills[1].Venue)
+	require.InDelta(t, 1.994, execution.Fills[1].Size, 0.001)
+	require.Less(t, execution.Fills[1].AveragePrice(), 151.0)
+	require.Greater(t, execution.AveragePrice(), 150.2)
+
+	// with no OpenBook bids, selling follows the pool's exact quote
+	quote, err := engine.Quote("pool1", "SOL", 1e9)
+	require.NoError(t, err)
+	execution, err = book.BestExecution(pb.Side_S_BID, 1)
+	require.NoError(t, err)
+	require.InDelta(t, usdc.toUI(quote.OutAmount), execution.Cost, 0.01)
+
+	_, err = book.BestExecution(pb.Side_S_ASK, 1_000)
+	require.ErrorIs(t, err, ErrInsufficientLiquidity)
+}
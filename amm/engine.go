@@ -0,0 +1,245 @@
+package amm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/bloXroute-Labs/solana-trader-proto/common"
+	log "github.com/sirupsen/logrus"
+)
+
+// QuoteEngine quotes swaps through Raydium AMM v4 and CPMM pools in process, using reserves kept current from
+// GetPoolReservesStream. Its quote methods mirror the clients' GetRaydiumQuotes and GetRaydiumQuotesCPMM.
+type QuoteEngine struct {
+	mutex sync.RWMutex
+	pools map[string]*Pool
+}
+
+// NewQuoteEngine creates an engine quoting the pools
+func NewQuoteEngine(pools ...Pool) *QuoteEngine {
+	e := &QuoteEngine{pools: make(map[string]*Pool, len(pools))}
+	for _, pool := range pools {
+		e.AddPool(pool)
+	}
+	return e
+}
+
+// AddPool adds a pool, replacing any pool with the same address
+func (e *QuoteEngine) AddPool(pool Pool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.pools[pool.Address] = &pool
+}
+
+// Pool returns a copy of a pool and its current reserves
+func (e *QuoteEngine) Pool(address string) (Pool, bool) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	pool, ok := e.pools[address]
+	if !ok {
+		return Pool{}, false
+	}
+	return *pool, true
+}
+
+// PoolAddresses returns the addresses of the pools to request from GetPoolReservesStream
+func (e *QuoteEngine) PoolAddresses() []string {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	addresses := make([]string, 0, len(e.pools))
+	for address := range e.pools {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+// Update applies a reserves update to its pool, returning false if the pool is unknown or the update is older than
+// the pool's reserves
+func (e *QuoteEngine) Update(reserves *pb.PoolReserves, slot int64) (bool, error) {
+	token1Reserves, err := strconv.ParseUint(reserves.Token1Reserves, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid token1 reserves of pool %v: %w", reserves.PoolAddress, err)
+	}
+	token2Reserves, err := strconv.ParseUint(reserves.Token2Reserves, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid token2 reserves of pool %v: %w", reserves.PoolAddress, err)
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	pool, ok := e.pools[reserves.PoolAddress]
+	if !ok || slot < pool.Slot {
+		return false, nil
+	}
+
+	// the stream may list the pool's tokens in either order
+	if reserves.Token1Address == pool.Token2.Mint && reserves.Token2Address == pool.Token1.Mint {
+		token1Reserves, token2Reserves = token2Reserves, token1Reserves
+	}
+	pool.Token1Reserves = token1Reserves
+	pool.Token2Reserves = token2Reserves
+	pool.Slot = slot
+	return true, nil
+}
+
+// Follow applies the updates of a GetPoolReservesStream stream until ctx is canceled or the stream ends
+func (e *QuoteEngine) Follow(ctx context.Context, stream connections.Streamer[*pb.GetPoolReservesStreamResponse]) {
+	ch := stream.Channel(100)
+	go func() {
+		for {
+			select {
+			case response, ok := <-ch:
+				if !ok {
+					log.Warn("pool reserves stream closed, quotes will use stale reserves")
+					return
+				}
+				if response.Reserves == nil {
+					continue
+				}
+				if _, err := e.Update(response.Reserves, response.Slot); err != nil {
+					log.Errorf("could not apply pool reserves update: %v", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Quote swaps inAmount of the token, in its smallest unit, through a pool. It skips building responses, for
+// evaluating many candidate trades.
+func (e *QuoteEngine) Quote(poolAddress string, inToken string, inAmount uint64) (SwapQuote, error) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	pool, ok := e.pools[poolAddress]
+	if !ok {
+		return SwapQuote{}, fmt.Errorf("unknown pool %v", poolAddress)
+	}
+	return pool.Quote(inToken, inAmount)
+}
+
+// GetRaydiumQuotes quotes the swap through every AMM v4 pool of the pair, best route first
+func (e *QuoteEngine) GetRaydiumQuotes(_ context.Context, request *pb.GetRaydiumQuotesRequest) (*pb.GetRaydiumQuotesResponse, error) {
+	quotes, err := e.routes(PoolAMM, request.InToken, request.OutToken, request.InAmount, request.Slippage)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetRaydiumQuotesResponse{
+		InToken:         quotes.in.Symbol,
+		InTokenAddress:  quotes.in.Mint,
+		OutToken:        quotes.out.Symbol,
+		OutTokenAddress: quotes.out.Mint,
+		InAmount:        request.InAmount,
+		Routes:          quotes.routes,
+	}, nil
+}
+
+// GetRaydiumQuotesCPMM quotes the swap through every CPMM pool of the pair, best route first
+func (e *QuoteEngine) GetRaydiumQuotesCPMM(_ context.Context, request *pb.GetRaydiumCPMMQuotesRequest) (*pb.GetRaydiumCPMMQuotesResponse, error) {
+	quotes, err := e.routes(PoolCPMM, request.InToken, request.OutToken, request.InAmount, request.Slippage)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.GetRaydiumCPMMQuotesResponse{
+		InToken:         quotes.in.Symbol,
+		InTokenAddress:  quotes.in.Mint,
+		OutToken:        quotes.out.Symbol,
+		OutTokenAddress: quotes.out.Mint,
+		InAmount:        request.InAmount,
+		TradeFeeRate:    quotes.feeRate,
+		Routes:          quotes.routes,
+	}, nil
+}
+
+type poolRoutes struct {
+	in      PoolToken
+	out     PoolToken
+	feeRate uint64
+	routes  []*pb.RaydiumQuoteRoute
+}
+
+// routes quotes the swap through each pool of the kind trading the pair. The fee rate is the best route's.
+func (e *QuoteEngine) routes(kind PoolKind, inToken, outToken string, inAmount, slippage float64) (poolRoutes, error) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	type quoted struct {
+		pool  *Pool
+		quote SwapQuote
+	}
+	var candidates []quoted
+	for _, pool := range e.pools {
+		if pool.Kind != kind {
+			continue
+		}
+		in, _, out, _, err := pool.sides(inToken)
+		if err != nil || !out.matches(outToken) {
+			continue
+		}
+
+		quote, err := pool.Quote(inToken, in.fromUI(inAmount))
+		if err != nil {
+			continue
+		}
+		candidates = append(candidates, quoted{pool: pool, quote: quote})
+	}
+	if len(candidates) == 0 {
+		return poolRoutes{}, fmt.Errorf("no %v pool with reserves for %v/%v", kind, inToken, outToken)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].quote.OutAmount != candidates[j].quote.OutAmount {
+			return candidates[i].quote.OutAmount > candidates[j].quote.OutAmount
+		}
+		return candidates[i].pool.Address < candidates[j].pool.Address
+	})
+
+	result := poolRoutes{
+		in:      candidates[0].quote.InToken,
+		out:     candidates[0].quote.OutToken,
+		feeRate: candidates[0].pool.feeRate(),
+		routes:  make([]*pb.RaydiumQuoteRoute, 0, len(candidates)),
+	}
+	for _, candidate := range candidates {
+		quote := candidate.quote
+		outAmount := quote.OutToken.toUI(quote.OutAmount)
+		outAmountMin := outAmount * (1 - slippage/100)
+		result.routes = append(result.routes, &pb.RaydiumQuoteRoute{
+			InAmount:     inAmount,
+			OutAmount:    outAmount,
+			OutAmountMin: outAmountMin,
+			Steps: []*pb.RaydiumQuoteStep{{
+				InToken:         quote.InToken.Symbol,
+				InTokenAddress:  quote.InToken.Mint,
+				OutToken:        quote.OutToken.Symbol,
+				OutTokenAddress: quote.OutToken.Mint,
+				InAmount:        inAmount,
+				OutAmount:       outAmount,
+				Slippage:        slippage,
+				PriceImpactPercent: &common.PriceImpactPercentV2{
+					Percent:  quote.PriceImpactPercent,
+					Infinity: "NOT",
+				},
+				Fee: &common.Fee{
+					Amount:  float32(quote.InToken.toUI(quote.FeeAmount)),
+					Mint:    quote.InToken.Mint,
+					Percent: float32(candidate.pool.feeRate()) / FeeRateDenominator * 100,
+				},
+				OutAmountMin: outAmountMin,
+				Project:      &pb.StepProject{Label: "Raydium", Id: candidate.pool.Address},
+			}},
+		})
+	}
+	return result, nil
+}
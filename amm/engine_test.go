@@ -0,0 +1,65 @@
+package amm
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	sol  = PoolToken{Mint: "So11111111111111111111111111111111111111112", Symbol: "SOL", Decimals: 9}
+	usdc = PoolToken{Mint: "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v", Symbol: "USDC", Decimals: 6}
+)
+
+func TestQuoteEngine(t *testing.T) {
+	engine := NewQuoteEngine(
+		Pool{Address: "pool1", Kind: PoolAMM, Token1: sol, Token2: usdc, Token1Reserves: 1_000e9, Token2Reserves: 150_000e6},
+		Pool{Address: "pool2", Kind: PoolAMM, Token1: usdc, Token2: sol},
+		Pool{Address: "pool3", Kind: PoolCPMM, Token1: sol, Token2: usdc, Token1Reserves: 1_000e9, Token2Reserves: 200_000e6},
+	)
+
+	quote, err := engine.Quote("pool1", "SOL", 1e9)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2_500_000), quote.FeeAmount)
+	require.Equal(t, uint64(149_475_897), quote.OutAmount)
+	require.InDelta(t, 0.0996506, quote.PriceImpactPercent, 1e-6)
+
+	// pool2 lists its tokens the other way around than the stream
+	updated, err := engine.Update(&pb.PoolReserves{
+		PoolAddress:    "pool2",
+		Token1Address:  sol.Mint,
+		Token1Reserves: "1000000000000",
+		Token2Address:  usdc.Mint,
+		Token2Reserves: "151000000000",
+	}, 10)
+	require.NoError(t, err)
+	require.True(t, updated)
+
+	updated, err = engine.Update(&pb.PoolReserves{PoolAddress: "pool2", Token1Reserves: "1", Token2Reserves: "1"}, 9)
+	require.NoError(t, err)
+	require.False(t, updated)
+
+	response, err := engine.GetRaydiumQuotes(context.Background(), &pb.GetRaydiumQuotesRequest{
+		InToken:  "SOL",
+		OutToken: usdc.Mint,
+		InAmount: 1,
+		Slippage: 1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(response.Routes))
+	require.Equal(t, "pool2", response.Routes[0].Steps[0].Project.Id)
+	require.Equal(t, 150.472403, response.Routes[0].OutAmount)
+	require.InDelta(t, 150.472403*0.99, response.Routes[0].OutAmountMin, 1e-9)
+
+	// CPMM pools are quoted separately
+	cpmm, err := engine.GetRaydiumQuotesCPMM(context.Background(), &pb.GetRaydiumCPMMQuotesRequest{
+		InToken:  "USDC",
+		OutToken: "SOL",
+		InAmount: 200,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(cpmm.Routes))
+	require.Equal(t, uint64(DefaultAMMFeeRate), cpmm.TradeFeeRate)
+}
@@ -0,0 +1,181 @@
+package amm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+)
+
+const (
+	// FeeRateDenominator is the denominator of pool fee rates, e.g. 2500 is 0.25%
+	FeeRateDenominator = 1_000_000
+
+	// DefaultAMMFeeRate is the fee of Raydium AMM v4 pools
+	DefaultAMMFeeRate = 2500
+)
+
+var (
+	ErrUnknownToken  = errors.New("token not in pool")
+	ErrEmptyReserves = errors.New("pool has no reserves")
+)
+
+type PoolKind int
+
+const (
+	// PoolAMM is a Raydium AMM v4 pool
+	PoolAMM PoolKind = iota
+
+	// PoolCPMM is a Raydium CPMM pool
+	PoolCPMM
+)
+
+func (k PoolKind) String() string {
+	switch k {
+	case PoolAMM:
+		return "amm"
+	case PoolCPMM:
+		return "cpmm"
+	default:
+		return fmt.Sprintf("kind(%d)", int(k))
+	}
+}
+
+// PoolToken is one side of a pool
+type PoolToken struct {
+	Mint     string
+	Symbol   string
+	Decimals uint8
+}
+
+func (t PoolToken) matches(token string) bool {
+	return token == t.Mint || (t.Symbol != "" && strings.EqualFold(token, t.Symbol))
+}
+
+func (t PoolToken) toUI(amount uint64) float64 {
+	return float64(amount) / math.Pow10(int(t.Decimals))
+}
+
+func (t PoolToken) fromUI(amount float64) uint64 {
+	return uint64(math.Floor(amount * math.Pow10(int(t.Decimals))))
+}
+
+// Pool is a constant product pool with the reserves last reported for it
+type Pool struct {
+	Address string
+	Kind    PoolKind
+	Token1  PoolToken
+	Token2  PoolToken
+
+	// FeeRate is the trade fee charged on the input amount, relative to FeeRateDenominator. Defaults to
+	// DefaultAMMFeeRate.
+	FeeRate uint64
+
+	Token1Reserves uint64
+	Token2Reserves uint64
+	Slot           int64
+}
+
+// NewPool creates a pool from a GetRaydiumPoolReserve or GetRaydiumPools entry. Token decimals aren't part of the
+// entry, so they must be provided.
+func NewPool(pool *pb.ProjectPool, kind PoolKind, token1Decimals, token2Decimals uint8) Pool {
+	p := Pool{
+		Address:        pool.PoolAddress,
+		Kind:           kind,
+		Token1:         PoolToken{Mint: pool.Token1MintAddress, Symbol: pool.Token1MintSymbol, Decimals: token1Decimals},
+		Token2:         PoolToken{Mint: pool.Token2MintAddress, Symbol: pool.Token2MintSymbol, Decimals: token2Decimals},
+		Token1Reserves: uint64(pool.Token1Reserves),
+		Token2Reserves: uint64(pool.Token2Reserves),
+	}
+	if pool.LiquidityPoolKeys != nil {
+		p.FeeRate = pool.LiquidityPoolKeys.TradeFeeRate
+	}
+	return p
+}
+
+// SwapQuote is the outcome of swapping an exact input amount through a pool, in the tokens' smallest units
+type SwapQuote struct {
+	InToken  PoolToken
+	OutToken PoolToken
+
+	InAmount  uint64
+	OutAmount uint64
+	FeeAmount uint64
+
+	// PriceImpactPercent is how much worse than the pool's spot price the input after fees is swapped
+	PriceImpactPercent float64
+}
+
+func (p *Pool) feeRate() uint64 {
+	if p.FeeRate == 0 {
+		return DefaultAMMFeeRate
+	}
+	return p.FeeRate
+}
+
+// sides returns the input and output tokens and reserves for swapping the token
+func (p *Pool) sides(inToken string) (PoolToken, uint64, PoolToken, uint64, error) {
+	switch {
+	case p.Token1.matches(inToken):
+		return p.Token1, p.Token1Reserves, p.Token2, p.Token2Reserves, nil
+	case p.Token2.matches(inToken):
+		return p.Token2, p.Token2Reserves, p.Token1, p.Token1Reserves, nil
+	default:
+		return PoolToken{}, 0, PoolToken{}, 0, fmt.Errorf("%w: %v", ErrUnknownToken, inToken)
+	}
+}
+
+// Quote swaps inAmount of the token, given as mint or symbol, through the pool. The fee is rounded up and the output
+// rounded down, like the on-chain programs.
+func (p *Pool) Quote(inToken string, inAmount uint64) (SwapQuote, error) {
+	in, inReserves, out, outReserves, err := p.sides(inToken)
+	if err != nil {
+		return SwapQuote{}, err
+	}
+	if inReserves == 0 || outReserves == 0 {
+		return SwapQuote{}, ErrEmptyReserves
+	}
+
+	fee := new(big.Int).SetUint64(inAmount)
+	fee.Mul(fee, new(big.Int).SetUint64(p.feeRate()))
+	fee.Add(fee, big.NewInt(FeeRateDenominator-1))
+	fee.Quo(fee, big.NewInt(FeeRateDenominator))
+
+	inAfterFee := new(big.Int).SetUint64(inAmount)
+	inAfterFee.Sub(inAfterFee, fee)
+
+	// out = outReserves * inAfterFee / (inReserves + inAfterFee)
+	denominator := new(big.Int).SetUint64(inReserves)
+	denominator.Add(denominator, inAfterFee)
+	outAmount := new(big.Int).SetUint64(outReserves)
+	outAmount.Mul(outAmount, inAfterFee)
+	outAmount.Quo(outAmount, denominator)
+
+	// at spot price inAfterFee would buy inAfterFee * outReserves / inReserves, so the shortfall is
+	// inAfterFee / (inReserves + inAfterFee)
+	impact, _ := new(big.Rat).SetFrac(inAfterFee, denominator).Float64()
+
+	return SwapQuote{
+		InToken:            in,
+		OutToken:           out,
+		InAmount:           inAmount,
+		OutAmount:          outAmount.Uint64(),
+		FeeAmount:          fee.Uint64(),
+		PriceImpactPercent: impact * 100,
+	}, nil
+}
+
+// SpotPrice is the pool's price of the token in the other token, ignoring fees
+func (p *Pool) SpotPrice(token string) (float64, error) {
+	base, baseReserves, quote, quoteReserves, err := p.sides(token)
+	if err != nil {
+		return 0, err
+	}
+	if baseReserves == 0 {
+		return 0, ErrEmptyReserves
+	}
+	return quote.toUI(quoteReserves) / base.toUI(baseReserves), nil
+}
@@ -0,0 +1,307 @@
+package amm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"math/bits"
+	"sync"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	PumpFunTokenDecimals = 6
+
+	// PumpFunFeeBasisPoints is the fee the program charges on the SOL side of buys and sells
+	PumpFunFeeBasisPoints = 100
+
+	// PumpFunInitialVirtualSolReserves and PumpFunInitialVirtualTokenReserves are the reserves of a new curve
+	PumpFunInitialVirtualSolReserves   = 30_000_000_000
+	PumpFunInitialVirtualTokenReserves = 1_073_000_000_000_000
+
+	lamportsPerSOL = 1_000_000_000
+)
+
+var (
+	ErrCurveExhausted = errors.New("bonding curve has too few tokens left")
+	ErrCurveEmpty     = errors.New("bonding curve has no reserves")
+	ErrAmountOverflow = errors.New("amount does not fit in 64 bits")
+)
+
+// BondingCurve models a Pump.fun bonding curve from its virtual reserves, in lamports and the token's smallest unit
+type BondingCurve struct {
+	Mint    string
+	Address string
+
+	VirtualSolReserves   uint64
+	VirtualTokenReserves uint64
+
+	// FeeBasisPoints defaults to PumpFunFeeBasisPoints
+	FeeBasisPoints uint64
+
+	Slot int64
+}
+
+// NewBondingCurve creates the curve of a newly created token, e.g. from GetPumpFunNewTokensStream
+func NewBondingCurve(mint, address string) BondingCurve {
+	return BondingCurve{
+		Mint:                 mint,
+		Address:              address,
+		VirtualSolReserves:   PumpFunInitialVirtualSolReserves,
+		VirtualTokenReserves: PumpFunInitialVirtualTokenReserves,
+	}
+}
+
+func (c BondingCurve) feeBasisPoints() uint64 {
+	if c.FeeBasisPoints == 0 {
+		return PumpFunFeeBasisPoints
+	}
+	return c.FeeBasisPoints
+}
+
+func (c BondingCurve) fee(solAmount uint64) (uint64, error) {
+	return mulDiv(solAmount, c.feeBasisPoints(), 10_000)
+}
+
+// checkReserves returns ErrCurveEmpty for curves without reserves, e.g. zero values or curves updated from swaps
+// without reserves, which can't be priced
+func (c BondingCurve) checkReserves() error {
+	if c.VirtualSolReserves == 0 || c.VirtualTokenReserves == 0 {
+		return ErrCurveEmpty
+	}
+	return nil
+}
+
+// Price is the current SOL price of one token
+func (c BondingCurve) Price() float64 {
+	if c.VirtualTokenReserves == 0 {
+		return 0
+	}
+	return (float64(c.VirtualSolReserves) / lamportsPerSOL) / (float64(c.VirtualTokenReserves) / math.Pow10(PumpFunTokenDecimals))
+}
+
+// BuyCost returns the lamports, including the fee, the program charges to buy tokenAmount tokens
+func (c BondingCurve) BuyCost(tokenAmount uint64) (uint64, error) {
+	if err := c.checkReserves(); err != nil {
+		return 0, err
+	}
+	if tokenAmount >= c.VirtualTokenReserves {
+		return 0, ErrCurveExhausted
+	}
+	cost, err := mulDiv(tokenAmount, c.VirtualSolReserves, c.VirtualTokenReserves-tokenAmount)
+	if err != nil {
+		return 0, err
+	}
+	if cost, err = add(cost, 1); err != nil {
+		return 0, err
+	}
+	fee, err := c.fee(cost)
+	if err != nil {
+		return 0, err
+	}
+	return add(cost, fee)
+}
+
+// TokensForSOL returns the most tokens that can be bought for solAmount lamports, including the fee. It returns
+// ErrCurveEmpty if the curve has no reserves.
+func (c BondingCurve) TokensForSOL(solAmount uint64) (uint64, error) {
+	if err := c.checkReserves(); err != nil {
+		return 0, err
+	}
+	solAfterFee, err := mulDiv(solAmount, 10_000, 10_000+c.feeBasisPoints())
+	if err != nil {
+		return 0, err
+	}
+	solReserves, err := add(c.VirtualSolReserves, solAfterFee)
+	if err != nil {
+		return 0, err
+	}
+	tokens, err := mulDiv(c.VirtualTokenReserves, solAfterFee, solReserves)
+	if err != nil {
+		return 0, err
+	}
+
+	// rounding in the program's cost may need a token less
+	for tokens > 0 {
+		cost, err := c.BuyCost(tokens)
+		if err == nil && cost <= solAmount {
+			break
+		}
+		tokens--
+	}
+	return tokens, nil
+}
+
+// SellProceeds returns the lamports, after the fee, received for selling tokenAmount tokens. It returns ErrCurveEmpty
+// if the curve has no reserves.
+func (c BondingCurve) SellProceeds(tokenAmount uint64) (uint64, error) {
+	if err := c.checkReserves(); err != nil {
+		return 0, err
+	}
+	tokenReserves, err := add(c.VirtualTokenReserves, tokenAmount)
+	if err != nil {
+		return 0, err
+	}
+	proceeds, err := mulDiv(tokenAmount, c.VirtualSolReserves, tokenReserves)
+	if err != nil {
+		return 0, err
+	}
+	fee, err := c.fee(proceeds)
+	if err != nil {
+		return 0, err
+	}
+	return proceeds - fee, nil
+}
+
+// BuySOLWorth builds a swap buying the tokens solAmount SOL buys at the current curve. The SOL spent may exceed
+// solAmount by slippage percent if the curve moves before the swap lands.
+func (c BondingCurve) BuySOLWorth(owner string, solAmount, slippage float64) (*pb.PostPumpFunSwapRequest, error) {
+	tokens, err := c.TokensForSOL(uint64(solAmount * lamportsPerSOL))
+	if err != nil {
+		return nil, err
+	}
+	if tokens == 0 {
+		return nil, fmt.Errorf("%v SOL buys no tokens", solAmount)
+	}
+
+	return &pb.PostPumpFunSwapRequest{
+		UserAddress:         owner,
+		BondingCurveAddress: c.Address,
+		TokenAddress:        c.Mint,
+		TokenAmount:         float64(tokens) / math.Pow10(PumpFunTokenDecimals),
+		SolThreshold:        solAmount * (1 + slippage/100),
+		IsBuy:               true,
+	}, nil
+}
+
+// SellPercent builds a swap selling percent of the holdings, given in tokens. The SOL received may fall short of
+// the current curve's proceeds by slippage percent.
+func (c BondingCurve) SellPercent(owner string, holdings, percent, slippage float64) (*pb.PostPumpFunSwapRequest, error) {
+	if percent <= 0 || percent > 100 {
+		return nil, fmt.Errorf("sell percent %v must be between 0 and 100", percent)
+	}
+
+	tokens := uint64(math.Floor(holdings * percent / 100 * math.Pow10(PumpFunTokenDecimals)))
+	if tokens == 0 {
+		return nil, errors.New("nothing to sell")
+	}
+	proceedsLamports, err := c.SellProceeds(tokens)
+	if err != nil {
+		return nil, err
+	}
+	proceeds := float64(proceedsLamports) / lamportsPerSOL
+
+	return &pb.PostPumpFunSwapRequest{
+		UserAddress:         owner,
+		BondingCurveAddress: c.Address,
+		TokenAddress:        c.Mint,
+		TokenAmount:         float64(tokens) / math.Pow10(PumpFunTokenDecimals),
+		SolThreshold:        proceeds * (1 - slippage/100),
+		IsBuy:               false,
+	}, nil
+}
+
+// PumpFunCurves tracks the bonding curves of Pump.fun tokens from GetPumpFunSwapsStream
+type PumpFunCurves struct {
+	mutex  sync.RWMutex
+	curves map[string]*BondingCurve
+}
+
+// NewPumpFunCurves creates a tracker of the curves
+func NewPumpFunCurves(curves ...BondingCurve) *PumpFunCurves {
+	p := &PumpFunCurves{curves: make(map[string]*BondingCurve, len(curves))}
+	for _, curve := range curves {
+		p.Add(curve)
+	}
+	return p
+}
+
+// Add tracks a curve, replacing any curve of the same mint
+func (p *PumpFunCurves) Add(curve BondingCurve) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.curves[curve.Mint] = &curve
+}
+
+// Curve returns the current state of a token's curve
+func (p *PumpFunCurves) Curve(mint string) (BondingCurve, bool) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+
+	curve, ok := p.curves[mint]
+	if !ok {
+		return BondingCurve{}, false
+	}
+	return *curve, true
+}
+
+// Update applies the reserves after a swap, tracking the token's curve if it isn't yet. Swaps older than the curve
+// are ignored.
+func (p *PumpFunCurves) Update(swap *pb.GetPumpFunSwapsStreamResponse) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	curve, ok := p.curves[swap.MintAddress]
+	if !ok {
+		curve = &BondingCurve{Mint: swap.MintAddress, Address: swap.BondingCurveAddress}
+		p.curves[swap.MintAddress] = curve
+	}
+	if swap.Slot < curve.Slot {
+		return false
+	}
+
+	curve.VirtualSolReserves = swap.VirtualSolReserves
+	curve.VirtualTokenReserves = swap.VirtualTokenReserves
+	curve.Slot = swap.Slot
+	if curve.Address == "" {
+		curve.Address = swap.BondingCurveAddress
+	}
+	return true
+}
+
+// Follow applies the swaps of a GetPumpFunSwapsStream stream until ctx is canceled or the stream ends
+func (p *PumpFunCurves) Follow(ctx context.Context, stream connections.Streamer[*pb.GetPumpFunSwapsStreamResponse]) {
+	ch := stream.Channel(100)
+	go func() {
+		for {
+			select {
+			case swap, ok := <-ch:
+				if !ok {
+					log.Warn("pumpfun swaps stream closed, curves will be stale")
+					return
+				}
+				p.Update(swap)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// mulDiv returns a * b / c rounded down without overflowing the intermediate product
+func mulDiv(a, b, c uint64) (uint64, error) {
+	if c == 0 {
+		return 0, ErrCurveEmpty
+	}
+	result := new(big.Int).SetUint64(a)
+	result.Mul(result, new(big.Int).SetUint64(b))
+	result.Quo(result, new(big.Int).SetUint64(c))
+	if !result.IsUint64() {
+		return 0, fmt.Errorf("%w: %v * %v / %v", ErrAmountOverflow, a, b, c)
+	}
+	return result.Uint64(), nil
+}
+
+func add(a, b uint64) (uint64, error) {
+	sum, carry := bits.Add64(a, b, 0)
+	if carry != 0 {
+		return 0, fmt.Errorf("%w: %v + %v", ErrAmountOverflow, a, b)
+	}
+	return sum, nil
+}
@@ -0,0 +1,78 @@
+package amm
+
+import (
+	"math"
+	"testing"
+
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBondingCurve(t *testing.T) {
+	curve := NewBondingCurve("mint", "curve")
+	require.InDelta(t, 30/1_073_000_000.0, curve.Price(), 1e-18)
+
+	// the tokens bought for 1 SOL cost at most 1 SOL, and within rounding of it
+	tokens, err := curve.TokensForSOL(1e9)
+	require.NoError(t, err)
+	cost, err := curve.BuyCost(tokens)
+	require.NoError(t, err)
+	require.LessOrEqual(t, cost, uint64(1e9))
+	require.InDelta(t, 1e9, cost, 10)
+
+	buy, err := curve.BuySOLWorth("owner", 1, 5)
+	require.NoError(t, err)
+	require.True(t, buy.IsBuy)
+	require.Equal(t, float64(tokens)/1e6, buy.TokenAmount)
+	require.Equal(t, 1.05, buy.SolThreshold)
+
+	// after the buy lands, selling the tokens returns less than was spent
+	curves := NewPumpFunCurves(curve)
+	require.True(t, curves.Update(&pb.GetPumpFunSwapsStreamResponse{
+		Slot:                 1,
+		MintAddress:          "mint",
+		VirtualSolReserves:   curve.VirtualSolReserves + 1e9,
+		VirtualTokenReserves: curve.VirtualTokenReserves - tokens,
+	}))
+	updated, ok := curves.Curve("mint")
+	require.True(t, ok)
+	proceeds, err := updated.SellProceeds(tokens)
+	require.NoError(t, err)
+	require.Less(t, proceeds, uint64(1e9))
+
+	sell, err := updated.SellPercent("owner", float64(tokens)/1e6, 50, 10)
+	require.NoError(t, err)
+	require.False(t, sell.IsBuy)
+	require.InDelta(t, float64(tokens)/2e6, sell.TokenAmount, 1e-6)
+	proceeds, err = updated.SellProceeds(tokens / 2)
+	require.NoError(t, err)
+	require.Less(t, sell.SolThreshold, float64(proceeds)/1e9)
+}
+
+func TestBondingCurveInvalidReserves(t *testing.T) {
+	// a curve first seen from a swap without reserves can't be priced
+	curves := NewPumpFunCurves()
+	require.True(t, curves.Update(&pb.GetPumpFunSwapsStreamResponse{Slot: 1, MintAddress: "mint"}))
+	empty, ok := curves.Curve("mint")
+	require.True(t, ok)
+
+	for _, curve := range []BondingCurve{{}, empty, {VirtualSolReserves: 1}, {VirtualTokenReserves: 1}} {
+		_, err := curve.TokensForSOL(0)
+		require.ErrorIs(t, err, ErrCurveEmpty)
+		_, err = curve.SellProceeds(0)
+		require.ErrorIs(t, err, ErrCurveEmpty)
+		_, err = curve.BuyCost(0)
+		require.ErrorIs(t, err, ErrCurveEmpty)
+		_, err = curve.BuySOLWorth("owner", 1, 5)
+		require.ErrorIs(t, err, ErrCurveEmpty)
+		_, err = curve.SellPercent("owner", 1, 50, 5)
+		require.ErrorIs(t, err, ErrCurveEmpty)
+	}
+
+	// amounts that don't fit in 64 bits are rejected instead of truncated
+	huge := BondingCurve{VirtualSolReserves: math.MaxUint64, VirtualTokenReserves: 2}
+	_, err := huge.BuyCost(1)
+	require.ErrorIs(t, err, ErrAmountOverflow)
+	_, err = huge.SellProceeds(math.MaxUint64)
+	require.ErrorIs(t, err, ErrAmountOverflow)
+}
@@ -0,0 +1,445 @@
+package candles
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	USDCMint = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+	USDTMint = "Es9vMFrzaCERmJfrF4H2FYD4KCoNkY11McCe8BenwNYB"
+
+	defaultInterval = time.Minute
+	defaultHistory  = 1000
+	defaultBuffer   = 1000
+)
+
+var ErrClosed = errors.New("candle aggregator closed")
+
+// Tick is a single trade or swap of a market or pool, priced in quote tokens per base token
+type Tick struct {
+	Key    string
+	Slot   int64
+	Time   time.Time
+	Price  float64
+	Volume float64
+}
+
+// Candle is an OHLCV bar of a market or pool. Open and Close are the prices of the ticks with the lowest and highest
+// slot, regardless of the order they arrived in.
+type Candle struct {
+	Key      string
+	Start    time.Time
+	Interval time.Duration
+
+	Open   float64
+	High   float64
+	Low    float64
+	Close  float64
+	Volume float64
+
+	// QuoteVolume is the volume in the quote token
+	QuoteVolume float64
+	Trades      int
+
+	FirstSlot int64
+	LastSlot  int64
+
+	// Closed is set once no more ticks are expected for the bar. Revised is set when a late tick changed a closed bar.
+	Closed  bool
+	Revised bool
+}
+
+// End is the start of the next bar
+func (c Candle) End() time.Time {
+	return c.Start.Add(c.Interval)
+}
+
+type Opts struct {
+	// Intervals are the bar durations built for every market or pool. Defaults to one minute.
+	Intervals []time.Duration
+
+	// Grace is how long past its end a bar waits for late ticks before closing
+	Grace time.Duration
+
+	// Partial emits the open bar after every tick, not only closed bars
+	Partial bool
+
+	// History is the number of bars kept per market or pool and interval, for Candles and revising late ticks.
+	// Defaults to 1000.
+	History int
+
+	// Buffer is the size of the channel read by Streamer. Defaults to 1000.
+	Buffer int
+
+	// OnCandle is called with every emitted bar, in order. It's called while the aggregator is locked, so it must not
+	// block or call the aggregator.
+	OnCandle func(Candle)
+
+	// QuoteTokens are the mints swaps are priced in, in order of preference when a swap has two of them. Defaults
+	// to USDC, USDT and SOL.
+	QuoteTokens []string
+}
+
+type seriesKey struct {
+	key      string
+	interval time.Duration
+}
+
+type bar struct {
+	Candle
+	openSlot  int64
+	closeSlot int64
+
+	// evicted is set once the bar is dropped from the kept history
+	evicted bool
+}
+
+// openBars orders the bars that aren't closed yet by their end, so closing bars doesn't scan every kept bar
+type openBars []*bar
+
+func (h openBars) Len() int           { return len(h) }
+func (h openBars) Less(i, j int) bool { return h[i].End().Before(h[j].End()) }
+func (h openBars) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *openBars) Push(x interface{}) {
+	*h = append(*h, x.(*bar))
+}
+
+func (h *openBars) Pop() interface{} {
+	old := *h
+	b := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return b
+}
+
+// Aggregator builds OHLCV bars from GetTradesStream and GetSwapsStream updates. Bars are placed by the stream's
+// timestamp and ordered within a bar by slot. A bar closes once a tick at least Grace past its end arrives, or
+// Advance is called past it, e.g. from a ticker to close the bars of quiet markets.
+type Aggregator struct {
+	mutex     sync.Mutex
+	opts      Opts
+	series    map[seriesKey][]*bar
+	open      openBars
+	watermark time.Time
+	candles   chan Candle
+	closed    bool
+}
+
+// NewAggregator creates an aggregator of the intervals
+func NewAggregator(opts Opts) *Aggregator {
+	if len(opts.Intervals) == 0 {
+		opts.Intervals = []time.Duration{defaultInterval}
+	}
+	if opts.History == 0 {
+		opts.History = defaultHistory
+	}
+	if opts.Buffer == 0 {
+		opts.Buffer = defaultBuffer
+	}
+	if len(opts.QuoteTokens) == 0 {
+		opts.QuoteTokens = []string{USDCMint, USDTMint, solana.SolMint.String()}
+	}
+	return &Aggregator{
+		opts:    opts,
+		series:  make(map[seriesKey][]*bar),
+		candles: make(chan Candle, opts.Buffer),
+	}
+}
+
+// Streamer returns the emitted bars. It returns ErrClosed once the aggregator is closed and the buffered bars are
+// read. Bars are dropped if they aren't read fast enough.
+func (a *Aggregator) Streamer() connections.Streamer[Candle] {
+	return func() (Candle, error) {
+		candle, ok := <-a.candles
+		if !ok {
+			return Candle{}, ErrClosed
+		}
+		return candle, nil
+	}
+}
+
+// Close stops emitting bars to Streamer
+func (a *Aggregator) Close() {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if !a.closed {
+		a.closed = true
+		close(a.candles)
+	}
+}
+
+// Candles returns the kept bars of a market or pool, oldest first, including the open ones
+func (a *Aggregator) Candles(key string, interval time.Duration) []Candle {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	bars := a.series[seriesKey{key: key, interval: interval}]
+	candles := make([]Candle, 0, len(bars))
+	for _, b := range bars {
+		candles = append(candles, b.Candle)
+	}
+	return candles
+}
+
+// Add applies a tick to its bar of every interval
+func (a *Aggregator) Add(tick Tick) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.add(tick)
+	a.closeBars()
+}
+
+// AddTrades applies the trades of a GetTradesStream update of the market
+func (a *Aggregator) AddTrades(market string, response *pb.GetTradesStreamResponse) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for _, tick := range TradeTicks(market, response) {
+		a.add(tick)
+	}
+	a.closeBars()
+}
+
+// AddSwap applies a GetSwapsStream update. Failed swaps and swaps without a quote token are ignored.
+func (a *Aggregator) AddSwap(response *pb.GetSwapsStreamResponse) {
+	tick, ok := SwapTick(response, a.opts.QuoteTokens)
+	if !ok {
+		return
+	}
+	a.Add(tick)
+}
+
+// Backfill applies recorded ticks in slot order. Call it before following streams, since ticks older than the
+// closed bars revise them.
+func (a *Aggregator) Backfill(ticks []Tick) {
+	sorted := append([]Tick(nil), ticks...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Slot < sorted[j].Slot
+	})
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for _, tick := range sorted {
+		a.add(tick)
+		a.closeBars()
+	}
+}
+
+// Advance closes the bars that ended at least Grace before now
+func (a *Aggregator) Advance(now time.Time) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if now.After(a.watermark) {
+		a.watermark = now
+	}
+	a.closeBars()
+}
+
+// FollowTrades applies the updates of a GetTradesStream stream of the market until ctx is canceled or the stream ends
+func (a *Aggregator) FollowTrades(ctx context.Context, market string, stream connections.Streamer[*pb.GetTradesStreamResponse]) {
+	ch := stream.Channel(100)
+	go func() {
+		for {
+			select {
+			case response, ok := <-ch:
+				if !ok {
+					log.Warnf("trades stream of %v closed, candles will stop updating", market)
+					return
+				}
+				a.AddTrades(market, response)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// FollowSwaps applies the updates of a GetSwapsStream stream until ctx is canceled or the stream ends
+func (a *Aggregator) FollowSwaps(ctx context.Context, stream connections.Streamer[*pb.GetSwapsStreamResponse]) {
+	ch := stream.Channel(100)
+	go func() {
+		for {
+			select {
+			case response, ok := <-ch:
+				if !ok {
+					log.Warn("swaps stream closed, candles will stop updating")
+					return
+				}
+				a.AddSwap(response)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (a *Aggregator) add(tick Tick) {
+	if tick.Time.After(a.watermark) {
+		a.watermark = tick.Time
+	}
+
+	for _, interval := range a.opts.Intervals {
+		b := a.bar(seriesKey{key: tick.Key, interval: interval}, tick.Time.Truncate(interval))
+		if b == nil {
+			log.Debugf("dropping tick of %v at slot %v older than the kept %v candles", tick.Key, tick.Slot, interval)
+			continue
+		}
+
+		if b.Trades == 0 {
+			b.Open, b.High, b.Low, b.Close = tick.Price, tick.Price, tick.Price, tick.Price
+			b.openSlot, b.closeSlot = tick.Slot, tick.Slot
+			b.FirstSlot, b.LastSlot = tick.Slot, tick.Slot
+		} else {
+			if tick.Price > b.High {
+				b.High = tick.Price
+			}
+			if tick.Price < b.Low {
+				b.Low = tick.Price
+			}
+			if tick.Slot < b.openSlot {
+				b.Open, b.openSlot, b.FirstSlot = tick.Price, tick.Slot, tick.Slot
+			}
+			if tick.Slot >= b.closeSlot {
+				b.Close, b.closeSlot, b.LastSlot = tick.Price, tick.Slot, tick.Slot
+			}
+		}
+		b.Volume += tick.Volume
+		b.QuoteVolume += tick.Volume * tick.Price
+		b.Trades++
+
+		if b.Closed {
+			b.Revised = true
+			a.emit(b.Candle)
+		} else if a.opts.Partial {
+			a.emit(b.Candle)
+		}
+	}
+}
+
+// bar returns the bar of the series starting at start, creating it if it's within the kept history
+func (a *Aggregator) bar(key seriesKey, start time.Time) *bar {
+	bars := a.series[key]
+	i := sort.Search(len(bars), func(i int) bool {
+		return !bars[i].Start.Before(start)
+	})
+	if i < len(bars) && bars[i].Start.Equal(start) {
+		return bars[i]
+	}
+	if i == 0 && len(bars) >= a.opts.History {
+		return nil
+	}
+
+	b := &bar{Candle: Candle{Key: key.key, Start: start, Interval: key.interval}}
+	bars = append(bars, nil)
+	copy(bars[i+1:], bars[i:])
+	bars[i] = b
+	if len(bars) > a.opts.History {
+		evicted := len(bars) - a.opts.History
+		for _, old := range bars[:evicted] {
+			old.evicted = true
+		}
+		bars = bars[evicted:]
+	}
+	a.series[key] = bars
+	heap.Push(&a.open, b)
+	return b
+}
+
+// closeBars closes the open bars that ended at least Grace before the watermark, in order of their end
+func (a *Aggregator) closeBars() {
+	for len(a.open) != 0 && !a.open[0].End().Add(a.opts.Grace).After(a.watermark) {
+		b := heap.Pop(&a.open).(*bar)
+		if b.evicted {
+			continue
+		}
+		b.Closed = true
+		a.emit(b.Candle)
+	}
+}
+
+func (a *Aggregator) emit(candle Candle) {
+	if a.opts.OnCandle != nil {
+		a.opts.OnCandle(candle)
+	}
+	if a.closed {
+		return
+	}
+
+	select {
+	case a.candles <- candle:
+	default:
+		log.Warnf("candles channel full, dropping %v candle of %v at %v", candle.Interval, candle.Key, candle.Start)
+	}
+}
+
+// TradeTicks converts the trades of a GetTradesStream update, skipping maker fills so each match is counted once
+func TradeTicks(market string, response *pb.GetTradesStreamResponse) []Tick {
+	if response.Trades == nil {
+		return nil
+	}
+
+	timestamp := responseTime(response.Timestamp)
+	ticks := make([]Tick, 0, len(response.Trades.Trades))
+	for _, trade := range response.Trades.Trades {
+		if trade.IsMaker {
+			continue
+		}
+		ticks = append(ticks, Tick{
+			Key:    market,
+			Slot:   response.Slot,
+			Time:   timestamp,
+			Price:  trade.FillPrice,
+			Volume: trade.Size,
+		})
+	}
+	return ticks
+}
+
+// SwapTick converts a GetSwapsStream update of a pool, priced in the first of quoteTokens it swaps. Swaps only
+// report the minimum output amount, so prices are the worst the swapper accepted.
+func SwapTick(response *pb.GetSwapsStreamResponse, quoteTokens []string) (Tick, bool) {
+	swap := response.Swap
+	if swap == nil || !swap.Success || swap.InAmount == 0 || swap.OutAmountMin == 0 {
+		return Tick{}, false
+	}
+
+	tick := Tick{
+		Key:  swap.PoolAddress,
+		Slot: response.Slot,
+		Time: responseTime(response.Timestamp),
+	}
+	for _, quote := range quoteTokens {
+		switch quote {
+		case swap.InTokenAddress:
+			tick.Price, tick.Volume = swap.InAmount/swap.OutAmountMin, swap.OutAmountMin
+			return tick, true
+		case swap.OutTokenAddress:
+			tick.Price, tick.Volume = swap.OutAmountMin/swap.InAmount, swap.InAmount
+			return tick, true
+		}
+	}
+	return Tick{}, false
+}
+
+func responseTime(timestamp *timestamppb.Timestamp) time.Time {
+	if timestamp == nil {
+		return time.Now()
+	}
+	return timestamp.AsTime()
+}
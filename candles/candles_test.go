@@ -0,0 +1,108 @@
+package candles
+
+import (
+	"testing"
+	"time"
+
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestAggregator(t *testing.T) {
+	var emitted []Candle
+	aggregator := NewAggregator(Opts{
+		Grace:    5 * time.Second,
+		OnCandle: func(candle Candle) { emitted = append(emitted, candle) },
+	})
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// recorded ticks are applied in slot order
+	aggregator.Backfill([]Tick{
+		{Key: "pool", Slot: 3, Time: start.Add(30 * time.Second), Price: 12, Volume: 1},
+		{Key: "pool", Slot: 1, Time: start.Add(10 * time.Second), Price: 10, Volume: 2},
+		{Key: "pool", Slot: 2, Time: start.Add(20 * time.Second), Price: 9, Volume: 1},
+	})
+	require.Empty(t, emitted)
+
+	// an out of order swap within the grace period updates the bar without moving its open
+	aggregator.AddSwap(&pb.GetSwapsStreamResponse{
+		Slot:      4,
+		Timestamp: timestamppb.New(start.Add(62 * time.Second)),
+		Swap:      &pb.GetSwapsStreamUpdate{Success: true, PoolAddress: "pool", InTokenAddress: USDCMint, InAmount: 33, OutTokenAddress: "token", OutAmountMin: 3},
+	})
+	aggregator.AddSwap(&pb.GetSwapsStreamResponse{
+		Slot:      0,
+		Timestamp: timestamppb.New(start.Add(59 * time.Second)),
+		Swap:      &pb.GetSwapsStreamUpdate{Success: true, PoolAddress: "pool", InTokenAddress: "token", InAmount: 1, OutTokenAddress: USDCMint, OutAmountMin: 8},
+	})
+	require.Empty(t, emitted)
+
+	aggregator.Advance(start.Add(65 * time.Second))
+	require.Equal(t, 1, len(emitted))
+	require.Equal(t, Candle{
+		Key:         "pool",
+		Start:       start,
+		Interval:    time.Minute,
+		Open:        8,
+		High:        12,
+		Low:         8,
+		Close:       12,
+		Volume:      5,
+		QuoteVolume: 49,
+		Trades:      4,
+		FirstSlot:   0,
+		LastSlot:    3,
+		Closed:      true,
+	}, emitted[0])
+
+	// a tick after the bar closed revises it
+	aggregator.Add(Tick{Key: "pool", Slot: 5, Time: start.Add(50 * time.Second), Price: 13, Volume: 1})
+	require.Equal(t, 2, len(emitted))
+	require.True(t, emitted[1].Revised)
+	require.Equal(t, 13.0, emitted[1].Close)
+
+	candles := aggregator.Candles("pool", time.Minute)
+	require.Equal(t, 2, len(candles))
+	require.False(t, candles[1].Closed)
+	require.Equal(t, 11.0, candles[1].Open)
+
+	aggregator.Close()
+	streamer := aggregator.Streamer()
+	for range emitted {
+		_, err := streamer()
+		require.NoError(t, err)
+	}
+	_, err := streamer()
+	require.ErrorIs(t, err, ErrClosed)
+}
+
+func TestAggregatorCloseOrder(t *testing.T) {
+	var emitted []Candle
+	aggregator := NewAggregator(Opts{
+		Intervals: []time.Duration{time.Minute, 5 * time.Minute},
+		Grace:     10 * time.Minute,
+		History:   2,
+		OnCandle:  func(candle Candle) { emitted = append(emitted, candle) },
+	})
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// the first one minute bar of "a" falls out of the kept history before it closes, so it's never emitted
+	aggregator.Add(Tick{Key: "b", Slot: 1, Time: start.Add(30 * time.Second), Price: 1, Volume: 1})
+	for minute := 0; minute < 3; minute++ {
+		aggregator.Add(Tick{Key: "a", Slot: int64(minute), Time: start.Add(time.Duration(minute)*time.Minute + 30*time.Second), Price: 1, Volume: 1})
+	}
+	require.Empty(t, emitted)
+
+	// bars of every series and interval close in order of their end
+	aggregator.Advance(start.Add(20 * time.Minute))
+	require.Equal(t, 5, len(emitted))
+	require.Equal(t, "b", emitted[0].Key)
+	require.Equal(t, start.Add(time.Minute), emitted[1].Start)
+	for i, candle := range emitted {
+		require.True(t, candle.Closed)
+		if i > 0 {
+			require.False(t, candle.End().Before(emitted[i-1].End()))
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package connections
 
 import (
+	"context"
 	"fmt"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
 	"io"
 )
 
@@ -20,3 +22,41 @@ func GRPCStream[T any](stream grpc.ClientStream, input string) Streamer[*T] {
 
 	return generator
 }
+
+// GRPCDisconnected returns a channel that is closed when a connection fails to connect, drops after it was ready, or
+// is shut down. GRPC moves a connection to idle both after its idle timeout and when its server goes away, so idle
+// connections are reconnected right away and only disconnect if that fails. Watching stops when ctx is canceled.
+func GRPCDisconnected(ctx context.Context, conn *grpc.ClientConn) <-chan struct{} {
+	return grpcDisconnected(ctx, conn)
+}
+
+// connectivityWatcher is the part of *grpc.ClientConn that reports connectivity changes
+type connectivityWatcher interface {
+	GetState() connectivity.State
+	WaitForStateChange(ctx context.Context, sourceState connectivity.State) bool
+	Connect()
+}
+
+func grpcDisconnected(ctx context.Context, conn connectivityWatcher) <-chan struct{} {
+	ch := make(chan struct{})
+	go func() {
+		previous := connectivity.Idle
+		for {
+			state := conn.GetState()
+			switch {
+			case state == connectivity.TransientFailure, state == connectivity.Shutdown,
+				state == connectivity.Connecting && previous == connectivity.Ready:
+				close(ch)
+				return
+			case state == connectivity.Idle:
+				conn.Connect()
+			}
+
+			if !conn.WaitForStateChange(ctx, state) {
+				return
+			}
+			previous = state
+		}
+	}()
+	return ch
+}
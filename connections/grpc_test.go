@@ -0,0 +1,116 @@
+package connections
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/connectivity"
+)
+
+// fakeConnectivity reports the states sent on its channel, one change at a time, and counts reconnects
+type fakeConnectivity struct {
+	mutex    sync.Mutex
+	state    connectivity.State
+	connects int
+	changes  chan connectivity.State
+}
+
+func newFakeConnectivity(state connectivity.State) *fakeConnectivity {
+	return &fakeConnectivity{state: state, changes: make(chan connectivity.State)}
+}
+
+func (f *fakeConnectivity) GetState() connectivity.State {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return f.state
+}
+
+func (f *fakeConnectivity) Connect() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.connects++
+}
+
+func (f *fakeConnectivity) WaitForStateChange(ctx context.Context, _ connectivity.State) bool {
+	select {
+	case state := <-f.changes:
+		f.mutex.Lock()
+		f.state = state
+		f.mutex.Unlock()
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func TestGRPCDisconnected(t *testing.T) {
+	tests := []struct {
+		name         string
+		states       []connectivity.State
+		disconnected bool
+		connects     int
+	}{
+		{
+			name:     "idle connection reconnects",
+			states:   []connectivity.State{connectivity.Ready, connectivity.Idle, connectivity.Connecting, connectivity.Ready},
+			connects: 2,
+		},
+		{
+			name:         "idle connection fails to reconnect",
+			states:       []connectivity.State{connectivity.Ready, connectivity.Idle, connectivity.Connecting, connectivity.TransientFailure},
+			disconnected: true,
+			connects:     2,
+		},
+		{
+			name:         "ready connection drops",
+			states:       []connectivity.State{connectivity.Ready, connectivity.Connecting},
+			disconnected: true,
+			connects:     1,
+		},
+		{
+			name:         "connection fails",
+			states:       []connectivity.State{connectivity.Connecting, connectivity.TransientFailure},
+			disconnected: true,
+			connects:     1,
+		},
+		{
+			name:         "connection shut down",
+			states:       []connectivity.State{connectivity.Ready, connectivity.Idle, connectivity.Shutdown},
+			disconnected: true,
+			connects:     2,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			conn := newFakeConnectivity(connectivity.Idle)
+			disconnected := grpcDisconnected(ctx, conn)
+			for _, state := range test.states {
+				select {
+				case conn.changes <- state:
+				case <-disconnected:
+					t.Fatalf("disconnected before state %v", state)
+				case <-time.After(time.Second):
+					t.Fatalf("state %v was not observed", state)
+				}
+			}
+
+			select {
+			case <-disconnected:
+				require.True(t, test.disconnected)
+			case <-time.After(50 * time.Millisecond):
+				require.False(t, test.disconnected)
+			}
+
+			// the watcher starts idle, so it connects once before any state is sent
+			conn.mutex.Lock()
+			defer conn.mutex.Unlock()
+			require.Equal(t, test.connects, conn.connects)
+		})
+	}
+}
@@ -42,6 +42,9 @@ type WS struct {
 
 	subscriptionMap map[string]subscriptionEntry
 
+	disconnectM sync.Mutex
+	disconnects []chan struct{}
+
 	// public to allow overriding of (un)subscribe method name
 	SubscribeMethodName   string
 	UnsubscribeMethodName string
@@ -106,6 +109,8 @@ func (w *WS) readLoop() {
 
 		_, msg, err := w.conn.ReadMessage()
 		if err != nil {
+			w.notifyDisconnect()
+
 			// reconnect the websocket connection if connection read message fails
 			for {
 				select {
@@ -413,6 +418,31 @@ func wsStream[T any](w *WS, ctx context.Context, streamName string, streamParams
 	}, nil
 }
 
+// Disconnected returns a channel that is closed the next time the connection drops, even if it's re-established
+// afterwards, or immediately if the connection has been closed
+func (w *WS) Disconnected() <-chan struct{} {
+	w.disconnectM.Lock()
+	defer w.disconnectM.Unlock()
+
+	ch := make(chan struct{})
+	if w.ctx.Err() != nil {
+		close(ch)
+		return ch
+	}
+	w.disconnects = append(w.disconnects, ch)
+	return ch
+}
+
+func (w *WS) notifyDisconnect() {
+	w.disconnectM.Lock()
+	defer w.disconnectM.Unlock()
+
+	for _, ch := range w.disconnects {
+		close(ch)
+	}
+	w.disconnects = nil
+}
+
 func (w *WS) Close(reason error) error {
 	w.messageM.Lock()
 	defer w.messageM.Unlock()
@@ -427,6 +457,7 @@ func (w *WS) Close(reason error) error {
 
 	// cancel main connection ctx
 	w.cancel()
+	w.notifyDisconnect()
 
 	// cancel all subscriptions
 	for _, sub := range w.subscriptionMap {
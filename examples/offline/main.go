@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/provider"
+	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	FileFlag = &cli.StringFlag{
+		Name:     "file",
+		Usage:    "path of the offline transaction file",
+		Required: true,
+	}
+
+	OutputFlag = &cli.StringFlag{
+		Name:  "output",
+		Usage: "path to write the updated offline file to (defaults to overwriting --file)",
+	}
+
+	KeyFileFlag = &cli.StringFlag{
+		Name:     "key-file",
+		Usage:    "private key file, either a Solana keygen JSON file or a base58 encoded key",
+		Required: true,
+	}
+
+	ProviderFlag = &cli.StringFlag{
+		Name:  "provider",
+		Usage: "provider to submit with: http, ws or grpc",
+		Value: "http",
+	}
+
+	EndpointFlag = &cli.StringFlag{
+		Name:  "endpoint",
+		Usage: "Trader API endpoint (defaults to the mainnet NY endpoint of the provider)",
+	}
+
+	UseBundleFlag = &cli.BoolFlag{
+		Name:  "use-bundle",
+		Usage: "submit the transactions as a bundle",
+	}
+
+	OwnerFlag = &cli.StringFlag{
+		Name:     "owner",
+		Usage:    "wallet address that signs the swap",
+		Required: true,
+	}
+
+	InTokenFlag = &cli.StringFlag{
+		Name:     "in-token",
+		Required: true,
+	}
+
+	OutTokenFlag = &cli.StringFlag{
+		Name:     "out-token",
+		Required: true,
+	}
+
+	AmountFlag = &cli.Float64Flag{
+		Name:     "amount",
+		Usage:    "amount of in-token to swap",
+		Required: true,
+	}
+
+	SlippageFlag = &cli.Float64Flag{
+		Name:  "slippage",
+		Usage: "slippage in percent",
+		Value: 0.5,
+	}
+
+	ProjectFlag = &cli.StringFlag{
+		Name:  "project",
+		Usage: "project to swap on: raydium, jupiter or all",
+		Value: "raydium",
+	}
+)
+
+func main() {
+	app := &cli.App{
+		Name:  "offline",
+		Usage: "Exports Trader API transactions for offline signing, signs them with a key file and submits them later",
+		Commands: []*cli.Command{
+			{
+				Name:   "export-swap",
+				Usage:  "requests an unsigned swap with PostTradeSwap and exports it to an offline file",
+				Flags:  []cli.Flag{FileFlag, EndpointFlag, OwnerFlag, InTokenFlag, OutTokenFlag, AmountFlag, SlippageFlag, ProjectFlag},
+				Action: exportSwap,
+			},
+			{
+				Name:   "inspect",
+				Usage:  "prints a readable summary of an offline file",
+				Flags:  []cli.Flag{FileFlag},
+				Action: inspect,
+			},
+			{
+				Name:   "sign",
+				Usage:  "signs an offline file with a key file, without network access",
+				Flags:  []cli.Flag{FileFlag, OutputFlag, KeyFileFlag},
+				Action: sign,
+			},
+			{
+				Name:   "submit",
+				Usage:  "submits a signed offline file with PostSubmitBatch",
+				Flags:  []cli.Flag{FileFlag, ProviderFlag, EndpointFlag, UseBundleFlag},
+				Action: submit,
+			},
+		},
+	}
+
+	if err := app.Run(os.Args); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func exportSwap(c *cli.Context) error {
+	endpoint := c.String(EndpointFlag.Name)
+	if endpoint == "" {
+		endpoint = provider.MainnetNYHTTP
+	}
+	project, err := provider.ProjectFromString(c.String(ProjectFlag.Name))
+	if err != nil {
+		return err
+	}
+
+	client := provider.NewHTTPClientWithOpts(http.DefaultClient, provider.DefaultRPCOpts(endpoint))
+	response, err := client.PostTradeSwap(c.Context, c.String(OwnerFlag.Name), c.String(InTokenFlag.Name),
+		c.String(OutTokenFlag.Name), c.Float64(AmountFlag.Name), c.Float64(SlippageFlag.Name), project)
+	if err != nil {
+		return err
+	}
+
+	file, err := provider.ExportOfflineFile("PostTradeSwap", response, response.Transactions)
+	if err != nil {
+		return err
+	}
+	if err := transaction.WriteOfflineFile(c.String(FileFlag.Name), file); err != nil {
+		return err
+	}
+
+	printSummary(file)
+	return nil
+}
+
+func inspect(c *cli.Context) error {
+	file, err := transaction.ReadOfflineFile(c.String(FileFlag.Name))
+	if err != nil {
+		return err
+	}
+
+	printSummary(file)
+	return nil
+}
+
+func sign(c *cli.Context) error {
+	file, err := transaction.ReadOfflineFile(c.String(FileFlag.Name))
+	if err != nil {
+		return err
+	}
+	privateKey, err := loadKeyFile(c.String(KeyFileFlag.Name))
+	if err != nil {
+		return err
+	}
+
+	if err := file.Sign(privateKey, nil, time.Now()); err != nil {
+		return err
+	}
+
+	output := c.String(OutputFlag.Name)
+	if output == "" {
+		output = c.String(FileFlag.Name)
+	}
+	if err := transaction.WriteOfflineFile(output, file); err != nil {
+		return err
+	}
+
+	printSummary(file)
+	return nil
+}
+
+func submit(c *cli.Context) error {
+	ctx, cancel := context.WithTimeout(c.Context, 30*time.Second)
+	defer cancel()
+
+	file, err := transaction.ReadOfflineFile(c.String(FileFlag.Name))
+	if err != nil {
+		return err
+	}
+
+	endpoint := c.String(EndpointFlag.Name)
+	opts := provider.SubmitOpts{SubmitStrategy: pb.SubmitStrategy_P_SUBMIT_ALL}
+	useBundle := c.Bool(UseBundleFlag.Name)
+
+	var submitter func(ctx context.Context) error
+	switch c.String(ProviderFlag.Name) {
+	case "http":
+		if endpoint == "" {
+			endpoint = provider.MainnetNYHTTP
+		}
+		client := provider.NewHTTPClientWithOpts(http.DefaultClient, provider.DefaultRPCOpts(endpoint))
+		submitter = func(ctx context.Context) error {
+			return printSubmission(provider.SubmitOfflineFile(ctx, client.PostSubmitBatch, file, useBundle, opts))
+		}
+	case "ws":
+		if endpoint == "" {
+			endpoint = provider.MainnetNYWS
+		}
+		client, err := provider.NewWSClientWithOpts(provider.DefaultRPCOpts(endpoint))
+		if err != nil {
+			return err
+		}
+		defer func() { _ = client.Close() }()
+		submitter = func(ctx context.Context) error {
+			return printSubmission(provider.SubmitOfflineFile(ctx, client.PostSubmitBatch, file, useBundle, opts))
+		}
+	case "grpc":
+		if endpoint == "" {
+			endpoint = provider.MainnetNYGRPC
+		}
+		client, err := provider.NewGRPCClientWithOpts(provider.DefaultRPCOpts(endpoint))
+		if err != nil {
+			return err
+		}
+		submitter = func(ctx context.Context) error {
+			return printSubmission(provider.SubmitOfflineFile(ctx, client.PostSubmitBatch, file, useBundle, opts))
+		}
+	default:
+		return fmt.Errorf("unknown provider %v", c.String(ProviderFlag.Name))
+	}
+
+	return submitter(ctx)
+}
+
+// loadKeyFile reads a Solana keygen JSON file, falling back to a base58 encoded key
+func loadKeyFile(path string) (solana.PrivateKey, error) {
+	privateKey, err := solana.PrivateKeyFromSolanaKeygenFile(path)
+	if err == nil {
+		return privateKey, nil
+	}
+
+	b, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return nil, readErr
+	}
+	privateKey, base58Err := solana.PrivateKeyFromBase58(strings.TrimSpace(string(b)))
+	if base58Err != nil {
+		return nil, errors.New("key file is neither a Solana keygen file nor a base58 encoded key")
+	}
+	return privateKey, nil
+}
+
+func printSummary(file *transaction.OfflineFile) {
+	fmt.Printf("%v (created %v)\n", file.Description, file.CreatedAt.Format(time.RFC3339))
+	if file.ExpiresAt != nil {
+		fmt.Printf("expires: %v\n", file.ExpiresAt.Format(time.RFC3339))
+	} else {
+		fmt.Println("expires: never (durable nonce)")
+	}
+	if len(file.Metadata) != 0 {
+		fmt.Printf("metadata: %s\n", file.Metadata)
+	}
+	for i, offlineTx := range file.Transactions {
+		fmt.Printf("transaction #%v: fee payer %v, signed=%v, cleanup=%v, SOL outflow %v lamports\n",
+			i, offlineTx.FeePayer, offlineTx.Signed, offlineTx.IsCleanup, offlineTx.SOLOutflow)
+		for _, instruction := range offlineTx.Instructions {
+			fmt.Printf("    %v\n", instruction)
+		}
+	}
+}
+
+func printSubmission(response interface{ String() string }, err error) error {
+	if err != nil {
+		return err
+	}
+	fmt.Println(response.String())
+	return nil
+}
@@ -0,0 +1,359 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultVWAPInterval = 10 * time.Second
+
+var (
+	ErrPriceLimit    = errors.New("quoted price below limit")
+	ErrVolumeClosed  = errors.New("volume source closed")
+	ErrScheduleEnded = errors.New("schedule ended before the order was filled")
+)
+
+// SwapFunc submits a child swap of inAmount of the parent order's input token
+type SwapFunc func(ctx context.Context, inAmount float64) (*pb.PostSubmitBatchResponse, error)
+
+// QuoteFunc returns the output amount currently quoted for a swap of inAmount
+type QuoteFunc func(ctx context.Context, inAmount float64) (float64, error)
+
+// ConfirmFunc waits until the transactions of a submitted child swap land, returning an error if any of them failed
+// or expired
+type ConfirmFunc func(ctx context.Context, response *pb.PostSubmitBatchResponse) error
+
+// Volume is market volume observed by a volume source, in units of the parent order's input token
+type Volume struct {
+	Time   time.Time
+	Amount float64
+}
+
+type Opts struct {
+	// InAmount is the size of the parent order in its input token
+	InAmount float64
+
+	// Duration is the length of a TWAP schedule. VWAP executions stop after Duration if it's set.
+	Duration time.Duration
+
+	// Slices is the number of child swaps of a TWAP schedule
+	Slices int
+
+	// Interval is how often VWAP executions size a child swap from the observed volume. Defaults to 10 seconds.
+	Interval time.Duration
+
+	// Participation is the fraction of observed volume VWAP executions trade
+	Participation float64
+
+	// MaxParticipation caps each child swap at this fraction of the volume observed since the previous child. It
+	// requires a volume source and is disabled if 0.
+	MaxParticipation float64
+
+	// LimitPrice skips child swaps whose live quote pays fewer output tokens per input token. Disabled if 0.
+	LimitPrice float64
+
+	// MinChildAmount defers child swaps smaller than this until more can be traded at once
+	MinChildAmount float64
+
+	// Confirm waits for each child swap to land before the next one is sized, e.g. NewConfirmFunc. Child swaps that
+	// don't land are rolled into later children. Without it, submitted child swaps are assumed to land.
+	Confirm ConfirmFunc
+}
+
+// Child is one child swap of an execution
+type Child struct {
+	Time            time.Time
+	InAmount        float64
+	QuotedOutAmount float64
+	Response        *pb.PostSubmitBatchResponse
+	Err             error
+}
+
+// Report summarizes the child swaps of an execution
+type Report struct {
+	Start time.Time
+	End   time.Time
+
+	TargetAmount float64
+
+	// ExecutedAmount is the input amount of child swaps that landed, or were submitted if Opts.Confirm is not set
+	ExecutedAmount  float64
+	QuotedOutAmount float64
+	Children        []Child
+
+	// Err is why the execution stopped before executing the target amount
+	Err error
+}
+
+// AveragePrice is the quoted output tokens per input token over all executed child swaps
+func (r Report) AveragePrice() float64 {
+	if r.ExecutedAmount == 0 {
+		return 0
+	}
+	return r.QuotedOutAmount / r.ExecutedAmount
+}
+
+// Execution splits a parent order into child swaps over time. Child swaps that fail or are skipped because of the
+// price limit are rolled into later children.
+type Execution struct {
+	mutex  sync.Mutex
+	swap   SwapFunc
+	quote  QuoteFunc
+	volume <-chan Volume
+	vwap   bool
+	opts   Opts
+
+	interval time.Duration
+	paused   bool
+	ticks    int
+	window   float64
+	report   Report
+	done     chan struct{}
+}
+
+// NewTWAP starts executing the parent order in Slices equal child swaps spread over Duration. The volume source is
+// only used for participation caps and may be nil.
+func NewTWAP(ctx context.Context, swap SwapFunc, quote QuoteFunc, volume <-chan Volume, opts Opts) (*Execution, error) {
+	if swap == nil || quote == nil {
+		return nil, errors.New("TWAP requires a swap and a quote function")
+	}
+	if opts.Slices <= 0 || opts.Duration <= 0 {
+		return nil, errors.New("TWAP requires a positive number of slices and duration")
+	}
+
+	e := newExecution(swap, quote, volume, false, opts.Duration/time.Duration(opts.Slices), opts)
+	go e.run(ctx)
+	return e, nil
+}
+
+// NewVWAP starts executing the parent order in child swaps sized to a fraction of the volume observed on the volume
+// source, e.g. SwapsVolume or TradesVolume
+func NewVWAP(ctx context.Context, swap SwapFunc, quote QuoteFunc, volume <-chan Volume, opts Opts) (*Execution, error) {
+	if swap == nil || quote == nil {
+		return nil, errors.New("VWAP requires a swap and a quote function")
+	}
+	if volume == nil {
+		return nil, errors.New("VWAP requires a volume source")
+	}
+	if opts.Participation <= 0 {
+		return nil, errors.New("VWAP requires a positive participation")
+	}
+	if opts.Interval == 0 {
+		opts.Interval = defaultVWAPInterval
+	}
+
+	e := newExecution(swap, quote, volume, true, opts.Interval, opts)
+	go e.run(ctx)
+	return e, nil
+}
+
+func newExecution(swap SwapFunc, quote QuoteFunc, volume <-chan Volume, vwap bool, interval time.Duration, opts Opts) *Execution {
+	return &Execution{
+		swap:     swap,
+		quote:    quote,
+		volume:   volume,
+		vwap:     vwap,
+		opts:     opts,
+		interval: interval,
+		report:   Report{Start: time.Now(), TargetAmount: opts.InAmount},
+		done:     make(chan struct{}),
+	}
+}
+
+// Pause stops submitting child swaps until Resume is called. Scheduled TWAP slices that pass while paused are rolled
+// into later slices, and volume observed while paused is ignored.
+func (e *Execution) Pause() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.paused = true
+}
+
+// Resume continues a paused execution
+func (e *Execution) Resume() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.paused = false
+}
+
+// Paused returns whether the execution is paused
+func (e *Execution) Paused() bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.paused
+}
+
+// Done returns a channel that is closed when the execution has finished
+func (e *Execution) Done() <-chan struct{} {
+	return e.done
+}
+
+// Report returns the progress of the execution, or its final report once Done is closed
+func (e *Execution) Report() Report {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	report := e.report
+	report.Children = append([]Child(nil), e.report.Children...)
+	return report
+}
+
+func (e *Execution) remaining() float64 {
+	return e.opts.InAmount - e.report.ExecutedAmount
+}
+
+// childAmount sizes the next child swap before participation caps are applied. TWAP spreads the remaining amount
+// over the remaining slices, VWAP trades its participation of the volume observed since the previous child.
+func (e *Execution) childAmount() float64 {
+	if e.vwap {
+		return e.opts.Participation * e.window
+	}
+
+	remainingSlices := e.opts.Slices - e.ticks + 1
+	if remainingSlices < 1 {
+		remainingSlices = 1
+	}
+	return e.remaining() / float64(remainingSlices)
+}
+
+func (e *Execution) run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	// TWAP schedules end with their last slice, so only VWAP executions need a deadline
+	var deadline <-chan time.Time
+	if e.vwap && e.opts.Duration > 0 {
+		timer := time.NewTimer(e.opts.Duration)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	for {
+		select {
+		case volume, ok := <-e.volume:
+			if !ok {
+				if e.vwap {
+					e.finish(ErrVolumeClosed)
+					return
+				}
+				e.volume = nil
+				continue
+			}
+			e.observe(volume)
+		case <-ticker.C:
+			if e.tick(ctx) {
+				e.finish(ErrScheduleEnded)
+				return
+			}
+		case <-deadline:
+			e.finish(ErrScheduleEnded)
+			return
+		case <-ctx.Done():
+			e.finish(ctx.Err())
+			return
+		}
+	}
+}
+
+func (e *Execution) observe(volume Volume) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if !e.paused {
+		e.window += volume.Amount
+	}
+}
+
+// tick submits the next child swap if one is due, and returns whether the execution is complete
+func (e *Execution) tick(ctx context.Context) bool {
+	e.mutex.Lock()
+	e.ticks++
+	if e.paused {
+		scheduleEnded := !e.vwap && e.ticks >= e.opts.Slices
+		e.mutex.Unlock()
+		return scheduleEnded
+	}
+
+	amount := e.childAmount()
+	if e.opts.MaxParticipation > 0 && e.volume != nil {
+		amount = math.Min(amount, e.opts.MaxParticipation*e.window)
+	}
+	remaining := e.remaining()
+	amount = math.Min(amount, remaining)
+	if amount <= 0 || (amount < e.opts.MinChildAmount && amount < remaining) {
+		// keep accumulating volume until the child is large enough
+		scheduleEnded := !e.vwap && e.ticks >= e.opts.Slices
+		e.mutex.Unlock()
+		return scheduleEnded
+	}
+	e.mutex.Unlock()
+
+	child := e.execute(ctx, amount)
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.report.Children = append(e.report.Children, child)
+	if child.Err == nil {
+		// the volume stays in the window of failed children, so the next child can make up for them
+		e.window = 0
+		e.report.ExecutedAmount += child.InAmount
+		e.report.QuotedOutAmount += child.QuotedOutAmount
+	} else {
+		log.Warnf("child swap of %v failed: %v", child.InAmount, child.Err)
+	}
+
+	return e.remaining() <= 0 || (!e.vwap && e.ticks >= e.opts.Slices)
+}
+
+func (e *Execution) execute(ctx context.Context, amount float64) Child {
+	child := Child{Time: time.Now(), InAmount: amount}
+
+	outAmount, err := e.quote(ctx, amount)
+	if err != nil {
+		child.Err = fmt.Errorf("could not quote child swap: %w", err)
+		return child
+	}
+	child.QuotedOutAmount = outAmount
+	if e.opts.LimitPrice > 0 && outAmount/amount < e.opts.LimitPrice {
+		child.Err = fmt.Errorf("%w: %v < %v", ErrPriceLimit, outAmount/amount, e.opts.LimitPrice)
+		return child
+	}
+
+	child.Response, child.Err = e.swap(ctx, amount)
+	if child.Err != nil {
+		return child
+	}
+	for _, entry := range child.Response.Transactions {
+		if !entry.Submitted {
+			child.Err = fmt.Errorf("transaction %v was not submitted: %v", entry.Signature, entry.Error)
+			return child
+		}
+	}
+
+	if e.opts.Confirm != nil {
+		if err := e.opts.Confirm(ctx, child.Response); err != nil {
+			child.Err = fmt.Errorf("child swap did not land: %w", err)
+		}
+	}
+	return child
+}
+
+// finish records why the execution stopped if the order wasn't filled
+func (e *Execution) finish(err error) {
+	e.mutex.Lock()
+	e.report.End = time.Now()
+	if e.remaining() > 0 {
+		e.report.Err = err
+	}
+	e.mutex.Unlock()
+	close(e.done)
+}
@@ -0,0 +1,196 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/provider"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	_ TradeSwapper   = (*provider.HTTPClient)(nil)
+	_ TradeSwapper   = (*provider.GRPCClient)(nil)
+	_ RaydiumSwapper = (*provider.WSClient)(nil)
+	_ Quoter         = (*provider.WSClient)(nil)
+)
+
+type fakeSwaps struct {
+	mutex   sync.Mutex
+	amounts []float64
+}
+
+func (f *fakeSwaps) swap(_ context.Context, inAmount float64) (*pb.PostSubmitBatchResponse, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.amounts = append(f.amounts, inAmount)
+	return &pb.PostSubmitBatchResponse{
+		Transactions: []*pb.PostSubmitBatchResponseEntry{{Signature: "signature", Submitted: true}},
+	}, nil
+}
+
+func TestTWAP(t *testing.T) {
+	swaps := &fakeSwaps{}
+
+	// the second quote is below the limit, so its slice rolls into the remaining slices
+	quotes := 0
+	quote := func(_ context.Context, inAmount float64) (float64, error) {
+		quotes++
+		if quotes == 2 {
+			return inAmount, nil
+		}
+		return inAmount * 2, nil
+	}
+
+	execution, err := NewTWAP(context.Background(), swaps.swap, quote, nil, Opts{
+		InAmount:   12,
+		Duration:   40 * time.Millisecond,
+		Slices:     4,
+		LimitPrice: 1.5,
+	})
+	require.NoError(t, err)
+
+	select {
+	case <-execution.Done():
+	case <-time.After(time.Second):
+		require.Fail(t, "TWAP did not finish")
+	}
+
+	report := execution.Report()
+	require.NoError(t, report.Err)
+	require.Equal(t, 4, len(report.Children))
+	require.ErrorIs(t, report.Children[1].Err, ErrPriceLimit)
+	require.Equal(t, []float64{3, 4.5, 4.5}, swaps.amounts)
+	require.Equal(t, float64(12), report.ExecutedAmount)
+	require.Equal(t, float64(2), report.AveragePrice())
+}
+
+func TestVWAPParticipation(t *testing.T) {
+	swaps := &fakeSwaps{}
+	quote := func(_ context.Context, inAmount float64) (float64, error) {
+		return inAmount, nil
+	}
+
+	volume := make(chan Volume, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	execution, err := NewVWAP(ctx, swaps.swap, quote, volume, Opts{
+		InAmount:         10,
+		Interval:         20 * time.Millisecond,
+		Participation:    0.5,
+		MaxParticipation: 0.25,
+	})
+	require.NoError(t, err)
+
+	// child swaps follow the observed volume, capped by the participation limit
+	volume <- Volume{Time: time.Now(), Amount: 8}
+	require.Eventually(t, func() bool { return len(execution.Report().Children) == 1 }, time.Second, 5*time.Millisecond)
+
+	execution.Pause()
+	volume <- Volume{Time: time.Now(), Amount: 100}
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, 1, len(execution.Report().Children))
+
+	execution.Resume()
+	volume <- Volume{Time: time.Now(), Amount: 100}
+	<-execution.Done()
+
+	report := execution.Report()
+	require.NoError(t, report.Err)
+	require.Equal(t, []float64{2, 8}, swaps.amounts)
+}
+
+func TestTWAPConfirm(t *testing.T) {
+	swaps := &fakeSwaps{}
+	quote := func(_ context.Context, inAmount float64) (float64, error) {
+		return inAmount, nil
+	}
+
+	// the second child is submitted but doesn't land, so it's rolled into the remaining slices
+	confirms := 0
+	confirm := func(_ context.Context, _ *pb.PostSubmitBatchResponse) error {
+		confirms++
+		if confirms == 2 {
+			return provider.ErrBlockHashExpired
+		}
+		return nil
+	}
+
+	execution, err := NewTWAP(context.Background(), swaps.swap, quote, nil, Opts{
+		InAmount: 12,
+		Duration: 40 * time.Millisecond,
+		Slices:   4,
+		Confirm:  confirm,
+	})
+	require.NoError(t, err)
+	<-execution.Done()
+
+	report := execution.Report()
+	require.NoError(t, report.Err)
+	require.Equal(t, []float64{3, 3, 4.5, 4.5}, swaps.amounts)
+	require.ErrorIs(t, report.Children[1].Err, provider.ErrBlockHashExpired)
+	require.Equal(t, float64(12), report.ExecutedAmount)
+	require.Equal(t, float64(12), report.QuotedOutAmount)
+}
+
+func TestVWAPWindowAfterFailure(t *testing.T) {
+	swaps := &fakeSwaps{}
+	quotes := 0
+	quote := func(_ context.Context, inAmount float64) (float64, error) {
+		quotes++
+		if quotes == 1 {
+			return 0, errors.New("no route")
+		}
+		return inAmount, nil
+	}
+
+	volume := make(chan Volume, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	execution, err := NewVWAP(ctx, swaps.swap, quote, volume, Opts{
+		InAmount:      10,
+		Interval:      10 * time.Millisecond,
+		Participation: 0.5,
+	})
+	require.NoError(t, err)
+
+	// the failed child's volume is kept, so the next child trades participation of both windows
+	volume <- Volume{Time: time.Now(), Amount: 4}
+	require.Eventually(t, func() bool { return len(execution.Report().Children) == 1 }, time.Second, time.Millisecond)
+	volume <- Volume{Time: time.Now(), Amount: 4}
+	require.Eventually(t, func() bool { return len(execution.Report().Children) == 2 }, time.Second, time.Millisecond)
+	volume <- Volume{Time: time.Now(), Amount: 20}
+	<-execution.Done()
+
+	report := execution.Report()
+	require.NoError(t, report.Err)
+	require.Error(t, report.Children[0].Err)
+	require.Equal(t, []float64{4, 6}, swaps.amounts)
+	require.Equal(t, float64(10), report.ExecutedAmount)
+}
+
+func TestExecutionRequiresFunctions(t *testing.T) {
+	swaps := &fakeSwaps{}
+	quote := func(_ context.Context, inAmount float64) (float64, error) {
+		return inAmount, nil
+	}
+	ctx := context.Background()
+	twap := Opts{InAmount: 1, Duration: time.Second, Slices: 1}
+	vwap := Opts{InAmount: 1, Participation: 0.1}
+
+	_, err := NewTWAP(ctx, swaps.swap, nil, nil, twap)
+	require.Error(t, err)
+	_, err = NewTWAP(ctx, nil, quote, nil, twap)
+	require.Error(t, err)
+	_, err = NewVWAP(ctx, swaps.swap, nil, make(chan Volume), vwap)
+	require.Error(t, err)
+	_, err = NewVWAP(ctx, nil, quote, make(chan Volume), vwap)
+	require.Error(t, err)
+}
@@ -0,0 +1,184 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	"github.com/bloXroute-Labs/solana-trader-client-go/provider"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"google.golang.org/protobuf/proto"
+)
+
+// Quoter is implemented by the HTTP, WS and GRPC clients
+type Quoter interface {
+	GetQuotes(ctx context.Context, inToken, outToken string, inAmount, slippage float64, limit int32, projects []pb.Project) (*pb.GetQuotesResponse, error)
+}
+
+// TradeSwapper is implemented by the HTTP and GRPC clients. The WS client takes its project as a string, so wrap its
+// SubmitTradeSwap in a SwapFunc directly.
+type TradeSwapper interface {
+	SubmitTradeSwap(ctx context.Context, owner, inToken, outToken string, inAmount, slippage float64, project pb.Project, opts provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error)
+}
+
+// RaydiumSwapper is implemented by the HTTP, WS and GRPC clients
+type RaydiumSwapper interface {
+	SubmitRaydiumSwap(ctx context.Context, request *pb.PostRaydiumSwapRequest, opts provider.SubmitOpts) (*pb.PostSubmitBatchResponse, error)
+}
+
+// NewQuoteFunc quotes child swaps with GetQuotes, returning the best output amount over the projects
+func NewQuoteFunc(quoter Quoter, inToken, outToken string, slippage float64, projects []pb.Project) QuoteFunc {
+	return func(ctx context.Context, inAmount float64) (float64, error) {
+		response, err := quoter.GetQuotes(ctx, inToken, outToken, inAmount, slippage, 1, projects)
+		if err != nil {
+			return 0, err
+		}
+
+		var best float64
+		for _, quote := range response.Quotes {
+			for _, route := range quote.Routes {
+				if route.OutAmount > best {
+					best = route.OutAmount
+				}
+			}
+		}
+		if best == 0 {
+			return 0, errors.New("no quotes returned")
+		}
+		return best, nil
+	}
+}
+
+// NewTradeSwapFunc submits child swaps with SubmitTradeSwap
+func NewTradeSwapFunc(swapper TradeSwapper, owner, inToken, outToken string, slippage float64, project pb.Project, opts provider.SubmitOpts) SwapFunc {
+	return func(ctx context.Context, inAmount float64) (*pb.PostSubmitBatchResponse, error) {
+		return swapper.SubmitTradeSwap(ctx, owner, inToken, outToken, inAmount, slippage, project, opts)
+	}
+}
+
+// NewRaydiumSwapFunc submits child swaps with SubmitRaydiumSwap, using the request with the child's InAmount
+func NewRaydiumSwapFunc(swapper RaydiumSwapper, request *pb.PostRaydiumSwapRequest, opts provider.SubmitOpts) SwapFunc {
+	return func(ctx context.Context, inAmount float64) (*pb.PostSubmitBatchResponse, error) {
+		childRequest := proto.Clone(request).(*pb.PostRaydiumSwapRequest)
+		childRequest.InAmount = inAmount
+		return swapper.SubmitRaydiumSwap(ctx, childRequest, opts)
+	}
+}
+
+// NewConfirmFunc waits for child swaps to reach the tracker's target commitment
+func NewConfirmFunc(tracker *provider.ConfirmationTracker) ConfirmFunc {
+	return func(ctx context.Context, response *pb.PostSubmitBatchResponse) error {
+		for _, future := range tracker.TrackBatch(response) {
+			result, err := future.Wait(ctx)
+			if err != nil {
+				return err
+			}
+			if result.Err != nil {
+				return result.Err
+			}
+		}
+		return nil
+	}
+}
+
+// SwapsVolume observes the volume of successful swaps on a GetSwapsStream stream in units of the token, counting
+// the input amount of swaps selling the token and the minimum output amount of swaps buying it
+func SwapsVolume(ctx context.Context, stream connections.Streamer[*pb.GetSwapsStreamResponse], tokenAddress string) <-chan Volume {
+	volumes := make(chan Volume, 100)
+	ch := stream.Channel(100)
+	go func() {
+		defer close(volumes)
+		for {
+			select {
+			case response, ok := <-ch:
+				if !ok {
+					return
+				}
+				swap := response.Swap
+				if swap == nil || !swap.Success {
+					continue
+				}
+
+				volume := Volume{Time: time.Now()}
+				if response.Timestamp != nil {
+					volume.Time = response.Timestamp.AsTime()
+				}
+				switch tokenAddress {
+				case swap.InTokenAddress:
+					volume.Amount = swap.InAmount
+				case swap.OutTokenAddress:
+					volume.Amount = swap.OutAmountMin
+				default:
+					continue
+				}
+
+				select {
+				case volumes <- volume:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return volumes
+}
+
+// TradesVolume observes the volume of trades on a GetTradesStream stream in units of the market's base token
+func TradesVolume(ctx context.Context, stream connections.Streamer[*pb.GetTradesStreamResponse]) <-chan Volume {
+	volumes := make(chan Volume, 100)
+	ch := stream.Channel(100)
+	go func() {
+		defer close(volumes)
+		for {
+			select {
+			case response, ok := <-ch:
+				if !ok {
+					return
+				}
+				if response.Trades == nil {
+					continue
+				}
+
+				volume := Volume{Time: time.Now()}
+				if response.Timestamp != nil {
+					volume.Time = response.Timestamp.AsTime()
+				}
+				for _, trade := range response.Trades.Trades {
+					volume.Amount += trade.Size
+				}
+
+				select {
+				case volumes <- volume:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return volumes
+}
+
+// NewTradeSwapTriggerFunc submits the swaps of fired triggers with SubmitTradeSwap
+func NewTradeSwapTriggerFunc(swapper TradeSwapper, opts provider.SubmitOpts) TriggerSwapFunc {
+	return func(ctx context.Context, swap TriggerSwap) (*pb.PostSubmitBatchResponse, error) {
+		return swapper.SubmitTradeSwap(ctx, swap.Owner, swap.InToken, swap.OutToken, swap.InAmount, swap.Slippage, swap.Project, opts)
+	}
+}
+
+// NewRaydiumSwapTriggerFunc submits the swaps of fired triggers with SubmitRaydiumSwap
+func NewRaydiumSwapTriggerFunc(swapper RaydiumSwapper, opts provider.SubmitOpts) TriggerSwapFunc {
+	return func(ctx context.Context, swap TriggerSwap) (*pb.PostSubmitBatchResponse, error) {
+		return swapper.SubmitRaydiumSwap(ctx, &pb.PostRaydiumSwapRequest{
+			OwnerAddress: swap.Owner,
+			InToken:      swap.InToken,
+			OutToken:     swap.OutToken,
+			InAmount:     swap.InAmount,
+			Slippage:     swap.Slippage,
+		}, opts)
+	}
+}
@@ -0,0 +1,499 @@
+package execution
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultTriggerBuffer = 100
+
+var ErrTriggerNotFound = errors.New("trigger not found")
+
+type TriggerKind string
+
+const (
+	// TriggerStopLoss fires when the price falls to Price or below
+	TriggerStopLoss TriggerKind = "stop-loss"
+
+	// TriggerTakeProfit fires when the price rises to Price or above
+	TriggerTakeProfit TriggerKind = "take-profit"
+
+	// TriggerTrailingStop fires when the price falls TrailingPercent below the highest price seen since it was added
+	TriggerTrailingStop TriggerKind = "trailing-stop"
+
+	// TriggerLimitBuy fires when the price falls to Price or below, buying on the AMM like a resting bid
+	TriggerLimitBuy TriggerKind = "limit-buy"
+
+	// TriggerLimitSell fires when the price rises to Price or above, selling on the AMM like a resting ask
+	TriggerLimitSell TriggerKind = "limit-sell"
+)
+
+type TriggerState string
+
+const (
+	TriggerActive    TriggerState = "active"
+	TriggerFiring    TriggerState = "firing"
+	TriggerFired     TriggerState = "fired"
+	TriggerFailed    TriggerState = "failed"
+	TriggerCancelled TriggerState = "cancelled"
+)
+
+// Price is an observed price of the watched token pair
+type Price struct {
+	Time  time.Time
+	Price float64
+}
+
+// TriggerSwap is the swap a trigger submits when it fires
+type TriggerSwap struct {
+	Owner    string     `json:"owner"`
+	InToken  string     `json:"inToken"`
+	OutToken string     `json:"outToken"`
+	InAmount float64    `json:"inAmount"`
+	Slippage float64    `json:"slippage"`
+	Project  pb.Project `json:"project,omitempty"`
+}
+
+// Trigger is a conditional swap on a token pair. Triggers sharing a Group are one-cancels-other: once one fires, the
+// others are cancelled.
+type Trigger struct {
+	ID              string      `json:"id"`
+	Kind            TriggerKind `json:"kind"`
+	Group           string      `json:"group,omitempty"`
+	Price           float64     `json:"price,omitempty"`
+	TrailingPercent float64     `json:"trailingPercent,omitempty"`
+	Swap            TriggerSwap `json:"swap"`
+
+	State TriggerState `json:"state"`
+
+	// Peak is the highest price a trailing stop has seen
+	Peak float64 `json:"peak,omitempty"`
+
+	FiredAt    *time.Time `json:"firedAt,omitempty"`
+	FiredPrice float64    `json:"firedPrice,omitempty"`
+	Signatures []string   `json:"signatures,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+func (t *Trigger) validate() error {
+	switch t.Kind {
+	case TriggerStopLoss, TriggerTakeProfit, TriggerLimitBuy, TriggerLimitSell:
+		if t.Price <= 0 {
+			return fmt.Errorf("%v trigger requires a positive price", t.Kind)
+		}
+	case TriggerTrailingStop:
+		if t.TrailingPercent <= 0 || t.TrailingPercent >= 100 {
+			return errors.New("trailing stop requires a trailing percent between 0 and 100")
+		}
+	default:
+		return fmt.Errorf("unknown trigger kind %q", t.Kind)
+	}
+	if t.Swap.InAmount <= 0 {
+		return errors.New("trigger swap requires a positive in amount")
+	}
+	return nil
+}
+
+// observe updates a trailing stop's peak and returns whether the trigger's condition holds at the price
+func (t *Trigger) observe(price float64) bool {
+	switch t.Kind {
+	case TriggerStopLoss, TriggerLimitBuy:
+		return price <= t.Price
+	case TriggerTakeProfit, TriggerLimitSell:
+		return price >= t.Price
+	case TriggerTrailingStop:
+		if price > t.Peak {
+			t.Peak = price
+			return false
+		}
+		return price <= t.Peak*(1-t.TrailingPercent/100)
+	default:
+		return false
+	}
+}
+
+// TriggerSwapFunc submits the swap of a fired trigger, e.g. through SubmitTradeSwap or SubmitRaydiumSwap
+type TriggerSwapFunc func(ctx context.Context, swap TriggerSwap) (*pb.PostSubmitBatchResponse, error)
+
+type triggerFile struct {
+	Triggers []*Trigger `json:"triggers"`
+}
+
+// TriggerEngine watches the prices of a token pair and submits the swaps of triggers whose condition holds. Triggers
+// are persisted to a file after every change, so they survive restarts. Triggers that were firing when the engine
+// stopped are marked failed on restart rather than fired again, since their swap may have landed.
+type TriggerEngine struct {
+	mutex    sync.Mutex
+	path     string
+	swap     TriggerSwapFunc
+	triggers map[string]*Trigger
+	updates  chan Trigger
+	wg       sync.WaitGroup
+}
+
+// NewTriggerEngine loads the triggers persisted at path, if any, and evaluates them against prices until ctx is
+// canceled or prices is closed
+func NewTriggerEngine(ctx context.Context, prices <-chan Price, swap TriggerSwapFunc, path string) (*TriggerEngine, error) {
+	e := &TriggerEngine{
+		path:     path,
+		swap:     swap,
+		triggers: make(map[string]*Trigger),
+		updates:  make(chan Trigger, defaultTriggerBuffer),
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		var file triggerFile
+		if err := json.Unmarshal(b, &file); err != nil {
+			return nil, fmt.Errorf("could not parse trigger file %v: %w", path, err)
+		}
+		for _, trigger := range file.Triggers {
+			if trigger.State == TriggerFiring {
+				trigger.State = TriggerFailed
+				trigger.Error = "engine stopped while the trigger was firing, check its swap before retrying"
+			}
+			e.triggers[trigger.ID] = trigger
+		}
+	}
+
+	go e.run(ctx, prices)
+	return e, nil
+}
+
+// Add validates and persists a new active trigger, returning its ID. The trigger is not added if it can't be persisted.
+func (e *TriggerEngine) Add(trigger Trigger) (string, error) {
+	if err := trigger.validate(); err != nil {
+		return "", err
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if trigger.ID == "" {
+		trigger.ID = newTriggerID()
+	}
+	if _, ok := e.triggers[trigger.ID]; ok {
+		return "", fmt.Errorf("trigger %v already exists", trigger.ID)
+	}
+	trigger.State = TriggerActive
+	e.triggers[trigger.ID] = &trigger
+	if err := e.save(); err != nil {
+		delete(e.triggers, trigger.ID)
+		return "", err
+	}
+	return trigger.ID, nil
+}
+
+// Cancel cancels an active trigger
+func (e *TriggerEngine) Cancel(id string) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	trigger, ok := e.triggers[id]
+	if !ok {
+		return ErrTriggerNotFound
+	}
+	if trigger.State != TriggerActive {
+		return fmt.Errorf("trigger %v is %v", id, trigger.State)
+	}
+	trigger.State = TriggerCancelled
+	e.publish(*trigger)
+	return e.save()
+}
+
+// Trigger returns a trigger by ID
+func (e *TriggerEngine) Trigger(id string) (Trigger, bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	trigger, ok := e.triggers[id]
+	if !ok {
+		return Trigger{}, false
+	}
+	return *trigger, true
+}
+
+// Triggers returns all triggers ordered by ID
+func (e *TriggerEngine) Triggers() []Trigger {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	triggers := make([]Trigger, 0, len(e.triggers))
+	for _, trigger := range e.triggers {
+		triggers = append(triggers, *trigger)
+	}
+	sort.Slice(triggers, func(i, j int) bool { return triggers[i].ID < triggers[j].ID })
+	return triggers
+}
+
+// Updates returns a channel on which triggers are published when they fire, fail or are cancelled. Updates are
+// dropped if the channel is full.
+func (e *TriggerEngine) Updates() <-chan Trigger {
+	return e.updates
+}
+
+// Wait blocks until the swaps of all fired triggers have completed
+func (e *TriggerEngine) Wait() {
+	e.wg.Wait()
+}
+
+func (e *TriggerEngine) run(ctx context.Context, prices <-chan Price) {
+	for {
+		select {
+		case price, ok := <-prices:
+			if !ok {
+				log.Warn("trigger price source closed")
+				return
+			}
+			e.evaluate(ctx, price)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (e *TriggerEngine) evaluate(ctx context.Context, price Price) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	ids := make([]string, 0, len(e.triggers))
+	for id := range e.triggers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	changed := false
+	var fired, cancelled []Trigger
+	rollback := make(map[string]Trigger)
+	for _, id := range ids {
+		trigger := e.triggers[id]
+		if trigger.State != TriggerActive {
+			continue
+		}
+
+		peak := trigger.Peak
+		holds := trigger.observe(price.Price)
+		changed = changed || trigger.Peak != peak
+		if !holds {
+			continue
+		}
+
+		rollback[trigger.ID] = *trigger
+		firedAt := price.Time
+		trigger.State = TriggerFiring
+		trigger.FiredAt = &firedAt
+		trigger.FiredPrice = price.Price
+		changed = true
+		cancelled = append(cancelled, e.cancelGroup(trigger, rollback)...)
+		fired = append(fired, *trigger)
+	}
+
+	// the firing state is persisted before any swap is sent, so a restart never fires a trigger twice. If it can't be
+	// persisted, the triggers stay active and fire on a later price instead.
+	if changed {
+		if err := e.save(); err != nil {
+			log.Errorf("could not persist triggers, not firing %v of them: %v", len(fired), err)
+			for id, trigger := range rollback {
+				*e.triggers[id] = trigger
+			}
+			return
+		}
+	}
+	for _, trigger := range cancelled {
+		e.publish(trigger)
+	}
+	for _, trigger := range fired {
+		e.wg.Add(1)
+		go e.fire(ctx, trigger)
+	}
+}
+
+// cancelGroup cancels the other active triggers in the fired trigger's one-cancels-other group, recording their
+// previous state in rollback
+func (e *TriggerEngine) cancelGroup(fired *Trigger, rollback map[string]Trigger) []Trigger {
+	if fired.Group == "" {
+		return nil
+	}
+	var cancelled []Trigger
+	for _, trigger := range e.triggers {
+		if trigger.ID != fired.ID && trigger.Group == fired.Group && trigger.State == TriggerActive {
+			rollback[trigger.ID] = *trigger
+			trigger.State = TriggerCancelled
+			cancelled = append(cancelled, *trigger)
+		}
+	}
+	return cancelled
+}
+
+func (e *TriggerEngine) fire(ctx context.Context, trigger Trigger) {
+	defer e.wg.Done()
+
+	response, err := e.swap(ctx, trigger.Swap)
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	stored := e.triggers[trigger.ID]
+	stored.State = TriggerFired
+	if response != nil {
+		for _, entry := range response.Transactions {
+			stored.Signatures = append(stored.Signatures, entry.Signature)
+			if !entry.Submitted && err == nil {
+				err = fmt.Errorf("transaction %v was not submitted: %v", entry.Signature, entry.Error)
+			}
+		}
+	}
+	if err != nil {
+		stored.State = TriggerFailed
+		stored.Error = err.Error()
+		log.Errorf("swap of %v trigger %v failed: %v", stored.Kind, stored.ID, err)
+	}
+
+	e.publish(*stored)
+	if err := e.save(); err != nil {
+		log.Errorf("could not persist triggers: %v", err)
+	}
+}
+
+func (e *TriggerEngine) publish(trigger Trigger) {
+	select {
+	case e.updates <- trigger:
+	default:
+		log.Warnf("trigger updates channel full, dropping update of trigger %v", trigger.ID)
+	}
+}
+
+// save writes the triggers to a temporary file that replaces the trigger file, so a crash never leaves it truncated
+func (e *TriggerEngine) save() error {
+	file := triggerFile{Triggers: make([]*Trigger, 0, len(e.triggers))}
+	for _, trigger := range e.triggers {
+		file.Triggers = append(file.Triggers, trigger)
+	}
+	sort.Slice(file.Triggers, func(i, j int) bool { return file.Triggers[i].ID < file.Triggers[j].ID })
+
+	b, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(e.path), filepath.Base(e.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), e.path)
+}
+
+func newTriggerID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// PricesStreamPrices observes the midpoint of a token's buy and sell prices on a GetPricesStream stream
+func PricesStreamPrices(ctx context.Context, stream connections.Streamer[*pb.GetPricesStreamResponse], tokenAddress string) <-chan Price {
+	prices := make(chan Price, 100)
+	ch := stream.Channel(100)
+	go func() {
+		defer close(prices)
+		for {
+			select {
+			case response, ok := <-ch:
+				if !ok {
+					return
+				}
+				update := response.Price
+				if update == nil || update.TokenAddress != tokenAddress {
+					continue
+				}
+
+				price := Price{Time: time.Now(), Price: midPrice(update.Buy, update.Sell)}
+				if response.Timestamp != nil {
+					price.Time = response.Timestamp.AsTime()
+				}
+				if price.Price <= 0 {
+					continue
+				}
+
+				select {
+				case prices <- price:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return prices
+}
+
+// QuotesStreamPrices observes the output tokens per input token quoted for a token pair on a GetQuotesStream stream
+func QuotesStreamPrices(ctx context.Context, stream connections.Streamer[*pb.GetQuotesStreamResponse], inTokenAddress, outTokenAddress string) <-chan Price {
+	prices := make(chan Price, 100)
+	ch := stream.Channel(100)
+	go func() {
+		defer close(prices)
+		for {
+			select {
+			case response, ok := <-ch:
+				if !ok {
+					return
+				}
+				quote := response.Quote
+				if quote == nil || quote.InTokenAddress != inTokenAddress || quote.OutTokenAddress != outTokenAddress || quote.InAmount <= 0 {
+					continue
+				}
+
+				price := Price{Time: time.Now(), Price: quote.OutAmount / quote.InAmount}
+				if response.Timestamp != nil {
+					price.Time = response.Timestamp.AsTime()
+				}
+
+				select {
+				case prices <- price:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return prices
+}
+
+func midPrice(buy, sell float64) float64 {
+	switch {
+	case buy > 0 && sell > 0:
+		return (buy + sell) / 2
+	case buy > 0:
+		return buy
+	default:
+		return sell
+	}
+}
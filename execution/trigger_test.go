@@ -0,0 +1,163 @@
+package execution
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTriggerEngine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "triggers.json")
+
+	var (
+		mutex sync.Mutex
+		swaps []TriggerSwap
+	)
+	swap := func(_ context.Context, swap TriggerSwap) (*pb.PostSubmitBatchResponse, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		swaps = append(swaps, swap)
+		return &pb.PostSubmitBatchResponse{
+			Transactions: []*pb.PostSubmitBatchResponseEntry{{Signature: "signature", Submitted: true}},
+		}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	prices := make(chan Price)
+	engine, err := NewTriggerEngine(ctx, prices, swap, path)
+	require.NoError(t, err)
+
+	sell := TriggerSwap{Owner: "owner", InToken: "BONK", OutToken: "USDC", InAmount: 100}
+	stopLoss, err := engine.Add(Trigger{Kind: TriggerStopLoss, Group: "exit", Price: 8, Swap: sell})
+	require.NoError(t, err)
+	takeProfit, err := engine.Add(Trigger{Kind: TriggerTakeProfit, Group: "exit", Price: 12, Swap: sell})
+	require.NoError(t, err)
+	trailing, err := engine.Add(Trigger{Kind: TriggerTrailingStop, TrailingPercent: 10, Swap: sell})
+	require.NoError(t, err)
+
+	prices <- Price{Time: time.Now(), Price: 10}
+	prices <- Price{Time: time.Now(), Price: 11}
+	// prices are evaluated in order, so receiving a price that changes nothing means the previous one was evaluated
+	prices <- Price{Time: time.Now(), Price: 10.5}
+	cancel()
+	engine.Wait()
+
+	// the trailing stop's peak survives a restart
+	prices = make(chan Price)
+	engine, err = NewTriggerEngine(context.Background(), prices, swap, path)
+	require.NoError(t, err)
+	restored, ok := engine.Trigger(trailing)
+	require.True(t, ok)
+	require.Equal(t, float64(11), restored.Peak)
+
+	// the take profit fires and cancels the stop loss of its group, then the drop fires the trailing stop
+	prices <- Price{Time: time.Now(), Price: 12}
+	prices <- Price{Time: time.Now(), Price: 7}
+	prices <- Price{Time: time.Now(), Price: 7}
+	engine.Wait()
+
+	fired, _ := engine.Trigger(takeProfit)
+	require.Equal(t, TriggerFired, fired.State)
+	require.Equal(t, []string{"signature"}, fired.Signatures)
+	cancelled, _ := engine.Trigger(stopLoss)
+	require.Equal(t, TriggerCancelled, cancelled.State)
+	restored, _ = engine.Trigger(trailing)
+	require.Equal(t, TriggerFired, restored.State)
+	require.Equal(t, float64(7), restored.FiredPrice)
+	require.Equal(t, 2, len(swaps))
+}
+
+func TestTriggerEngineSavesFiringState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "triggers.json")
+
+	// the swap sees the trigger file as a restart would, so it must already record the trigger as firing
+	saved := make(chan []byte, 1)
+	swap := func(_ context.Context, _ TriggerSwap) (*pb.PostSubmitBatchResponse, error) {
+		b, err := os.ReadFile(path)
+		saved <- b
+		return &pb.PostSubmitBatchResponse{}, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	prices := make(chan Price)
+	engine, err := NewTriggerEngine(ctx, prices, swap, path)
+	require.NoError(t, err)
+	_, err = engine.Add(Trigger{Kind: TriggerStopLoss, Price: 8, Swap: TriggerSwap{Owner: "owner", InToken: "BONK", OutToken: "USDC", InAmount: 100}})
+	require.NoError(t, err)
+
+	prices <- Price{Time: time.Now(), Price: 7}
+	prices <- Price{Time: time.Now(), Price: 7}
+	engine.Wait()
+
+	var file triggerFile
+	require.NoError(t, json.Unmarshal(<-saved, &file))
+	require.Equal(t, 1, len(file.Triggers))
+	require.Equal(t, TriggerFiring, file.Triggers[0].State)
+}
+
+func TestTriggerEngineUnwritablePath(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "triggers")
+	require.NoError(t, os.Mkdir(dir, 0o755))
+
+	var (
+		mutex sync.Mutex
+		swaps int
+	)
+	swap := func(_ context.Context, _ TriggerSwap) (*pb.PostSubmitBatchResponse, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		swaps++
+		return &pb.PostSubmitBatchResponse{}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	prices := make(chan Price)
+	engine, err := NewTriggerEngine(ctx, prices, swap, filepath.Join(dir, "triggers.json"))
+	require.NoError(t, err)
+	sell := TriggerSwap{Owner: "owner", InToken: "BONK", OutToken: "USDC", InAmount: 100}
+	stopLoss, err := engine.Add(Trigger{Kind: TriggerStopLoss, Group: "exit", Price: 8, Swap: sell})
+	require.NoError(t, err)
+	takeProfit, err := engine.Add(Trigger{Kind: TriggerTakeProfit, Group: "exit", Price: 12, Swap: sell})
+	require.NoError(t, err)
+
+	// triggers that can't be persisted are neither added nor fired
+	require.NoError(t, os.RemoveAll(dir))
+	_, err = engine.Add(Trigger{Kind: TriggerStopLoss, Price: 5, Swap: sell})
+	require.Error(t, err)
+	require.Equal(t, 2, len(engine.Triggers()))
+
+	prices <- Price{Time: time.Now(), Price: 7}
+	prices <- Price{Time: time.Now(), Price: 7}
+	engine.Wait()
+	mutex.Lock()
+	require.Equal(t, 0, swaps)
+	mutex.Unlock()
+	for _, id := range []string{stopLoss, takeProfit} {
+		trigger, _ := engine.Trigger(id)
+		require.Equal(t, TriggerActive, trigger.State)
+		require.Nil(t, trigger.FiredAt)
+	}
+
+	// once the path is writable again, the trigger fires on the next price
+	require.NoError(t, os.Mkdir(dir, 0o755))
+	prices <- Price{Time: time.Now(), Price: 7}
+	prices <- Price{Time: time.Now(), Price: 7}
+	engine.Wait()
+	mutex.Lock()
+	require.Equal(t, 1, swaps)
+	mutex.Unlock()
+	fired, _ := engine.Trigger(stopLoss)
+	require.Equal(t, TriggerFired, fired.State)
+	cancelled, _ := engine.Trigger(takeProfit)
+	require.Equal(t, TriggerCancelled, cancelled.State)
+}
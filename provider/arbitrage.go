@@ -0,0 +1,506 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// defaultArbitrageFeePercent approximates the trade fee of a swap on projects without a configured fee
+	defaultArbitrageFeePercent = 0.25
+
+	defaultArbitrageComputeUnits      = 400_000
+	defaultArbitrageMaxAge            = 5 * time.Second
+	defaultArbitrageOpportunityBuffer = 100
+	defaultArbitrageResubscribeDelay  = time.Second
+
+	usdcMint = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+)
+
+var ErrArbitrageUnprofitable = errors.New("arbitrage no longer profitable")
+
+// ArbitragePair is a token traded against a quote token, e.g. USDC or SOL, on several projects. Both are given as
+// mints, since stream updates are matched by address.
+type ArbitragePair struct {
+	Token string
+	Quote string
+
+	// Amount of the quote token spent buying the token on the cheaper project
+	Amount float64
+
+	// TokenAmount also streams quotes of selling this amount of the token. Sell prices are estimated from buy quotes
+	// if 0.
+	TokenAmount float64
+
+	// QuotePerSOL values priority fees in the quote token. It is only needed if neither token is SOL; priority fees
+	// are not counted otherwise.
+	QuotePerSOL float64
+}
+
+type ArbitrageOpts struct {
+	Pairs []ArbitragePair
+
+	// Projects are compared with each other. Defaults to Raydium and Jupiter, the projects two-leg executions can
+	// build instructions for.
+	Projects []pb.Project
+
+	// Prices also uses GetPricesStream. Its prices are in USDC, so they only apply to pairs quoted in USDC.
+	Prices bool
+
+	// Slippage in percent is passed to both legs of an execution. It is counted as a cost of both legs when
+	// detecting opportunities.
+	Slippage float64
+
+	// FeePercent is the estimated trade fee of a swap on a project, in percent. Defaults to 0.25 for projects not in
+	// the map.
+	FeePercent map[pb.Project]float64
+
+	// MinProfit is the lowest profit in the quote token, net of costs, reported as an opportunity
+	MinProfit float64
+
+	// Percentile of recent priority fees used to estimate the priority fee. Defaults to 50.
+	Percentile float64
+
+	// ComputeUnits of a two-leg transaction, used to estimate the priority fee. Defaults to 400,000.
+	ComputeUnits uint32
+
+	// MaxAge ignores prices older than this. Defaults to 5s.
+	MaxAge time.Duration
+
+	// OpportunityBuffer is the size of the channel returned by Opportunities. Defaults to 100.
+	OpportunityBuffer int
+}
+
+// ArbitrageOpportunity is a price difference between two projects that beats the estimated costs of trading it
+type ArbitrageOpportunity struct {
+	Pair        ArbitragePair
+	BuyProject  pb.Project
+	SellProject pb.Project
+
+	// BuyPrice and SellPrice are in quote tokens per token
+	BuyPrice      float64
+	SellPrice     float64
+	SpreadPercent float64
+
+	// GrossProfit, Costs and NetProfit are in the quote token. Costs include trade fees, slippage and the priority
+	// fee.
+	GrossProfit float64
+	Costs       float64
+	NetProfit   float64
+
+	Slot int64
+	Time time.Time
+}
+
+type arbitrageQuote struct {
+	price float64
+	slot  int64
+	time  time.Time
+}
+
+// arbitrageQuotes are the latest buy and sell prices of a pair on a project
+type arbitrageQuotes struct {
+	buy  arbitrageQuote
+	sell arbitrageQuote
+}
+
+type arbitrageKey struct {
+	pair    int
+	project pb.Project
+}
+
+type pricesStreamProvider func(ctx context.Context, projects []pb.Project, tokens []string) (connections.Streamer[*pb.GetPricesStreamResponse], error)
+type quotesStreamProvider func(ctx context.Context, projects []pb.Project, tokenPairs []*pb.TokenPair) (connections.Streamer[*pb.GetQuotesStreamResponse], error)
+type swapComposerProvider func(owner string) *SwapComposer
+
+type arbitrageAPI struct {
+	pricesStream pricesStreamProvider
+	quotesStream quotesStreamProvider
+	composer     swapComposerProvider
+}
+
+// ArbitrageDetector keeps the prices of token pairs on several projects from GetQuotesStream, and optionally
+// GetPricesStream, and reports opportunities when buying on one project and selling on another beats the estimated
+// fees, priority fee and slippage
+type ArbitrageDetector struct {
+	mutex            sync.Mutex
+	api              arbitrageAPI
+	fees             *priorityFeeStore
+	opts             ArbitrageOpts
+	quotes           map[arbitrageKey]*arbitrageQuotes
+	opportunities    chan *ArbitrageOpportunity
+	resubscribeDelay time.Duration
+}
+
+func newArbitrageDetector(api arbitrageAPI, fees *priorityFeeStore, opts ArbitrageOpts) *ArbitrageDetector {
+	if len(opts.Projects) == 0 {
+		opts.Projects = []pb.Project{pb.Project_P_RAYDIUM, pb.Project_P_JUPITER}
+	}
+	if opts.Percentile == 0 {
+		opts.Percentile = defaultPriorityFeePercentile
+	}
+	if opts.ComputeUnits == 0 {
+		opts.ComputeUnits = defaultArbitrageComputeUnits
+	}
+	if opts.MaxAge == 0 {
+		opts.MaxAge = defaultArbitrageMaxAge
+	}
+	if opts.OpportunityBuffer == 0 {
+		opts.OpportunityBuffer = defaultArbitrageOpportunityBuffer
+	}
+	return &ArbitrageDetector{
+		api:              api,
+		fees:             fees,
+		opts:             opts,
+		quotes:           make(map[arbitrageKey]*arbitrageQuotes),
+		opportunities:    make(chan *ArbitrageOpportunity, opts.OpportunityBuffer),
+		resubscribeDelay: defaultArbitrageResubscribeDelay,
+	}
+}
+
+// NewArbitrageDetector subscribes to the prices of the pairs and reports opportunities until ctx is canceled
+func (w *WSClient) NewArbitrageDetector(ctx context.Context, opts ArbitrageOpts) (*ArbitrageDetector, error) {
+	return startArbitrageDetector(ctx, newArbitrageDetector(arbitrageAPI{
+		pricesStream: w.GetPricesStream,
+		quotesStream: w.GetQuotesStream,
+		composer:     w.NewSwapComposer,
+	}, w.priorityFeeStore, opts))
+}
+
+// NewArbitrageDetector subscribes to the prices of the pairs and reports opportunities until ctx is canceled
+func (g *GRPCClient) NewArbitrageDetector(ctx context.Context, opts ArbitrageOpts) (*ArbitrageDetector, error) {
+	return startArbitrageDetector(ctx, newArbitrageDetector(arbitrageAPI{
+		pricesStream: g.GetPricesStream,
+		quotesStream: g.GetQuotesStream,
+		composer:     g.NewSwapComposer,
+	}, g.priorityFeeStore, opts))
+}
+
+func startArbitrageDetector(ctx context.Context, d *ArbitrageDetector) (*ArbitrageDetector, error) {
+	quotes, err := d.subscribeQuotes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var prices chan *pb.GetPricesStreamResponse
+	if d.opts.Prices {
+		prices, err = d.subscribePrices(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	go d.run(ctx, quotes, prices)
+	return d, nil
+}
+
+// Opportunities returns a channel on which opportunities are published as prices change. Opportunities are dropped if
+// the channel is full.
+func (d *ArbitrageDetector) Opportunities() <-chan *ArbitrageOpportunity {
+	return d.opportunities
+}
+
+// Execute buys the pair's token on the opportunity's buy project and sells it on its sell project in one transaction
+// built by a SwapComposer, so either both legs land or neither does. The sell leg sells the buy leg's minimum output,
+// and the execution is abandoned with ErrArbitrageUnprofitable if the sell leg's minimum output no longer covers the
+// amount spent and MinProfit.
+func (d *ArbitrageDetector) Execute(ctx context.Context, owner string, opportunity *ArbitrageOpportunity, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	pair := opportunity.Pair
+	composer := d.api.composer(owner)
+
+	bought, err := d.addLeg(ctx, composer, opportunity.BuyProject, pair.Quote, pair.Token, pair.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("could not build buy leg on %v: %w", opportunity.BuyProject, err)
+	}
+	sold, err := d.addLeg(ctx, composer, opportunity.SellProject, pair.Token, pair.Quote, bought)
+	if err != nil {
+		return nil, fmt.Errorf("could not build sell leg on %v: %w", opportunity.SellProject, err)
+	}
+
+	if profit := sold - pair.Amount; profit < d.opts.MinProfit {
+		return nil, fmt.Errorf("%w: minimum profit %v is below %v", ErrArbitrageUnprofitable, profit, d.opts.MinProfit)
+	}
+	return composer.Submit(ctx, useBundle, opts)
+}
+
+// addLeg adds a swap on the project to the composer, returning its minimum output
+func (d *ArbitrageDetector) addLeg(ctx context.Context, composer *SwapComposer, project pb.Project, inToken, outToken string, inAmount float64) (float64, error) {
+	switch project {
+	case pb.Project_P_JUPITER:
+		response, err := composer.AddJupiterSwap(ctx, &pb.PostJupiterSwapInstructionsRequest{
+			InToken:  inToken,
+			OutToken: outToken,
+			InAmount: inAmount,
+			Slippage: d.opts.Slippage,
+		})
+		if err != nil {
+			return 0, err
+		}
+		return response.OutAmountMin, nil
+	case pb.Project_P_RAYDIUM:
+		response, err := composer.AddRaydiumSwap(ctx, &pb.PostRaydiumSwapInstructionsRequest{
+			InToken:  inToken,
+			OutToken: outToken,
+			InAmount: inAmount,
+			Slippage: d.opts.Slippage,
+		})
+		if err != nil {
+			return 0, err
+		}
+		return response.OutAmountMin, nil
+	default:
+		return 0, fmt.Errorf("swap instructions are not available on %v", project)
+	}
+}
+
+func (d *ArbitrageDetector) subscribeQuotes(ctx context.Context) (chan *pb.GetQuotesStreamResponse, error) {
+	var tokenPairs []*pb.TokenPair
+	for _, pair := range d.opts.Pairs {
+		tokenPairs = append(tokenPairs, &pb.TokenPair{InToken: pair.Quote, OutToken: pair.Token, InAmount: pair.Amount})
+		if pair.TokenAmount > 0 {
+			tokenPairs = append(tokenPairs, &pb.TokenPair{InToken: pair.Token, OutToken: pair.Quote, InAmount: pair.TokenAmount})
+		}
+	}
+
+	stream, err := d.api.quotesStream(ctx, d.opts.Projects, tokenPairs)
+	if err != nil {
+		return nil, err
+	}
+	return stream.Channel(100), nil
+}
+
+func (d *ArbitrageDetector) subscribePrices(ctx context.Context) (chan *pb.GetPricesStreamResponse, error) {
+	tokens := make([]string, 0, len(d.opts.Pairs))
+	for _, pair := range d.opts.Pairs {
+		tokens = append(tokens, pair.Token)
+	}
+
+	stream, err := d.api.pricesStream(ctx, d.opts.Projects, tokens)
+	if err != nil {
+		return nil, err
+	}
+	return stream.Channel(100), nil
+}
+
+func (d *ArbitrageDetector) run(ctx context.Context, quotes chan *pb.GetQuotesStreamResponse, prices chan *pb.GetPricesStreamResponse) {
+	for {
+		select {
+		case response, ok := <-quotes:
+			if !ok {
+				log.Warn("quotes stream closed, resubscribing")
+				quotes = resubscribe(ctx, d.resubscribeDelay, "quotes", d.subscribeQuotes)
+				if quotes == nil {
+					return
+				}
+				continue
+			}
+			d.updateQuote(ctx, response)
+		case response, ok := <-prices:
+			if !ok {
+				log.Warn("prices stream closed, resubscribing")
+				prices = resubscribe(ctx, d.resubscribeDelay, "prices", d.subscribePrices)
+				if prices == nil {
+					return
+				}
+				continue
+			}
+			d.updatePrice(ctx, response)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (d *ArbitrageDetector) updateQuote(ctx context.Context, response *pb.GetQuotesStreamResponse) {
+	quote := response.Quote
+	if quote == nil || quote.InAmount <= 0 || quote.OutAmount <= 0 {
+		return
+	}
+	update := arbitrageQuote{slot: response.Slot, time: streamTime(response.Timestamp)}
+
+	for i, pair := range d.opts.Pairs {
+		switch {
+		case quote.InTokenAddress == pair.Quote && quote.OutTokenAddress == pair.Token:
+			update.price = quote.InAmount / quote.OutAmount
+			d.update(ctx, i, quote.Project, &update, nil)
+		case quote.InTokenAddress == pair.Token && quote.OutTokenAddress == pair.Quote:
+			update.price = quote.OutAmount / quote.InAmount
+			d.update(ctx, i, quote.Project, nil, &update)
+		}
+	}
+}
+
+func (d *ArbitrageDetector) updatePrice(ctx context.Context, response *pb.GetPricesStreamResponse) {
+	price := response.Price
+	if price == nil {
+		return
+	}
+	t := streamTime(response.Timestamp)
+	buy := arbitrageQuote{price: firstPositive(price.Buy, price.Sell), slot: response.Slot, time: t}
+	sell := arbitrageQuote{price: firstPositive(price.Sell, price.Buy), slot: response.Slot, time: t}
+	if buy.price <= 0 {
+		return
+	}
+
+	for i, pair := range d.opts.Pairs {
+		if pair.Token == price.TokenAddress && pair.Quote == usdcMint {
+			d.update(ctx, i, price.Project, &buy, &sell)
+		}
+	}
+}
+
+// update records the pair's prices on a project, ignoring prices older than the recorded ones, and publishes the
+// best opportunity of the pair
+func (d *ArbitrageDetector) update(ctx context.Context, pair int, project pb.Project, buy, sell *arbitrageQuote) {
+	d.mutex.Lock()
+	key := arbitrageKey{pair: pair, project: project}
+	quotes, ok := d.quotes[key]
+	if !ok {
+		quotes = &arbitrageQuotes{}
+		d.quotes[key] = quotes
+	}
+	if buy != nil && buy.slot >= quotes.buy.slot {
+		quotes.buy = *buy
+	}
+	if sell != nil && sell.slot >= quotes.sell.slot {
+		quotes.sell = *sell
+	}
+	snapshot := make(map[pb.Project]arbitrageQuotes, len(d.opts.Projects))
+	for _, p := range d.opts.Projects {
+		if q, ok := d.quotes[arbitrageKey{pair: pair, project: p}]; ok {
+			snapshot[p] = *q
+		}
+	}
+	d.mutex.Unlock()
+
+	opportunity, err := d.best(ctx, d.opts.Pairs[pair], snapshot, time.Now())
+	if err != nil {
+		log.Errorf("could not evaluate arbitrage of %v/%v: %v", d.opts.Pairs[pair].Token, d.opts.Pairs[pair].Quote, err)
+		return
+	}
+	if opportunity == nil {
+		return
+	}
+
+	select {
+	case d.opportunities <- opportunity:
+	default:
+		log.Warnf("arbitrage opportunities channel full, dropping %v/%v opportunity", opportunity.Pair.Token, opportunity.Pair.Quote)
+	}
+}
+
+// best returns the most profitable opportunity between the projects' fresh prices, or nil if none beats MinProfit
+func (d *ArbitrageDetector) best(ctx context.Context, pair ArbitragePair, quotes map[pb.Project]arbitrageQuotes, now time.Time) (*ArbitrageOpportunity, error) {
+	fresh := func(q arbitrageQuote) bool {
+		return q.price > 0 && now.Sub(q.time) <= d.opts.MaxAge
+	}
+
+	var best *ArbitrageOpportunity
+	for buyProject, buyQuotes := range quotes {
+		if !fresh(buyQuotes.buy) {
+			continue
+		}
+		for sellProject, sellQuotes := range quotes {
+			if sellProject == buyProject {
+				continue
+			}
+			sell := sellQuotes.sell
+			if !fresh(sell) {
+				// without sell quotes the project's buy price stands in for its sell price
+				sell = sellQuotes.buy
+				if !fresh(sell) {
+					continue
+				}
+			}
+			if sell.price <= buyQuotes.buy.price {
+				continue
+			}
+
+			opportunity := &ArbitrageOpportunity{
+				Pair:          pair,
+				BuyProject:    buyProject,
+				SellProject:   sellProject,
+				BuyPrice:      buyQuotes.buy.price,
+				SellPrice:     sell.price,
+				SpreadPercent: (sell.price/buyQuotes.buy.price - 1) * 100,
+				Slot:          max(buyQuotes.buy.slot, sell.slot),
+				Time:          now,
+			}
+			opportunity.GrossProfit = pair.Amount * opportunity.SpreadPercent / 100
+			if best != nil && opportunity.GrossProfit <= best.GrossProfit {
+				continue
+			}
+			best = opportunity
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+
+	priorityFee, err := d.priorityFee(ctx, pair, best.BuyPrice)
+	if err != nil {
+		return nil, err
+	}
+	costPercent := d.feePercent(best.BuyProject) + d.feePercent(best.SellProject) + 2*d.opts.Slippage
+	best.Costs = pair.Amount*costPercent/100 + priorityFee
+	best.NetProfit = best.GrossProfit - best.Costs
+	if best.NetProfit <= 0 || best.NetProfit < d.opts.MinProfit {
+		return nil, nil
+	}
+	return best, nil
+}
+
+func (d *ArbitrageDetector) feePercent(project pb.Project) float64 {
+	if fee, ok := d.opts.FeePercent[project]; ok {
+		return fee
+	}
+	return defaultArbitrageFeePercent
+}
+
+// priorityFee estimates the priority fee of a two-leg transaction in the quote token
+func (d *ArbitrageDetector) priorityFee(ctx context.Context, pair ArbitragePair, price float64) (float64, error) {
+	var quotePerSOL float64
+	switch {
+	case isSOLToken(pair.Quote):
+		quotePerSOL = 1
+	case isSOLToken(pair.Token):
+		quotePerSOL = price
+	default:
+		quotePerSOL = pair.QuotePerSOL
+	}
+	if quotePerSOL == 0 {
+		return 0, nil
+	}
+
+	computeUnitPrice, err := d.fees.get(ctx, pb.Project_P_RAYDIUM, d.opts.Percentile)
+	if err != nil {
+		return 0, fmt.Errorf("could not retrieve priority fee: %w", err)
+	}
+	lamports := priorityFeeLamports(d.opts.ComputeUnits, computeUnitPrice)
+	return float64(lamports) / float64(solana.LAMPORTS_PER_SOL) * quotePerSOL, nil
+}
+
+func streamTime(timestamp *timestamppb.Timestamp) time.Time {
+	if timestamp == nil {
+		return time.Now()
+	}
+	return timestamp.AsTime()
+}
+
+func firstPositive(values ...float64) float64 {
+	for _, v := range values {
+		if v > 0 {
+			return v
+		}
+	}
+	return 0
+}
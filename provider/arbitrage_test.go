@@ -0,0 +1,172 @@
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestArbitrageBest(t *testing.T) {
+	now := time.Now()
+	usdcPair := ArbitragePair{Token: "token", Quote: usdcMint, Amount: 100}
+	solPair := ArbitragePair{Token: "token", Quote: solana.SolMint.String(), Amount: 10}
+	quote := func(price float64, age time.Duration) arbitrageQuote {
+		return arbitrageQuote{price: price, time: now.Add(-age)}
+	}
+
+	tests := []struct {
+		name      string
+		pair      ArbitragePair
+		minProfit float64
+		quotes    map[pb.Project]arbitrageQuotes
+		// expected opportunity, nil if none
+		sellPrice float64
+		netProfit float64
+	}{
+		{
+			name: "profitable",
+			pair: usdcPair,
+			quotes: map[pb.Project]arbitrageQuotes{
+				pb.Project_P_RAYDIUM: {buy: quote(1, 0), sell: quote(0.99, 0)},
+				pb.Project_P_JUPITER: {buy: quote(1.03, 0), sell: quote(1.02, 0)},
+			},
+			sellPrice: 1.02,
+			netProfit: 1.5,
+		},
+		{
+			name: "stale sell price",
+			pair: usdcPair,
+			quotes: map[pb.Project]arbitrageQuotes{
+				pb.Project_P_RAYDIUM: {buy: quote(1, 0)},
+				pb.Project_P_JUPITER: {buy: quote(1.03, 10*time.Second), sell: quote(1.02, 10*time.Second)},
+			},
+		},
+		{
+			name: "buy price stands in for missing sell price",
+			pair: usdcPair,
+			quotes: map[pb.Project]arbitrageQuotes{
+				pb.Project_P_RAYDIUM: {buy: quote(1, 0)},
+				pb.Project_P_JUPITER: {buy: quote(1.02, 0)},
+			},
+			sellPrice: 1.02,
+			netProfit: 1.5,
+		},
+		{
+			name: "spread below fees",
+			pair: usdcPair,
+			quotes: map[pb.Project]arbitrageQuotes{
+				pb.Project_P_RAYDIUM: {buy: quote(1, 0)},
+				pb.Project_P_JUPITER: {buy: quote(1.004, 0)},
+			},
+		},
+		{
+			name:      "net profit below minimum",
+			pair:      usdcPair,
+			minProfit: 2,
+			quotes: map[pb.Project]arbitrageQuotes{
+				pb.Project_P_RAYDIUM: {buy: quote(1, 0)},
+				pb.Project_P_JUPITER: {buy: quote(1.02, 0)},
+			},
+		},
+		{
+			// 400,000 compute units at 100,000,000 micro-lamports cost 0.04 SOL on top of 0.05 SOL in trade fees
+			name: "priority fee counted in SOL",
+			pair: solPair,
+			quotes: map[pb.Project]arbitrageQuotes{
+				pb.Project_P_RAYDIUM: {buy: quote(1, 0)},
+				pb.Project_P_JUPITER: {buy: quote(1.1, 0)},
+			},
+			sellPrice: 1.1,
+			netProfit: 0.91,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d := newArbitrageDetector(arbitrageAPI{}, fixedPriorityFees(100_000_000), ArbitrageOpts{
+				Pairs:     []ArbitragePair{test.pair},
+				MinProfit: test.minProfit,
+			})
+			opportunity, err := d.best(context.Background(), test.pair, test.quotes, now)
+			require.NoError(t, err)
+			if test.sellPrice == 0 {
+				require.Nil(t, opportunity)
+				return
+			}
+			require.NotNil(t, opportunity)
+			require.Equal(t, pb.Project_P_RAYDIUM, opportunity.BuyProject)
+			require.Equal(t, pb.Project_P_JUPITER, opportunity.SellProject)
+			require.Equal(t, test.sellPrice, opportunity.SellPrice)
+			require.InDelta(t, test.netProfit, opportunity.NetProfit, 1e-9)
+		})
+	}
+}
+
+func TestArbitrageUpdates(t *testing.T) {
+	ctx := context.Background()
+	pair := ArbitragePair{Token: "token", Quote: usdcMint, Amount: 100}
+	solPair := ArbitragePair{Token: "other", Quote: solana.SolMint.String(), Amount: 100}
+	d := newArbitrageDetector(arbitrageAPI{}, fixedPriorityFees(0), ArbitrageOpts{Pairs: []ArbitragePair{pair, solPair}})
+	quoteResponse := func(project pb.Project, slot int64, inToken, outToken string, inAmount, outAmount float64) *pb.GetQuotesStreamResponse {
+		return &pb.GetQuotesStreamResponse{Slot: slot, Timestamp: timestamppb.Now(), Quote: &pb.GetQuotesStreamUpdate{
+			Project:         project,
+			InTokenAddress:  inToken,
+			OutTokenAddress: outToken,
+			InAmount:        inAmount,
+			OutAmount:       outAmount,
+		}}
+	}
+	opportunity := func() *ArbitrageOpportunity {
+		select {
+		case o := <-d.Opportunities():
+			return o
+		default:
+			return nil
+		}
+	}
+
+	// buying 100 tokens for 100 USDC on Raydium and selling them for 102 on Jupiter
+	d.updateQuote(ctx, quoteResponse(pb.Project_P_RAYDIUM, 10, usdcMint, "token", 100, 100))
+	require.Nil(t, opportunity())
+	d.updateQuote(ctx, quoteResponse(pb.Project_P_JUPITER, 10, "token", usdcMint, 100, 102))
+	o := opportunity()
+	require.NotNil(t, o)
+	require.Equal(t, 1.0, o.BuyPrice)
+	require.Equal(t, 1.02, o.SellPrice)
+	require.Equal(t, int64(10), o.Slot)
+
+	// a quote from an older slot doesn't replace a newer one
+	d.updateQuote(ctx, quoteResponse(pb.Project_P_JUPITER, 9, "token", usdcMint, 100, 100))
+	o = opportunity()
+	require.NotNil(t, o)
+	require.Equal(t, 1.02, o.SellPrice)
+
+	// stream prices are in USDC, so they only apply to pairs quoted in USDC
+	d.updatePrice(ctx, &pb.GetPricesStreamResponse{Slot: 11, Timestamp: timestamppb.Now()})
+	require.Nil(t, opportunity())
+	for _, project := range []pb.Project{pb.Project_P_RAYDIUM, pb.Project_P_JUPITER} {
+		d.updatePrice(ctx, &pb.GetPricesStreamResponse{Slot: 11, Timestamp: timestamppb.Now(), Price: &pb.TokenPrice{
+			Project:      project,
+			TokenAddress: "other",
+			Buy:          1 + float64(project),
+			Sell:         1 + float64(project),
+		}})
+	}
+	require.Nil(t, opportunity())
+	require.Empty(t, d.quotes[arbitrageKey{pair: 1, project: pb.Project_P_RAYDIUM}])
+
+	d.updatePrice(ctx, &pb.GetPricesStreamResponse{Slot: 11, Timestamp: timestamppb.Now(), Price: &pb.TokenPrice{
+		Project:      pb.Project_P_RAYDIUM,
+		TokenAddress: "token",
+		Buy:          0.9,
+		Sell:         0.9,
+	}})
+	o = opportunity()
+	require.NotNil(t, o)
+	require.Equal(t, 0.9, o.BuyPrice)
+	require.Equal(t, int64(11), o.Slot)
+}
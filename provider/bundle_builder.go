@@ -0,0 +1,251 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
+	"github.com/bloXroute-Labs/solana-trader-client-go/utils"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+)
+
+var ErrEmptyBundle = errors.New("bundle has no transactions")
+
+// TipPlacement determines how the bloXroute tip is added to a bundle
+type TipPlacement int
+
+const (
+	// TipTransaction appends the tip as a separate, final transaction of the bundle
+	TipTransaction TipPlacement = iota
+
+	// TipInstruction adds the tip transfer to the last transaction of the bundle. The last transaction must only
+	// require the client's signature, since adding an instruction invalidates any existing signatures.
+	TipInstruction
+)
+
+// BundleTipOpts configures how the tip of a bundle is chosen from GetBundleTipStream
+type BundleTipOpts struct {
+	// Percentile of recently landed bundle tips: 25, 50, 75, 95 or 99. Defaults to 50.
+	Percentile int
+
+	// UseEMA uses the exponential moving average of the 50th percentile instead of Percentile
+	UseEMA bool
+
+	// MinTip and MaxTip bound the tip in lamports. MaxTip is the tip budget and must be set.
+	MinTip uint64
+	MaxTip uint64
+
+	Placement TipPlacement
+}
+
+// BundleBuilder collects transactions, appends a tip sized from live bundle tips, signs everything and submits the
+// result as a bundle
+type BundleBuilder struct {
+	signer       txSigner
+	opts         BundleTipOpts
+	tips         *bundleTipStore
+	blockHash    blockHashProvider
+	submit       batchSubmitter
+	dryRun       dryRunner
+	transactions []*pb.TransactionMessage
+}
+
+// NewBundleBuilder creates a bundle builder that uses the client's private key and bundle tip stream
+func (w *WSClient) NewBundleBuilder(opts BundleTipOpts) (*BundleBuilder, error) {
+	if w.privateKey == nil {
+		return nil, ErrPrivateKeyNotFound
+	}
+	return newBundleBuilder(w.txSigner(), opts, w.bundleTipStore, w.RecentBlockHash, w.PostSubmitBatch, w.dryRun)
+}
+
+// NewBundleBuilder creates a bundle builder that uses the client's private key and bundle tip stream
+func (g *GRPCClient) NewBundleBuilder(opts BundleTipOpts) (*BundleBuilder, error) {
+	if g.privateKey == nil {
+		return nil, ErrPrivateKeyNotFound
+	}
+	return newBundleBuilder(g.txSigner(), opts, g.bundleTipStore, g.RecentBlockHash, g.PostSubmitBatch, g.dryRun)
+}
+
+func newBundleBuilder(signer txSigner, opts BundleTipOpts, tips *bundleTipStore, blockHash blockHashProvider, submit batchSubmitter, dryRun dryRunner) (*BundleBuilder, error) {
+	if opts.MaxTip == 0 {
+		return nil, errors.New("bundle tip budget (MaxTip) must be set")
+	}
+	if opts.MinTip > opts.MaxTip {
+		return nil, fmt.Errorf("minimum tip %v exceeds tip budget %v", opts.MinTip, opts.MaxTip)
+	}
+	if opts.Percentile == 0 {
+		opts.Percentile = 50
+	}
+
+	return &BundleBuilder{
+		signer:    signer,
+		opts:      opts,
+		tips:      tips,
+		blockHash: blockHash,
+		submit:    submit,
+		dryRun:    dryRun,
+	}, nil
+}
+
+// Add appends transactions to the bundle, in execution order
+func (b *BundleBuilder) Add(transactions ...*pb.TransactionMessage) *BundleBuilder {
+	b.transactions = append(b.transactions, transactions...)
+	return b
+}
+
+// Tip returns the tip in lamports the bundle would currently pay
+func (b *BundleBuilder) Tip(ctx context.Context) (uint64, error) {
+	tips, err := b.tips.get(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("could not retrieve bundle tips: %w", err)
+	}
+
+	var tipSOL float64
+	if b.opts.UseEMA {
+		tipSOL = tips.EmaPercentile50
+	} else {
+		switch b.opts.Percentile {
+		case 25:
+			tipSOL = tips.Percentile25
+		case 50:
+			tipSOL = tips.Percentile50
+		case 75:
+			tipSOL = tips.Percentile75
+		case 95:
+			tipSOL = tips.Percentile95
+		case 99:
+			tipSOL = tips.Percentile99
+		default:
+			return 0, fmt.Errorf("unsupported bundle tip percentile %v", b.opts.Percentile)
+		}
+	}
+
+	tip := uint64(tipSOL * float64(solana.LAMPORTS_PER_SOL))
+	if tip < b.opts.MinTip {
+		tip = b.opts.MinTip
+	}
+	if tip > b.opts.MaxTip {
+		tip = b.opts.MaxTip
+	}
+	return tip, nil
+}
+
+// Build signs all transactions and adds the tip, returning the signed bundle and the tip paid
+func (b *BundleBuilder) Build(ctx context.Context) ([]*pb.TransactionMessage, uint64, error) {
+	if len(b.transactions) == 0 {
+		return nil, 0, ErrEmptyBundle
+	}
+
+	tip, err := b.Tip(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	signed := make([]*pb.TransactionMessage, 0, len(b.transactions)+1)
+	last := len(b.transactions) - 1
+	for i, tx := range b.transactions {
+		var content string
+		if i == last && b.opts.Placement == TipInstruction {
+			content, err = b.signWithTip(ctx, tx.Content, tip)
+		} else {
+			content, err = b.signer.sign(ctx, tx.Content)
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		signed = append(signed, &pb.TransactionMessage{Content: content, IsCleanup: tx.IsCleanup})
+	}
+
+	if b.opts.Placement == TipTransaction {
+		tipTx, err := b.tipTransaction(ctx, tip)
+		if err != nil {
+			return nil, 0, err
+		}
+		signed = append(signed, tipTx)
+	}
+
+	return signed, tip, nil
+}
+
+// Submit builds the bundle and submits it with useBundle set, or simulates it in dry-run mode
+func (b *BundleBuilder) Submit(ctx context.Context, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	signed, _, err := b.Build(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	skipPreFlight := true
+	if opts.SkipPreFlight != nil {
+		skipPreFlight = *opts.SkipPreFlight
+	}
+	useBundle := true
+	request := &pb.PostSubmitBatchRequest{
+		SubmitStrategy: opts.SubmitStrategy,
+		UseBundle:      &useBundle,
+	}
+	for _, tx := range signed {
+		request.Entries = append(request.Entries, &pb.PostSubmitRequestEntry{
+			Transaction:   tx,
+			SkipPreFlight: skipPreFlight,
+		})
+	}
+	if b.dryRun.active(opts) {
+		return b.dryRun.simulateBatch(ctx, request)
+	}
+	return b.submit(ctx, request)
+}
+
+func (b *BundleBuilder) tipTransaction(ctx context.Context, tip uint64) (*pb.TransactionMessage, error) {
+	blockHash, err := b.blockHash(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve block hash: %w", err)
+	}
+	hash, err := solana.HashFromBase58(blockHash.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := utils.CreateBloxrouteTipTransactionToUseBundles(*b.signer.privateKey, tip, hash)
+	if err != nil {
+		return nil, err
+	}
+	content, err := tx.ToBase64()
+	if err != nil {
+		return nil, err
+	}
+	return &pb.TransactionMessage{Content: content}, nil
+}
+
+func (b *BundleBuilder) signWithTip(ctx context.Context, txBase64 string, tip uint64) (string, error) {
+	tx, err := transaction.DecodeTransaction(txBase64)
+	if err != nil {
+		return "", err
+	}
+
+	if tx.Message.Header.NumRequiredSignatures != 1 {
+		return "", errors.New("tip instruction can only be added to a transaction signed solely by the client")
+	}
+	feePayer := tx.Message.AccountKeys[0]
+	if _, ok := b.signer.keyring.Get(feePayer); !ok {
+		return "", errors.New("tip instruction can only be added to a transaction signed solely by the client")
+	}
+
+	// accounts the message loads from lookup tables are only recognised with the tables resolved
+	lookupTables, err := b.signer.resolveLookupTables(ctx, tx)
+	if err != nil {
+		return "", err
+	}
+	err = transaction.AppendInstructionWithLookupTables(tx, utils.CreateBloxrouteTipInstruction(feePayer, tip), lookupTables)
+	if err != nil {
+		return "", err
+	}
+
+	tx.Signatures = []solana.Signature{{}}
+	unsignedBase64, err := tx.ToBase64()
+	if err != nil {
+		return "", err
+	}
+	return b.signer.sign(ctx, unsignedBase64)
+}
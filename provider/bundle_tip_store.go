@@ -0,0 +1,116 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultBundleTipResubscribeDelay = time.Second
+
+var errBundleTipStreamClosed = errors.New("bundle tip stream closed")
+
+type bundleTipStreamProvider func(ctx context.Context) (connections.Streamer[*pb.GetBundleTipResponse], error)
+
+// bundleTipStore keeps the latest bundle tip percentiles. The stream is opened on first use and reopened whenever it
+// closes; while it's down, get returns the reason instead of a stale tip.
+type bundleTipStore struct {
+	mutex            sync.RWMutex
+	streamProvider   bundleTipStreamProvider
+	running          bool
+	tip              *pb.GetBundleTipResponse
+	err              error
+	ready            chan struct{}
+	readyOnce        sync.Once
+	resubscribeDelay time.Duration
+}
+
+func newBundleTipStore(streamProvider bundleTipStreamProvider) *bundleTipStore {
+	return &bundleTipStore{
+		streamProvider:   streamProvider,
+		ready:            make(chan struct{}),
+		resubscribeDelay: defaultBundleTipResubscribeDelay,
+	}
+}
+
+func (s *bundleTipStore) run(ctx context.Context) {
+	for {
+		err := s.follow(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Errorf("bundle tip stream failed, resubscribing: %v", err)
+		s.fail(err)
+		select {
+		case <-time.After(s.resubscribeDelay):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// follow updates the tip from a new stream until the stream closes
+func (s *bundleTipStore) follow(ctx context.Context) error {
+	stream, err := s.streamProvider(ctx)
+	if err != nil {
+		return err
+	}
+
+	ch := stream.Channel(1)
+	for {
+		select {
+		case tip, ok := <-ch:
+			if !ok {
+				return errBundleTipStreamClosed
+			}
+			s.update(tip)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *bundleTipStore) update(tip *pb.GetBundleTipResponse) {
+	s.mutex.Lock()
+	s.tip = tip
+	s.err = nil
+	s.mutex.Unlock()
+	s.readyOnce.Do(func() { close(s.ready) })
+}
+
+func (s *bundleTipStore) fail(err error) {
+	s.mutex.Lock()
+	s.tip = nil
+	s.err = err
+	s.mutex.Unlock()
+	s.readyOnce.Do(func() { close(s.ready) })
+}
+
+// get returns the latest bundle tip percentiles, waiting for the first stream update if necessary
+func (s *bundleTipStore) get(ctx context.Context) (*pb.GetBundleTipResponse, error) {
+	s.mutex.Lock()
+	if !s.running {
+		s.running = true
+		go s.run(context.Background())
+	}
+	s.mutex.Unlock()
+
+	select {
+	case <-s.ready:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if s.tip == nil {
+		return nil, s.err
+	}
+	return s.tip, nil
+}
@@ -0,0 +1,83 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
+	"github.com/bloXroute-Labs/solana-trader-client-go/utils"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBundleTipStoreResubscribes(t *testing.T) {
+	var mutex sync.Mutex
+	subscriptions := 0
+	store := newBundleTipStore(func(_ context.Context) (connections.Streamer[*pb.GetBundleTipResponse], error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		subscriptions++
+
+		// the first stream closes before its first update, later ones send one tip each and stay open
+		if subscriptions == 1 {
+			return func() (*pb.GetBundleTipResponse, error) { return nil, errors.New("closed") }, nil
+		}
+		sent := false
+		return func() (*pb.GetBundleTipResponse, error) {
+			if sent {
+				select {}
+			}
+			sent = true
+			return &pb.GetBundleTipResponse{Percentile50: float64(subscriptions)}, nil
+		}, nil
+	})
+	store.resubscribeDelay = 10 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := store.get(ctx)
+	require.ErrorIs(t, err, errBundleTipStreamClosed)
+
+	require.Eventually(t, func() bool {
+		tip, err := store.get(ctx)
+		return err == nil && tip.Percentile50 == 2
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestBundleBuilderTipWithLookupTables(t *testing.T) {
+	owner := solana.NewWallet().PrivateKey
+	table, recipient := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	tables := map[solana.PublicKey]solana.PublicKeySlice{table: {recipient}}
+
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		system.NewTransferInstruction(1000, owner.PublicKey(), recipient).Build(),
+	}, solana.Hash{1}, solana.TransactionPayer(owner.PublicKey()), solana.TransactionAddressTables(tables))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(tx.Message.AddressTableLookups))
+	txBase64, err := tx.ToBase64()
+	require.NoError(t, err)
+
+	builder := &BundleBuilder{signer: txSigner{privateKey: &owner, keyring: transaction.NewKeyring(owner), lookupTables: newLookupTableResolver("")}}
+	_, err = builder.signWithTip(context.Background(), txBase64, 5000)
+	require.ErrorIs(t, err, transaction.ErrLookupTablesRequired)
+
+	builder.signer.lookupTables = func(_ context.Context, ids []solana.PublicKey) (map[solana.PublicKey]solana.PublicKeySlice, error) {
+		require.Equal(t, []solana.PublicKey{table}, ids)
+		return tables, nil
+	}
+	signed, err := builder.signWithTip(context.Background(), txBase64, 5000)
+	require.NoError(t, err)
+
+	tipped, err := transaction.DecodeTransaction(signed)
+	require.NoError(t, err)
+	require.NoError(t, tipped.VerifySignatures())
+	require.Equal(t, 2, len(tipped.Message.Instructions))
+	tipAddress := solana.MustPublicKeyFromBase58(utils.BloxrouteTipAddress)
+	require.Contains(t, tipped.Message.AccountKeys, tipAddress)
+}
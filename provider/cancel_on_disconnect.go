@@ -0,0 +1,222 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultCancelOnDisconnectTimeout = 10 * time.Second
+
+var (
+	// ErrConnectionLost is the reason a guard fires when the guarded connection drops
+	ErrConnectionLost = errors.New("connection to Trader API lost")
+
+	// ErrHeartbeatMissed is the reason a guard fires when no heartbeat was received within the timeout
+	ErrHeartbeatMissed = errors.New("heartbeat missed")
+)
+
+// CancelOnDisconnectMarket is a market whose resting orders are cancelled when the guard fires
+type CancelOnDisconnectMarket struct {
+	Market              string
+	Owner               string
+	OpenOrdersAddresses []string
+	Project             pb.Project
+
+	// SubmitOpts are used for the cancel transactions of this market
+	SubmitOpts SubmitOpts
+}
+
+// CancelOnDisconnectResult is the outcome of cancelling the orders of one market
+type CancelOnDisconnectResult struct {
+	Market   CancelOnDisconnectMarket
+	Response *pb.PostSubmitBatchResponse
+	Err      error
+}
+
+type CancelOnDisconnectOpts struct {
+	Markets []CancelOnDisconnectMarket
+
+	// HeartbeatTimeout fires the guard if Heartbeat isn't called for this long. Disabled if 0.
+	HeartbeatTimeout time.Duration
+
+	// CancelTimeout bounds the time spent cancelling the orders of each market. Defaults to 10 seconds.
+	CancelTimeout time.Duration
+
+	// OnFire receives the reason the guard fired and the outcome for every market
+	OnFire func(reason error, results []CancelOnDisconnectResult)
+}
+
+// CancelAllSubmitter cancels all orders of an owner on a market, e.g. the SubmitCancelAll method of a client
+type CancelAllSubmitter func(ctx context.Context, market, owner string, openOrdersAddresses []string, project pb.Project, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error)
+
+// CancelOnDisconnectGuard cancels all orders of the registered markets through a fallback client, e.g. the
+// SubmitCancelAll method of a separate HTTP client, when the guarded connection drops or heartbeats stop. The guard
+// fires at most once; create a new one after reconnecting.
+type CancelOnDisconnectGuard struct {
+	mutex         sync.Mutex
+	fallback      CancelAllSubmitter
+	opts          CancelOnDisconnectOpts
+	markets       map[cancelOnDisconnectKey]CancelOnDisconnectMarket
+	lastHeartbeat time.Time
+	fired         chan struct{}
+	reason        error
+	results       []CancelOnDisconnectResult
+}
+
+// cancelOnDisconnectKey identifies a registration, so the orders of several owners on one market can be guarded
+type cancelOnDisconnectKey struct {
+	market string
+	owner  string
+}
+
+// NewCancelOnDisconnectGuard creates a guard that fires when the disconnected channel is closed or heartbeats stop,
+// until ctx is canceled. The WS and GRPC clients provide guards bound to their connection.
+func NewCancelOnDisconnectGuard(ctx context.Context, disconnected <-chan struct{}, fallback CancelAllSubmitter, opts CancelOnDisconnectOpts) *CancelOnDisconnectGuard {
+	if opts.CancelTimeout == 0 {
+		opts.CancelTimeout = defaultCancelOnDisconnectTimeout
+	}
+
+	guard := &CancelOnDisconnectGuard{
+		fallback:      fallback,
+		opts:          opts,
+		markets:       make(map[cancelOnDisconnectKey]CancelOnDisconnectMarket),
+		lastHeartbeat: time.Now(),
+		fired:         make(chan struct{}),
+	}
+	for _, market := range opts.Markets {
+		guard.markets[cancelOnDisconnectKey{market: market.Market, owner: market.Owner}] = market
+	}
+
+	go guard.run(ctx, disconnected)
+	return guard
+}
+
+// NewCancelOnDisconnectGuard creates a guard that fires when the websocket connection drops or heartbeats stop
+func (w *WSClient) NewCancelOnDisconnectGuard(ctx context.Context, fallback CancelAllSubmitter, opts CancelOnDisconnectOpts) *CancelOnDisconnectGuard {
+	return NewCancelOnDisconnectGuard(ctx, w.conn.Disconnected(), fallback, opts)
+}
+
+// NewCancelOnDisconnectGuard creates a guard that fires when the GRPC connection drops or heartbeats stop
+func (g *GRPCClient) NewCancelOnDisconnectGuard(ctx context.Context, fallback CancelAllSubmitter, opts CancelOnDisconnectOpts) *CancelOnDisconnectGuard {
+	return NewCancelOnDisconnectGuard(ctx, connections.GRPCDisconnected(ctx, g.conn), fallback, opts)
+}
+
+// Register adds a market to cancel when the guard fires, replacing any previous registration of the market for the
+// same owner
+func (c *CancelOnDisconnectGuard) Register(market CancelOnDisconnectMarket) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.markets[cancelOnDisconnectKey{market: market.Market, owner: market.Owner}] = market
+}
+
+// Unregister stops cancelling the owner's orders on a market when the guard fires
+func (c *CancelOnDisconnectGuard) Unregister(market, owner string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.markets, cancelOnDisconnectKey{market: market, owner: owner})
+}
+
+// Heartbeat postpones the heartbeat timeout. Call it from the trading loop to fire the guard if the loop stalls.
+func (c *CancelOnDisconnectGuard) Heartbeat() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.lastHeartbeat = time.Now()
+}
+
+// Fired returns a channel that is closed once the guard has fired and all cancels have completed
+func (c *CancelOnDisconnectGuard) Fired() <-chan struct{} {
+	return c.fired
+}
+
+// Reason returns why the guard fired, or nil if it hasn't
+func (c *CancelOnDisconnectGuard) Reason() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.reason
+}
+
+// Results returns the cancel outcome of every market once the guard has fired
+func (c *CancelOnDisconnectGuard) Results() []CancelOnDisconnectResult {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.results
+}
+
+func (c *CancelOnDisconnectGuard) run(ctx context.Context, disconnected <-chan struct{}) {
+	var heartbeat <-chan time.Time
+	if c.opts.HeartbeatTimeout > 0 {
+		ticker := time.NewTicker(c.opts.HeartbeatTimeout / 4)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+
+	for {
+		select {
+		case <-disconnected:
+			c.fire(ErrConnectionLost)
+			return
+		case now := <-heartbeat:
+			c.mutex.Lock()
+			missed := now.Sub(c.lastHeartbeat) > c.opts.HeartbeatTimeout
+			c.mutex.Unlock()
+			if missed {
+				c.fire(ErrHeartbeatMissed)
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *CancelOnDisconnectGuard) fire(reason error) {
+	c.mutex.Lock()
+	markets := make([]CancelOnDisconnectMarket, 0, len(c.markets))
+	for _, market := range c.markets {
+		markets = append(markets, market)
+	}
+	c.mutex.Unlock()
+
+	log.Warnf("cancelling orders on %v markets: %v", len(markets), reason)
+
+	results := make([]CancelOnDisconnectResult, len(markets))
+	var wg sync.WaitGroup
+	for i, market := range markets {
+		wg.Add(1)
+		go func(i int, market CancelOnDisconnectMarket) {
+			defer wg.Done()
+
+			// the guarded context may be tied to the lost connection, so cancels get their own
+			ctx, cancel := context.WithTimeout(context.Background(), c.opts.CancelTimeout)
+			defer cancel()
+
+			response, err := c.fallback(ctx, market.Market, market.Owner, market.OpenOrdersAddresses, market.Project, market.SubmitOpts)
+			if err != nil {
+				log.Errorf("failed to cancel orders on market %v: %v", market.Market, err)
+			}
+			results[i] = CancelOnDisconnectResult{Market: market, Response: response, Err: err}
+		}(i, market)
+	}
+	wg.Wait()
+
+	c.mutex.Lock()
+	c.reason = reason
+	c.results = results
+	c.mutex.Unlock()
+	close(c.fired)
+
+	if c.opts.OnFire != nil {
+		c.opts.OnFire(reason, results)
+	}
+}
@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCancelAll struct {
+	mutex   sync.Mutex
+	markets []string
+}
+
+func (f *fakeCancelAll) submit(_ context.Context, market, _ string, _ []string, _ pb.Project, _ SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.markets = append(f.markets, market)
+	return &pb.PostSubmitBatchResponse{}, nil
+}
+
+func TestCancelOnDisconnectGuardConnectionLost(t *testing.T) {
+	drop := make(chan struct{})
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		<-drop
+		_ = conn.Close()
+	}))
+	defer server.Close()
+
+	// the client closes itself once it gives up reconnecting
+	client, err := NewWSClientWithOpts(RPCOpts{Endpoint: "ws" + strings.TrimPrefix(server.URL, "http")})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fallback := &fakeCancelAll{}
+	guard := client.NewCancelOnDisconnectGuard(ctx, fallback.submit, CancelOnDisconnectOpts{
+		Markets: []CancelOnDisconnectMarket{{Market: "SOL/USDC", Owner: "a"}, {Market: "SOL/USDC", Owner: "b"}, {Market: "RAY/USDC"}},
+	})
+	guard.Unregister("RAY/USDC", "")
+
+	close(drop)
+	select {
+	case <-guard.Fired():
+	case <-time.After(5 * time.Second):
+		t.Fatal("guard did not fire after the connection dropped")
+	}
+
+	require.ErrorIs(t, guard.Reason(), ErrConnectionLost)
+	require.Equal(t, []string{"SOL/USDC", "SOL/USDC"}, fallback.markets)
+}
+
+func TestCancelOnDisconnectGuardHeartbeat(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fallback := &fakeCancelAll{}
+	guard := NewCancelOnDisconnectGuard(ctx, nil, fallback.submit, CancelOnDisconnectOpts{HeartbeatTimeout: 100 * time.Millisecond})
+	guard.Register(CancelOnDisconnectMarket{Market: "SOL/USDC"})
+
+	for i := 0; i < 5; i++ {
+		time.Sleep(50 * time.Millisecond)
+		guard.Heartbeat()
+	}
+	require.Nil(t, guard.Reason())
+
+	select {
+	case <-guard.Fired():
+	case <-time.After(time.Second):
+		t.Fatal("guard did not fire after heartbeats stopped")
+	}
+
+	require.ErrorIs(t, guard.Reason(), ErrHeartbeatMissed)
+	require.Equal(t, []string{"SOL/USDC"}, fallback.markets)
+}
@@ -1,6 +1,7 @@
 package provider
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"github.com/gagliardetto/solana-go"
+	log "github.com/sirupsen/logrus"
 )
 
 const (
@@ -79,6 +81,30 @@ type PostOrderOpts struct {
 type SubmitOpts struct {
 	SubmitStrategy pb.SubmitStrategy
 	SkipPreFlight  *bool
+
+	// RebroadcastInterval is the cadence at which SignAndSubmitWithRebroadcast re-sends a transaction until it lands
+	// or its blockhash expires. Defaults to 2 seconds.
+	RebroadcastInterval time.Duration
+
+	// ComputeBudget attaches compute unit price and limit instructions to transactions built client-side (e.g.
+	// SubmitJupiterSwapInstructions). Leave nil to submit the instructions as returned by the API.
+	ComputeBudget *ComputeBudgetOpts
+
+	// BuildSteps are applied to transactions built client-side from API instructions, e.g. transaction.MemoStep,
+	// transaction.TipStep or transaction.InstructionsStep. They run before the compute budget is attached.
+	BuildSteps []transaction.BuildStep
+
+	// DryRun builds and signs transactions as usual, but simulates them instead of submitting. The response only
+	// reports each transaction's signature and simulated error; the full results are passed to RPCOpts.OnSimulation.
+	DryRun bool
+
+	// NonceAccount makes transactions built client-side use the durable nonce of this account, whose authority must
+	// be the owner, instead of a recent blockhash. Requires RPCOpts.SolanaRPCEndpoint.
+	NonceAccount *solana.PublicKey
+
+	// Owner selects the keyring key that pays for and signs transactions the client builds itself. Defaults to the
+	// request's owner address if it has one, otherwise RPCOpts.PrivateKey.
+	Owner *solana.PublicKey
 }
 
 type RPCOpts struct {
@@ -89,6 +115,33 @@ type RPCOpts struct {
 	AuthHeader     string
 	CacheBlockHash bool
 	BlockHashTtl   time.Duration
+
+	// CachePriorityFee subscribes to GetPriorityFeeStream (WS and GRPC only) for percentiles requested by compute
+	// budget options instead of calling GetPriorityFee for every transaction
+	CachePriorityFee bool
+
+	// SolanaRPCEndpoint is a Solana RPC node used for operations the Trader API does not provide, such as
+	// transaction simulation
+	SolanaRPCEndpoint string
+
+	// SigningPolicy is checked before the client signs any transaction, making signing fail with a
+	// *transaction.PolicyViolationError if it is violated. Lookup tables of versioned transactions are resolved through
+	// SolanaRPCEndpoint; without it, accounts loaded from lookup tables cannot be verified.
+	SigningPolicy *transaction.Policy
+
+	// Keyring holds the keys of additional wallets. Transactions are signed by every key of the keyring, including
+	// PrivateKey, that they require, so one client can manage several wallets. The client keeps a copy of the keyring
+	// with PrivateKey added, so keys added to it after the client is created aren't used.
+	Keyring *transaction.Keyring
+
+	// DryRun simulates every transaction the client would submit, including submissions without SubmitOpts
+	DryRun bool
+
+	// Simulator is used for dry runs. Defaults to simulating against SolanaRPCEndpoint.
+	Simulator Simulator
+
+	// OnSimulation receives the logs, compute units and balance changes of every dry-run transaction
+	OnSimulation func(result *SimulationResult)
 }
 
 func DefaultRPCOpts(endpoint string) RPCOpts {
@@ -119,12 +172,12 @@ func ProjectFromString(project string) (pb.Project, error) {
 	return pb.Project_P_UNKNOWN, fmt.Errorf("could not find project %s", project)
 }
 
-func buildBatchRequest(transactions []*pb.TransactionMessage, privateKey solana.PrivateKey, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchRequest, error) {
+func buildBatchRequest(ctx context.Context, transactions []*pb.TransactionMessage, signer txSigner, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchRequest, error) {
 	batchRequest := pb.PostSubmitBatchRequest{}
 	batchRequest.SubmitStrategy = opts.SubmitStrategy
 
 	for _, tx := range transactions {
-		request, err := createBatchRequestEntry(opts, tx.Content, privateKey)
+		request, err := createBatchRequestEntry(ctx, opts, tx.Content, signer)
 		if err != nil {
 			return nil, err
 		}
@@ -138,7 +191,24 @@ func buildBatchRequest(transactions []*pb.TransactionMessage, privateKey solana.
 	return &batchRequest, nil
 }
 
-func createBatchRequestEntry(opts SubmitOpts, txBase64 string, privateKey solana.PrivateKey) (*pb.PostSubmitRequestEntry, error) {
+// signAndPostBatch signs the transactions and submits them with PostSubmitBatch, even if there is only one, so
+// useBundle and every SubmitOpts field take effect
+func signAndPostBatch(ctx context.Context, transactions []*pb.TransactionMessage, signer txSigner, dryRun dryRunner, post batchSubmitter, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	if signer.keyring.Len() == 0 {
+		return nil, ErrPrivateKeyNotFound
+	}
+
+	batchRequest, err := buildBatchRequest(ctx, transactions, signer, useBundle, opts)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun.active(opts) {
+		return dryRun.simulateBatch(ctx, batchRequest)
+	}
+	return post(ctx, batchRequest)
+}
+
+func createBatchRequestEntry(ctx context.Context, opts SubmitOpts, txBase64 string, signer txSigner) (*pb.PostSubmitRequestEntry, error) {
 	oneRequest := pb.PostSubmitRequestEntry{}
 	if opts.SkipPreFlight == nil {
 		oneRequest.SkipPreFlight = true
@@ -146,7 +216,7 @@ func createBatchRequestEntry(opts SubmitOpts, txBase64 string, privateKey solana
 		oneRequest.SkipPreFlight = *opts.SkipPreFlight
 	}
 
-	signedTxBase64, err := transaction.SignTxWithPrivateKey(txBase64, privateKey)
+	signedTxBase64, err := signer.sign(ctx, txBase64)
 	if err != nil {
 		return nil, err
 	}
@@ -155,3 +225,21 @@ func createBatchRequestEntry(opts SubmitOpts, txBase64 string, privateKey solana
 	}
 	return &oneRequest, nil
 }
+
+// resubscribe reopens a stream, retrying until ctx is canceled
+func resubscribe[T any](ctx context.Context, delay time.Duration, name string, subscribe func(ctx context.Context) (chan T, error)) chan T {
+	for {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil
+		}
+
+		ch, err := subscribe(ctx)
+		if err != nil {
+			log.Errorf("can't resubscribe to %v stream: %v", name, err)
+			continue
+		}
+		return ch
+	}
+}
@@ -0,0 +1,177 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+	solanarpc "github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	defaultPriorityFeePercentile = 50
+	defaultComputeUnitMargin     = 1.1
+)
+
+var ErrComputeUnitLimitUnknown = errors.New("compute unit limit not provided and no Solana RPC endpoint configured for simulation")
+
+// ComputeBudgetOpts configures the compute budget instructions attached to client-side built transactions
+type ComputeBudgetOpts struct {
+	// Percentile of recent priority fees used as the compute unit price. Defaults to 50.
+	Percentile float64
+
+	// MinComputeUnitPrice and MaxComputeUnitPrice (in micro-lamports) bound the compute unit price. A zero
+	// MaxComputeUnitPrice disables the upper bound.
+	MinComputeUnitPrice uint64
+	MaxComputeUnitPrice uint64
+
+	// MaxPriorityFee caps the total priority fee in lamports (compute unit price * compute unit limit). Zero
+	// disables the cap. MinComputeUnitPrice takes precedence, so the cap can be exceeded if it would require a
+	// lower compute unit price.
+	MaxPriorityFee uint64
+
+	// ComputeUnitLimit sets the compute unit limit. If zero, the limit is estimated by simulating the transaction
+	// against RPCOpts.SolanaRPCEndpoint.
+	ComputeUnitLimit uint32
+
+	// ComputeUnitMargin scales the simulated compute units to leave headroom. Defaults to 1.1.
+	ComputeUnitMargin float64
+}
+
+type computeUnitEstimator func(ctx context.Context, tx *solana.Transaction) (uint64, error)
+
+func newComputeUnitEstimator(endpoint string) computeUnitEstimator {
+	if endpoint == "" {
+		return nil
+	}
+
+	client := solanarpc.New(endpoint)
+	return func(ctx context.Context, tx *solana.Transaction) (uint64, error) {
+		response, err := client.SimulateTransactionWithOpts(ctx, tx, &solanarpc.SimulateTransactionOpts{
+			ReplaceRecentBlockhash: true,
+			Commitment:             solanarpc.CommitmentProcessed,
+		})
+		if err != nil {
+			return 0, fmt.Errorf("could not simulate transaction: %w", err)
+		}
+		if response.Value == nil {
+			return 0, errors.New("empty simulation response")
+		}
+		if response.Value.Err != nil {
+			return 0, fmt.Errorf("transaction simulation failed: %v", response.Value.Err)
+		}
+		if response.Value.UnitsConsumed == nil {
+			return 0, errors.New("simulation did not report consumed compute units")
+		}
+		return *response.Value.UnitsConsumed, nil
+	}
+}
+
+// addComputeBudget replaces any compute budget instructions in the provided instructions with a compute unit price
+// selected from recent priority fees and a compute unit limit that is either provided or simulated
+func addComputeBudget(
+	ctx context.Context,
+	instructions []solana.Instruction,
+	addressLookupTables map[solana.PublicKey]solana.PublicKeySlice,
+	feePayer solana.PublicKey,
+	project pb.Project,
+	opts ComputeBudgetOpts,
+	fees *priorityFeeStore,
+	estimator computeUnitEstimator,
+) ([]solana.Instruction, error) {
+	instructions = transaction.RemoveComputeBudgetInstructions(instructions)
+
+	percentile := opts.Percentile
+	if percentile == 0 {
+		percentile = defaultPriorityFeePercentile
+	}
+	fee, err := fees.get(ctx, project, percentile)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve priority fee: %w", err)
+	}
+	price := boundComputeUnitPrice(fee, opts)
+
+	limit := opts.ComputeUnitLimit
+	if limit == 0 {
+		limit, err = estimateComputeUnitLimit(ctx, instructions, addressLookupTables, feePayer, price, opts, estimator)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.MaxPriorityFee != 0 && priorityFeeLamports(limit, price) > opts.MaxPriorityFee {
+		price = opts.MaxPriorityFee * 1_000_000 / uint64(limit)
+		if price < opts.MinComputeUnitPrice {
+			price = opts.MinComputeUnitPrice
+		}
+	}
+
+	return append(transaction.CreateComputeBudgetInstructions(limit, price), instructions...), nil
+}
+
+// computeBudgetStep attaches compute budget instructions once all other instructions of the transaction are known
+func computeBudgetStep(project pb.Project, opts ComputeBudgetOpts, fees *priorityFeeStore, estimator computeUnitEstimator) transaction.BuildStep {
+	return func(ctx context.Context, b *transaction.TxBuilder) error {
+		instructions, err := addComputeBudget(ctx, b.Instructions(), b.AddressLookupTables(), b.FeePayer(), project, opts, fees, estimator)
+		if err != nil {
+			return err
+		}
+		b.SetInstructions(instructions)
+		return nil
+	}
+}
+
+func boundComputeUnitPrice(fee uint64, opts ComputeBudgetOpts) uint64 {
+	if fee < opts.MinComputeUnitPrice {
+		fee = opts.MinComputeUnitPrice
+	}
+	if opts.MaxComputeUnitPrice != 0 && fee > opts.MaxComputeUnitPrice {
+		fee = opts.MaxComputeUnitPrice
+	}
+	return fee
+}
+
+func priorityFeeLamports(limit uint32, price uint64) uint64 {
+	return uint64(math.Ceil(float64(limit) * float64(price) / 1_000_000))
+}
+
+func estimateComputeUnitLimit(
+	ctx context.Context,
+	instructions []solana.Instruction,
+	addressLookupTables map[solana.PublicKey]solana.PublicKeySlice,
+	feePayer solana.PublicKey,
+	price uint64,
+	opts ComputeBudgetOpts,
+	estimator computeUnitEstimator,
+) (uint32, error) {
+	if estimator == nil {
+		return 0, ErrComputeUnitLimitUnknown
+	}
+
+	// simulate with the maximum limit so the estimate is not constrained by the default limit
+	simulationInstructions := append(transaction.CreateComputeBudgetInstructions(transaction.MaxComputeUnitLimit, price), instructions...)
+	tx, err := solana.NewTransaction(simulationInstructions, solana.Hash{}, solana.TransactionPayer(feePayer), solana.TransactionAddressTables(addressLookupTables))
+	if err != nil {
+		return 0, err
+	}
+	tx.Signatures = make([]solana.Signature, tx.Message.Header.NumRequiredSignatures)
+
+	consumed, err := estimator(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	margin := opts.ComputeUnitMargin
+	if margin == 0 {
+		margin = defaultComputeUnitMargin
+	}
+	limit := math.Ceil(float64(consumed) * margin)
+	if limit > transaction.MaxComputeUnitLimit {
+		limit = transaction.MaxComputeUnitLimit
+	}
+	return uint32(limit), nil
+}
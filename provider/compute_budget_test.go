@@ -0,0 +1,107 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBoundComputeUnitPrice(t *testing.T) {
+	tests := []struct {
+		name     string
+		fee      uint64
+		opts     ComputeBudgetOpts
+		expected uint64
+	}{
+		{name: "unbounded", fee: 5000, expected: 5000},
+		{name: "raised to minimum", fee: 5000, opts: ComputeBudgetOpts{MinComputeUnitPrice: 10_000}, expected: 10_000},
+		{name: "lowered to maximum", fee: 5000, opts: ComputeBudgetOpts{MaxComputeUnitPrice: 1000}, expected: 1000},
+		{name: "within bounds", fee: 5000, opts: ComputeBudgetOpts{MinComputeUnitPrice: 1000, MaxComputeUnitPrice: 10_000}, expected: 5000},
+		{name: "zero maximum disables the upper bound", fee: 1_000_000, opts: ComputeBudgetOpts{MinComputeUnitPrice: 1000}, expected: 1_000_000},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, boundComputeUnitPrice(test.fee, test.opts))
+		})
+	}
+}
+
+func TestPriorityFeeLamports(t *testing.T) {
+	require.Equal(t, uint64(0), priorityFeeLamports(200_000, 0))
+	require.Equal(t, uint64(1_000_000), priorityFeeLamports(200_000, 5_000_000))
+
+	// fractions of a lamport are rounded up
+	require.Equal(t, uint64(1), priorityFeeLamports(1, 1))
+	require.Equal(t, uint64(3), priorityFeeLamports(200_001, 10))
+}
+
+func TestAddComputeBudget(t *testing.T) {
+	payer := solana.NewWallet().PublicKey()
+	transfer := system.NewTransferInstruction(1, payer, solana.NewWallet().PublicKey()).Build()
+	instructions := append(transaction.CreateComputeBudgetInstructions(1, 1), transfer)
+	estimator := func(_ context.Context, _ *solana.Transaction) (uint64, error) {
+		return 100_000, nil
+	}
+
+	tests := []struct {
+		name      string
+		opts      ComputeBudgetOpts
+		estimator computeUnitEstimator
+		limit     uint32
+		price     uint64
+	}{
+		{
+			name:  "fixed limit",
+			opts:  ComputeBudgetOpts{ComputeUnitLimit: 200_000},
+			limit: 200_000,
+			price: 5_000_000,
+		},
+		{
+			name:  "price bounded",
+			opts:  ComputeBudgetOpts{ComputeUnitLimit: 200_000, MaxComputeUnitPrice: 1_000_000},
+			limit: 200_000,
+			price: 1_000_000,
+		},
+		{
+			// 200,000 compute units at 5,000,000 micro-lamports are 1,000,000 lamports, twice the cap
+			name:  "total fee capped",
+			opts:  ComputeBudgetOpts{ComputeUnitLimit: 200_000, MaxPriorityFee: 500_000},
+			limit: 200_000,
+			price: 2_500_000,
+		},
+		{
+			name:  "minimum takes precedence over cap",
+			opts:  ComputeBudgetOpts{ComputeUnitLimit: 200_000, MinComputeUnitPrice: 4_000_000, MaxPriorityFee: 500_000},
+			limit: 200_000,
+			price: 4_000_000,
+		},
+		{
+			name:  "fee below cap",
+			opts:  ComputeBudgetOpts{ComputeUnitLimit: 200_000, MaxPriorityFee: 5_000_000},
+			limit: 200_000,
+			price: 5_000_000,
+		},
+		{
+			name:      "simulated limit",
+			opts:      ComputeBudgetOpts{ComputeUnitMargin: 1.5, MaxPriorityFee: 300_000},
+			estimator: estimator,
+			limit:     150_000,
+			price:     2_000_000,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := addComputeBudget(context.Background(), instructions, nil, payer, pb.Project_P_RAYDIUM, test.opts, fixedPriorityFees(5_000_000), test.estimator)
+			require.NoError(t, err)
+			require.Equal(t, append(transaction.CreateComputeBudgetInstructions(test.limit, test.price), transfer), result)
+		})
+	}
+
+	_, err := addComputeBudget(context.Background(), instructions, nil, payer, pb.Project_P_RAYDIUM, ComputeBudgetOpts{}, fixedPriorityFees(5_000_000), nil)
+	require.ErrorIs(t, err, ErrComputeUnitLimitUnknown)
+}
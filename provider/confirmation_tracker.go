@@ -0,0 +1,543 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// maxBlockHashAge is the number of blocks after which a blockhash can no longer be used by the cluster
+	maxBlockHashAge = 150
+
+	// finalizedSlotDepth is the number of slots after which a confirmed block is considered rooted
+	finalizedSlotDepth = 32
+
+	defaultConfirmationPollInterval = 2 * time.Second
+	defaultBlockHashValidity        = 90 * time.Second
+	defaultSlotDuration             = 400 * time.Millisecond
+	defaultConfirmationResultBuffer = 100
+	defaultBlockResubscribeDelay    = time.Second
+)
+
+// ErrBlockHashExpired is returned when a transaction's blockhash expired before the transaction was seen on chain. The
+// transaction can no longer land, so this is a definitive failure.
+var ErrBlockHashExpired = errors.New("transaction blockhash expired before confirmation")
+
+// Commitment is the level of confirmation a transaction has reached
+type Commitment int
+
+const (
+	CommitmentProcessed Commitment = iota
+	CommitmentConfirmed
+	CommitmentFinalized
+)
+
+func (c Commitment) String() string {
+	switch c {
+	case CommitmentProcessed:
+		return "processed"
+	case CommitmentConfirmed:
+		return "confirmed"
+	case CommitmentFinalized:
+		return "finalized"
+	default:
+		return fmt.Sprintf("commitment(%d)", int(c))
+	}
+}
+
+// TransactionError indicates that a transaction landed on chain but failed during execution. Fees are still charged.
+type TransactionError struct {
+	Signature string
+	Message   string
+}
+
+func (e TransactionError) Error() string {
+	return fmt.Sprintf("transaction %v failed: %v", e.Signature, e.Message)
+}
+
+// ConfirmationResult describes the final state of a tracked transaction
+type ConfirmationResult struct {
+	Signature  string
+	Commitment Commitment
+	Slot       uint64
+	Fee        uint64
+	Err        error
+}
+
+// ConfirmationFuture resolves once its transaction reaches the tracker's target commitment or fails
+type ConfirmationFuture struct {
+	signature string
+	done      chan struct{}
+	result    ConfirmationResult
+}
+
+func newConfirmationFuture(signature string) *ConfirmationFuture {
+	return &ConfirmationFuture{
+		signature: signature,
+		done:      make(chan struct{}),
+	}
+}
+
+// Signature returns the signature being tracked
+func (f *ConfirmationFuture) Signature() string {
+	return f.signature
+}
+
+// Done returns a channel that is closed once the result is available
+func (f *ConfirmationFuture) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait blocks until the result is available or the context is canceled
+func (f *ConfirmationFuture) Wait(ctx context.Context) (ConfirmationResult, error) {
+	select {
+	case <-f.done:
+		return f.result, nil
+	case <-ctx.Done():
+		return ConfirmationResult{}, ctx.Err()
+	}
+}
+
+func (f *ConfirmationFuture) resolve(result ConfirmationResult) {
+	f.result = result
+	close(f.done)
+}
+
+type ConfirmationTrackerOpts struct {
+	// Commitment is the level a transaction must reach before its future resolves. A transaction found by
+	// GetTransaction is at the commitment its status reports, or confirmed if the status names none.
+	Commitment Commitment
+
+	// PollInterval is how often pending transactions are queried with GetTransaction
+	PollInterval time.Duration
+
+	// BlockHashValidity is the wall-clock expiry used when no block stream is available
+	BlockHashValidity time.Duration
+
+	// ResultBuffer is the size of the channel returned by Results
+	ResultBuffer int
+}
+
+func DefaultConfirmationTrackerOpts() ConfirmationTrackerOpts {
+	return ConfirmationTrackerOpts{
+		Commitment:        CommitmentConfirmed,
+		PollInterval:      defaultConfirmationPollInterval,
+		BlockHashValidity: defaultBlockHashValidity,
+		ResultBuffer:      defaultConfirmationResultBuffer,
+	}
+}
+
+type transactionProvider func(ctx context.Context, request *pb.GetTransactionRequest) (*pb.GetTransactionResponse, error)
+type blockStreamProvider func(ctx context.Context) (connections.Streamer[*pb.GetBlockStreamResponse], error)
+
+// seenBlock is a block from the block stream whose hash transactions may use as their recent blockhash
+type seenBlock struct {
+	height uint64
+	seenAt time.Time
+}
+
+type trackedTransaction struct {
+	future     *ConfirmationFuture
+	trackedAt  time.Time
+	height     uint64
+	commitment Commitment
+	landed     bool
+	slot       uint64
+	fee        uint64
+	landedAt   time.Time
+}
+
+// ConfirmationTracker watches submitted transactions until they reach the requested commitment, fail, or their
+// blockhash expires. Transactions are queried with GetTransaction, while the block stream (if available) is used to
+// track the current slot and block height for finalization and expiry, and is reopened if it closes.
+type ConfirmationTracker struct {
+	mutex               sync.Mutex
+	txProvider          transactionProvider
+	blockStreamProvider blockStreamProvider
+	opts                ConfirmationTrackerOpts
+	pending             map[string]*trackedTransaction
+	results             chan ConfirmationResult
+	resubscribeDelay    time.Duration
+
+	slot   uint64
+	height uint64
+
+	// blocks holds the blocks of the last maxBlockHashAge heights by hash, so expiry can be counted from a
+	// transaction's blockhash
+	blocks map[string]seenBlock
+}
+
+func newConfirmationTracker(txProvider transactionProvider, blockStreamProvider blockStreamProvider, opts ConfirmationTrackerOpts) *ConfirmationTracker {
+	if opts.PollInterval == 0 {
+		opts.PollInterval = defaultConfirmationPollInterval
+	}
+	if opts.BlockHashValidity == 0 {
+		opts.BlockHashValidity = defaultBlockHashValidity
+	}
+	if opts.ResultBuffer == 0 {
+		opts.ResultBuffer = defaultConfirmationResultBuffer
+	}
+
+	return &ConfirmationTracker{
+		txProvider:          txProvider,
+		blockStreamProvider: blockStreamProvider,
+		opts:                opts,
+		pending:             make(map[string]*trackedTransaction),
+		results:             make(chan ConfirmationResult, opts.ResultBuffer),
+		resubscribeDelay:    defaultBlockResubscribeDelay,
+		blocks:              make(map[string]seenBlock),
+	}
+}
+
+// NewConfirmationTracker creates a tracker that polls transactions and follows the block stream until ctx is canceled
+func (w *WSClient) NewConfirmationTracker(ctx context.Context, opts ConfirmationTrackerOpts) *ConfirmationTracker {
+	tracker := newConfirmationTracker(w.GetTransaction, w.GetBlockStream, opts)
+	go tracker.run(ctx)
+	return tracker
+}
+
+// NewConfirmationTracker creates a tracker that polls transactions and follows the block stream until ctx is canceled
+func (g *GRPCClient) NewConfirmationTracker(ctx context.Context, opts ConfirmationTrackerOpts) *ConfirmationTracker {
+	tracker := newConfirmationTracker(g.GetTransaction, g.GetBlockStream, opts)
+	go tracker.run(ctx)
+	return tracker
+}
+
+// NewConfirmationTracker creates a tracker that polls transactions until ctx is canceled. HTTP has no block stream,
+// so expiry and finalization are estimated from wall-clock time.
+func (h *HTTPClient) NewConfirmationTracker(ctx context.Context, opts ConfirmationTrackerOpts) *ConfirmationTracker {
+	tracker := newConfirmationTracker(h.GetTransaction, nil, opts)
+	go tracker.run(ctx)
+	return tracker
+}
+
+// Results returns a channel on which every resolved transaction is published, in addition to its future. Results
+// are dropped if the channel is full.
+func (t *ConfirmationTracker) Results() <-chan ConfirmationResult {
+	return t.results
+}
+
+// Track starts watching a signature. The blockhash validity window starts at the time of this call; use
+// TrackWithBlockHash if the transaction's blockhash is known.
+func (t *ConfirmationTracker) Track(signature string) *ConfirmationFuture {
+	return t.TrackWithBlockHash(signature, "")
+}
+
+// TrackWithBlockHash starts watching a signature whose transaction uses recentBlockHash. The blockhash validity window
+// starts at the block of that hash if the block stream has seen it, and at the time of this call otherwise.
+func (t *ConfirmationTracker) TrackWithBlockHash(signature string, recentBlockHash string) *ConfirmationFuture {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if tracked, ok := t.pending[signature]; ok {
+		return tracked.future
+	}
+
+	future := newConfirmationFuture(signature)
+	tracked := &trackedTransaction{
+		future:    future,
+		trackedAt: time.Now(),
+		height:    t.height,
+	}
+	if block, ok := t.blocks[recentBlockHash]; ok && recentBlockHash != "" {
+		tracked.trackedAt = block.seenAt
+		tracked.height = block.height
+	}
+	t.pending[signature] = tracked
+	return future
+}
+
+// TrackAll starts watching all provided signatures
+func (t *ConfirmationTracker) TrackAll(signatures []string) []*ConfirmationFuture {
+	futures := make([]*ConfirmationFuture, 0, len(signatures))
+	for _, signature := range signatures {
+		futures = append(futures, t.Track(signature))
+	}
+	return futures
+}
+
+// TrackBatch starts watching every entry of a batch submission. Entries that were not submitted resolve immediately
+// with their submission error.
+func (t *ConfirmationTracker) TrackBatch(response *pb.PostSubmitBatchResponse) []*ConfirmationFuture {
+	futures := make([]*ConfirmationFuture, 0, len(response.Transactions))
+	for _, entry := range response.Transactions {
+		if !entry.Submitted {
+			future := newConfirmationFuture(entry.Signature)
+			t.publish(future, ConfirmationResult{
+				Signature: entry.Signature,
+				Err:       fmt.Errorf("transaction was not submitted: %v", entry.Error),
+			})
+			futures = append(futures, future)
+			continue
+		}
+		futures = append(futures, t.Track(entry.Signature))
+	}
+	return futures
+}
+
+// Pending returns the number of transactions that have not resolved yet
+func (t *ConfirmationTracker) Pending() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return len(t.pending)
+}
+
+func (t *ConfirmationTracker) run(ctx context.Context) {
+	if t.blockStreamProvider != nil {
+		go t.followBlocks(ctx)
+	}
+
+	ticker := time.NewTicker(t.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			t.poll(ctx)
+		case <-ctx.Done():
+			t.cancelPending(ctx.Err())
+			return
+		}
+	}
+}
+
+func (t *ConfirmationTracker) subscribeBlocks(ctx context.Context) (chan *pb.GetBlockStreamResponse, error) {
+	stream, err := t.blockStreamProvider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return stream.Channel(10), nil
+}
+
+func (t *ConfirmationTracker) followBlocks(ctx context.Context) {
+	blocks, err := t.subscribeBlocks(ctx)
+	if err != nil {
+		log.Errorf("can't open block stream for confirmation tracking, using wall-clock expiry until it opens: %v", err)
+		blocks = resubscribe(ctx, t.resubscribeDelay, "block", t.subscribeBlocks)
+		if blocks == nil {
+			return
+		}
+	}
+
+	for {
+		select {
+		case block, ok := <-blocks:
+			if !ok {
+				// a frozen slot would keep finalized from ever resolving, so both finalization and expiry fall back to
+				// wall-clock estimates until the stream is reopened
+				log.Warn("block stream for confirmation tracking closed, resubscribing")
+				t.mutex.Lock()
+				t.slot = 0
+				t.height = 0
+				t.mutex.Unlock()
+
+				blocks = resubscribe(ctx, t.resubscribeDelay, "block", t.subscribeBlocks)
+				if blocks == nil {
+					return
+				}
+				continue
+			}
+			if block.Block == nil {
+				continue
+			}
+			t.observeBlock(block.Block, time.Now())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (t *ConfirmationTracker) observeBlock(block *pb.Block, now time.Time) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if block.Slot > t.slot {
+		t.slot = block.Slot
+	}
+	if block.Height > t.height {
+		t.height = block.Height
+	}
+	if block.Hash != "" {
+		t.blocks[block.Hash] = seenBlock{height: block.Height, seenAt: now}
+	}
+	for hash, seen := range t.blocks {
+		if seen.height+maxBlockHashAge < t.height {
+			delete(t.blocks, hash)
+		}
+	}
+}
+
+func (t *ConfirmationTracker) poll(ctx context.Context) {
+	t.mutex.Lock()
+	toQuery := make([]string, 0, len(t.pending))
+	for signature, tracked := range t.pending {
+		// processed transactions are queried again until they are confirmed, after which finalization follows the slot
+		if !tracked.landed || tracked.commitment == CommitmentProcessed && t.opts.Commitment > CommitmentProcessed {
+			toQuery = append(toQuery, signature)
+		}
+	}
+	t.mutex.Unlock()
+
+	responses := make([]*pb.GetTransactionResponse, len(toQuery))
+	wg := sync.WaitGroup{}
+	for i, signature := range toQuery {
+		wg.Add(1)
+		go func(i int, signature string) {
+			defer wg.Done()
+			responses[i] = t.query(ctx, signature)
+		}(i, signature)
+	}
+	wg.Wait()
+
+	now := time.Now()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for i, signature := range toQuery {
+		tracked, ok := t.pending[signature]
+		if !ok {
+			continue
+		}
+		if responses[i] != nil {
+			t.land(tracked, responses[i], now)
+		}
+	}
+
+	for signature, tracked := range t.pending {
+		if tracked.landed {
+			if t.finalized(tracked, now) {
+				tracked.commitment = CommitmentFinalized
+			}
+			if tracked.commitment >= t.opts.Commitment {
+				delete(t.pending, signature)
+				t.publish(tracked.future, ConfirmationResult{
+					Signature:  signature,
+					Commitment: tracked.commitment,
+					Slot:       tracked.slot,
+					Fee:        tracked.fee,
+				})
+			}
+			continue
+		}
+
+		if t.expired(tracked, now) {
+			delete(t.pending, signature)
+			t.publish(tracked.future, ConfirmationResult{
+				Signature: signature,
+				Err:       ErrBlockHashExpired,
+			})
+		}
+	}
+}
+
+// query returns nil if the transaction has not been seen yet
+func (t *ConfirmationTracker) query(ctx context.Context, signature string) *pb.GetTransactionResponse {
+	response, err := t.txProvider(ctx, &pb.GetTransactionRequest{Signature: signature})
+	if err != nil {
+		log.Debugf("transaction %v not found yet: %v", signature, err)
+		return nil
+	}
+	if response.Slot == 0 {
+		return nil
+	}
+	return response
+}
+
+// land records a transaction seen on chain. Failed transactions resolve immediately regardless of the target
+// commitment, since their outcome can no longer change.
+func (t *ConfirmationTracker) land(tracked *trackedTransaction, response *pb.GetTransactionResponse, now time.Time) {
+	if !tracked.landed {
+		tracked.landed = true
+		tracked.landedAt = now
+	}
+	tracked.slot = response.Slot
+	if commitment := transactionCommitment(response); commitment > tracked.commitment {
+		tracked.commitment = commitment
+	}
+	if response.Metadata != nil {
+		tracked.fee = response.Metadata.Fee
+	}
+
+	if failure := transactionFailure(response); failure != "" {
+		signature := tracked.future.Signature()
+		delete(t.pending, signature)
+		t.publish(tracked.future, ConfirmationResult{
+			Signature:  signature,
+			Commitment: tracked.commitment,
+			Slot:       tracked.slot,
+			Fee:        tracked.fee,
+			Err:        TransactionError{Signature: signature, Message: failure},
+		})
+	}
+}
+
+// transactionCommitment returns the commitment named by the transaction's status. GetTransaction only returns
+// transactions that are at least confirmed unless its status says otherwise.
+func transactionCommitment(response *pb.GetTransactionResponse) Commitment {
+	switch strings.ToLower(response.Status) {
+	case "processed":
+		return CommitmentProcessed
+	case "finalized":
+		return CommitmentFinalized
+	default:
+		return CommitmentConfirmed
+	}
+}
+
+func transactionFailure(response *pb.GetTransactionResponse) string {
+	if response.Metadata != nil && (response.Metadata.Errored || response.Metadata.Err != "") {
+		if response.Metadata.Err != "" {
+			return response.Metadata.Err
+		}
+		return "unknown error"
+	}
+	if strings.EqualFold(response.Status, "failed") {
+		return "unknown error"
+	}
+	return ""
+}
+
+func (t *ConfirmationTracker) finalized(tracked *trackedTransaction, now time.Time) bool {
+	if t.slot != 0 {
+		return t.slot >= tracked.slot+finalizedSlotDepth
+	}
+	return now.Sub(tracked.landedAt) >= finalizedSlotDepth*defaultSlotDuration
+}
+
+func (t *ConfirmationTracker) expired(tracked *trackedTransaction, now time.Time) bool {
+	if t.height != 0 && tracked.height != 0 {
+		return t.height > tracked.height+maxBlockHashAge
+	}
+	return now.Sub(tracked.trackedAt) > t.opts.BlockHashValidity
+}
+
+func (t *ConfirmationTracker) cancelPending(err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for signature, tracked := range t.pending {
+		delete(t.pending, signature)
+		t.publish(tracked.future, ConfirmationResult{
+			Signature:  signature,
+			Commitment: tracked.commitment,
+			Slot:       tracked.slot,
+			Fee:        tracked.fee,
+			Err:        err,
+		})
+	}
+}
+
+func (t *ConfirmationTracker) publish(future *ConfirmationFuture, result ConfirmationResult) {
+	future.resolve(result)
+	select {
+	case t.results <- result:
+	default:
+		log.Warnf("confirmation results channel full, dropping result for %v", result.Signature)
+	}
+}
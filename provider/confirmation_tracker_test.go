@@ -0,0 +1,176 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfirmationTracker(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mutex         sync.Mutex
+		subscriptions int
+	)
+	landed := map[string]uint64{}
+	blocks := make(chan *pb.GetBlockStreamResponse)
+	drop := make(chan struct{})
+	tracker := newConfirmationTracker(func(_ context.Context, request *pb.GetTransactionRequest) (*pb.GetTransactionResponse, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		slot, ok := landed[request.Signature]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		return &pb.GetTransactionResponse{Slot: slot, Metadata: &pb.TransactionMeta{Fee: 5000}}, nil
+	}, func(_ context.Context) (connections.Streamer[*pb.GetBlockStreamResponse], error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		subscriptions++
+
+		// the first stream is dropped on request, later ones stay open
+		first := subscriptions == 1
+		return func() (*pb.GetBlockStreamResponse, error) {
+			if !first {
+				return <-blocks, nil
+			}
+			select {
+			case block := <-blocks:
+				return block, nil
+			case <-drop:
+				return nil, errors.New("closed")
+			}
+		}, nil
+	}, ConfirmationTrackerOpts{Commitment: CommitmentFinalized, BlockHashValidity: time.Hour})
+	tracker.resubscribeDelay = time.Millisecond
+	go tracker.followBlocks(ctx)
+
+	block := func(slot, height uint64) {
+		blocks <- &pb.GetBlockStreamResponse{Block: &pb.Block{Slot: slot, Height: height, Hash: fmt.Sprint("hash ", height)}}
+		require.Eventually(t, func() bool {
+			tracker.mutex.Lock()
+			defer tracker.mutex.Unlock()
+			return tracker.slot == slot && tracker.height == height
+		}, time.Second, time.Millisecond)
+	}
+	land := func(signature string, slot uint64) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		landed[signature] = slot
+	}
+	resolved := func(future *ConfirmationFuture) bool {
+		select {
+		case <-future.Done():
+			return true
+		default:
+			return false
+		}
+	}
+
+	block(100, 1000)
+	confirmed := tracker.Track("confirmed")
+	expiring := tracker.Track("expiring")
+	closing := tracker.Track("closing")
+
+	// a confirmed transaction waits for the slot to advance past the finalization depth
+	land("confirmed", 101)
+	tracker.poll(ctx)
+	require.False(t, resolved(confirmed))
+	require.Equal(t, CommitmentConfirmed, tracker.pending["confirmed"].commitment)
+
+	block(132, 1100)
+	tracker.poll(ctx)
+	require.False(t, resolved(confirmed))
+
+	// expiry counts from the block of the transaction's blockhash rather than from when it's tracked
+	old := tracker.TrackWithBlockHash("old blockhash", "hash 1000")
+	unknown := tracker.TrackWithBlockHash("unknown blockhash", "hash 1")
+
+	block(133, 1101)
+	tracker.poll(ctx)
+	require.True(t, resolved(confirmed))
+	result, err := confirmed.Wait(ctx)
+	require.NoError(t, err)
+	require.NoError(t, result.Err)
+	require.Equal(t, CommitmentFinalized, result.Commitment)
+	require.Equal(t, uint64(101), result.Slot)
+	require.Equal(t, uint64(5000), result.Fee)
+
+	// the blockhash expires once the block height moves past its validity window
+	land("closing", 140)
+	block(150, 1151)
+	tracker.poll(ctx)
+	require.True(t, resolved(expiring))
+	result, err = expiring.Wait(ctx)
+	require.NoError(t, err)
+	require.ErrorIs(t, result.Err, ErrBlockHashExpired)
+	require.True(t, resolved(old))
+	require.False(t, resolved(unknown))
+	require.False(t, resolved(closing))
+
+	// while the stream is closed, finalization falls back to the time since the transaction landed
+	close(drop)
+	require.Eventually(t, func() bool {
+		tracker.mutex.Lock()
+		defer tracker.mutex.Unlock()
+		return tracker.slot == 0 && tracker.height == 0
+	}, time.Second, time.Millisecond)
+	tracker.poll(ctx)
+	require.False(t, resolved(closing))
+
+	tracker.mutex.Lock()
+	tracker.pending["closing"].landedAt = time.Now().Add(-finalizedSlotDepth * defaultSlotDuration)
+	tracker.mutex.Unlock()
+	tracker.poll(ctx)
+	require.True(t, resolved(closing))
+	result, err = closing.Wait(ctx)
+	require.NoError(t, err)
+	require.NoError(t, result.Err)
+	require.Equal(t, CommitmentFinalized, result.Commitment)
+
+	// the stream is reopened, so expiry follows the block height again
+	block(200, 1251)
+	tracker.poll(ctx)
+	require.True(t, resolved(unknown))
+	result, err = unknown.Wait(ctx)
+	require.NoError(t, err)
+	require.ErrorIs(t, result.Err, ErrBlockHashExpired)
+	require.Equal(t, 0, tracker.Pending())
+	mutex.Lock()
+	defer mutex.Unlock()
+	require.Equal(t, 2, subscriptions)
+}
+
+func TestConfirmationTrackerCommitment(t *testing.T) {
+	ctx := context.Background()
+	status := "processed"
+	tracker := newConfirmationTracker(func(_ context.Context, _ *pb.GetTransactionRequest) (*pb.GetTransactionResponse, error) {
+		return &pb.GetTransactionResponse{Status: status, Slot: 100}, nil
+	}, nil, ConfirmationTrackerOpts{Commitment: CommitmentProcessed})
+	processed := tracker.Track("processed")
+	tracker.poll(ctx)
+	result, err := processed.Wait(ctx)
+	require.NoError(t, err)
+	require.Equal(t, CommitmentProcessed, result.Commitment)
+
+	// processed transactions are queried again until they reach the target commitment
+	tracker.opts.Commitment = CommitmentConfirmed
+	confirmed := tracker.Track("confirmed")
+	tracker.poll(ctx)
+	require.Equal(t, 1, tracker.Pending())
+
+	status = ""
+	tracker.poll(ctx)
+	result, err = confirmed.Wait(ctx)
+	require.NoError(t, err)
+	require.Equal(t, CommitmentConfirmed, result.Commitment)
+}
@@ -21,9 +21,19 @@ type GRPCClient struct {
 	pb.UnimplementedApiServer
 
 	apiClient pb.ApiClient
+	conn      *grpc.ClientConn
 
 	privateKey           *solana.PrivateKey
+	keyring              *transaction.Keyring
 	recentBlockHashStore *recentBlockHashStore
+	priorityFeeStore     *priorityFeeStore
+	bundleTipStore       *bundleTipStore
+	computeUnitEstimator computeUnitEstimator
+	signingPolicy        *transaction.Policy
+	lookupTableResolver  lookupTableResolver
+	dryRun               dryRunner
+	nonceProvider        nonceProvider
+	mintProvider         mintProvider
 }
 
 // NewGRPCClient connects to Mainnet Trader API
@@ -78,7 +88,7 @@ func (bc blxrCredentials) RequireTransportSecurity() bool {
 // NewGRPCClientWithOpts connects to custom Trader API
 func NewGRPCClientWithOpts(opts RPCOpts, dialOpts ...grpc.DialOption) (*GRPCClient, error) {
 	var (
-		conn     grpc.ClientConnInterface
+		conn     *grpc.ClientConn
 		err      error
 		grpcOpts = make([]grpc.DialOption, 0)
 	)
@@ -100,8 +110,16 @@ func NewGRPCClientWithOpts(opts RPCOpts, dialOpts ...grpc.DialOption) (*GRPCClie
 	}
 
 	client := &GRPCClient{
-		apiClient:  pb.NewApiClient(conn),
-		privateKey: opts.PrivateKey,
+		apiClient:            pb.NewApiClient(conn),
+		conn:                 conn,
+		privateKey:           opts.PrivateKey,
+		keyring:              newKeyring(opts),
+		computeUnitEstimator: newComputeUnitEstimator(opts.SolanaRPCEndpoint),
+		signingPolicy:        opts.SigningPolicy,
+		lookupTableResolver:  newLookupTableResolver(opts.SolanaRPCEndpoint),
+		dryRun:               newDryRunner(opts),
+		nonceProvider:        newNonceProvider(opts.SolanaRPCEndpoint),
+		mintProvider:         newMintProvider(opts.SolanaRPCEndpoint),
 	}
 
 	client.recentBlockHashStore = newRecentBlockHashStore(
@@ -112,9 +130,23 @@ func NewGRPCClientWithOpts(opts RPCOpts, dialOpts ...grpc.DialOption) (*GRPCClie
 	if opts.CacheBlockHash {
 		go client.recentBlockHashStore.run(context.Background())
 	}
+	client.priorityFeeStore = newPriorityFeeStore(
+		func(ctx context.Context, project pb.Project, percentile *float64) (*pb.GetPriorityFeeResponse, error) {
+			return client.GetPriorityFee(ctx, &pb.GetPriorityFeeRequest{Project: project, Percentile: percentile})
+		},
+		client.GetPriorityFeeStream,
+		opts,
+	)
+	client.bundleTipStore = newBundleTipStore(client.GetBundleTipStream)
 	return client, nil
 }
 
+// Close stops the streams kept open by the client and closes the connection
+func (g *GRPCClient) Close() error {
+	g.priorityFeeStore.close()
+	return g.conn.Close()
+}
+
 func (g *GRPCClient) RecentBlockHash(ctx context.Context) (*pb.GetRecentBlockHashResponse, error) {
 	return g.recentBlockHashStore.get(ctx)
 }
@@ -164,6 +196,11 @@ func (g *GRPCClient) GetRaydiumQuotesCPMM(ctx context.Context, request *pb.GetRa
 	return g.apiClient.GetRaydiumCPMMQuotes(ctx, request)
 }
 
+// GetRaydiumCLMMQuotes returns the CLMM quotes on Raydium
+func (g *GRPCClient) GetRaydiumCLMMQuotes(ctx context.Context, request *pb.GetRaydiumCLMMQuotesRequest) (*pb.GetRaydiumCLMMQuotesResponse, error) {
+	return g.apiClient.GetRaydiumCLMMQuotes(ctx, request)
+}
+
 // GetPumpFunQuotes returns the best quotes for swapping a token on PumpFun platform
 func (g *GRPCClient) GetPumpFunQuotes(ctx context.Context, request *pb.GetPumpFunQuotesRequest) (*pb.GetPumpFunQuotesResponse, error) {
 	return g.apiClient.GetPumpFunQuotes(ctx, request)
@@ -307,17 +344,23 @@ func (g *GRPCClient) GetQuotes(ctx context.Context, inToken, outToken string, in
 	return g.apiClient.GetQuotes(ctx, &pb.GetQuotesRequest{InToken: inToken, OutToken: outToken, InAmount: inAmount, Slippage: slippage, Limit: limit, Projects: projects})
 }
 
-// SignAndSubmit signs the given transaction and submits it.
+// SignAndSubmit signs the given transaction and submits it. If RPCOpts.DryRun is set, the transaction is simulated
+// instead and a simulated failure is returned as an error; SignAndSimulate dry-runs a single transaction and returns
+// its SimulationResult.
 func (g *GRPCClient) SignAndSubmit(ctx context.Context, tx *pb.TransactionMessage,
 	skipPreFlight bool, frontRunningProtection bool, useStakedRPCs bool) (string, error) {
-	if g.privateKey == nil {
+	if g.keyring.Len() == 0 {
 		return "", ErrPrivateKeyNotFound
 	}
-	txBase64, err := transaction.SignTxWithPrivateKey(tx.Content, *g.privateKey)
+	txBase64, err := g.txSigner().sign(ctx, tx.Content)
 	if err != nil {
 		return "", err
 	}
 
+	if g.dryRun.enabled {
+		return g.dryRun.simulateSignature(ctx, txBase64)
+	}
+
 	response, err := g.PostSubmit(ctx, &pb.TransactionMessage{
 		Content:   txBase64,
 		IsCleanup: tx.IsCleanup,
@@ -329,12 +372,33 @@ func (g *GRPCClient) SignAndSubmit(ctx context.Context, tx *pb.TransactionMessag
 	return response.Signature, nil
 }
 
+// SignAndSimulate signs the given transaction and simulates it instead of submitting it, whether or not
+// RPCOpts.DryRun is set. The result is also passed to RPCOpts.OnSimulation.
+func (g *GRPCClient) SignAndSimulate(ctx context.Context, tx *pb.TransactionMessage) (*SimulationResult, error) {
+	if g.keyring.Len() == 0 {
+		return nil, ErrPrivateKeyNotFound
+	}
+	txBase64, err := g.txSigner().sign(ctx, tx.Content)
+	if err != nil {
+		return nil, err
+	}
+	return g.dryRun.simulate(ctx, txBase64)
+}
+
 // signAndSubmitBatch signs the given transactions and submits them.
 func (g *GRPCClient) signAndSubmitBatch(ctx context.Context, transactions []*pb.TransactionMessage, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
-	if g.privateKey == nil {
+	if g.keyring.Len() == 0 {
 		return nil, ErrPrivateKeyNotFound
 	}
 
+	if g.dryRun.active(opts) {
+		batchRequest, err := buildBatchRequest(ctx, transactions, g.txSigner(), useBundle, opts)
+		if err != nil {
+			return nil, err
+		}
+		return g.dryRun.simulateBatch(ctx, batchRequest)
+	}
+
 	if len(transactions) == 1 {
 		println("here")
 		signature, err := g.SignAndSubmit(ctx, transactions[0], *opts.SkipPreFlight, false, false)
@@ -352,7 +416,7 @@ func (g *GRPCClient) signAndSubmitBatch(ctx context.Context, transactions []*pb.
 		}, nil
 	}
 
-	batchRequest, err := buildBatchRequest(transactions, *g.privateKey, useBundle, opts)
+	batchRequest, err := buildBatchRequest(ctx, transactions, g.txSigner(), useBundle, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -360,6 +424,11 @@ func (g *GRPCClient) signAndSubmitBatch(ctx context.Context, transactions []*pb.
 	return g.PostSubmitBatch(ctx, batchRequest)
 }
 
+// signAndPostBatch signs the given transactions and submits them as one batch request, even if there is only one
+func (g *GRPCClient) signAndPostBatch(ctx context.Context, transactions []*pb.TransactionMessage, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return signAndPostBatch(ctx, transactions, g.txSigner(), g.dryRun, g.PostSubmitBatch, useBundle, opts)
+}
+
 // PostTradeSwap returns a partially signed transaction for submitting a swap request
 func (g *GRPCClient) PostTradeSwap(ctx context.Context, ownerAddress, inToken, outToken string, inAmount, slippage float64, project pb.Project) (*pb.TradeSwapResponse, error) {
 	return g.apiClient.PostTradeSwap(ctx, &pb.TradeSwapRequest{
@@ -502,120 +571,57 @@ func (g *GRPCClient) SubmitJupiterSwap(ctx context.Context, request *pb.PostJupi
 
 // SubmitJupiterSwapInstructions builds a Jupiter Swap transaction then signs it, and submits to the network.
 func (g *GRPCClient) SubmitJupiterSwapInstructions(ctx context.Context, request *pb.PostJupiterSwapInstructionsRequest, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
-	swapInstructions, err := g.PostJupiterSwapInstructions(ctx, request)
-	if err != nil {
-		return nil, err
-	}
-
-	txBuilder := solana.NewTransactionBuilder()
-
-	addressLookupTable, err := utils.ConvertProtoAddressLookupTable(swapInstructions.AddressLookupTableAddresses)
-	if err != nil {
-		return nil, err
-	}
-
-	txBuilder.WithOpt(solana.TransactionAddressTables(addressLookupTable))
-
-	instructions, err := utils.ConvertJupiterInstructions(swapInstructions.Instructions)
+	opts, err := withOwner(opts, request.OwnerAddress)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, inst := range instructions {
-		txBuilder.AddInstruction(inst)
-	}
-
-	txBuilder.SetFeePayer(g.privateKey.PublicKey())
-	blockHash, err := g.RecentBlockHash(ctx)
-
-	if err != nil {
-		panic(fmt.Errorf("server error: could not retrieve block hash: %w", err))
-	}
-
-	hash, err := solana.HashFromBase58(blockHash.BlockHash)
+	swapInstructions, err := g.PostJupiterSwapInstructions(ctx, request)
 	if err != nil {
 		return nil, err
 	}
 
-	txBuilder.SetRecentBlockHash(hash)
-	tx, err := txBuilder.Build()
+	addressLookupTable, err := utils.ConvertProtoAddressLookupTable(swapInstructions.AddressLookupTableAddresses)
 	if err != nil {
 		return nil, err
 	}
 
-	err = transaction.PartialSign(tx, g.privateKey.PublicKey(), make(map[solana.PublicKey]solana.PrivateKey))
+	instructions, err := utils.ConvertJupiterInstructions(swapInstructions.Instructions)
 	if err != nil {
 		return nil, err
 	}
 
-	var txToBeSigned []*pb.TransactionMessage
-
-	txBase64, err := tx.ToBase64()
+	tx, err := g.instructionTxBuilder().build(ctx, instructions, addressLookupTable, pb.Project_P_JUPITER, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	txToBeSigned = append(txToBeSigned, &pb.TransactionMessage{
-		Content:   txBase64,
-		IsCleanup: false,
-	})
-
-	return g.signAndSubmitBatch(ctx, txToBeSigned, useBundle, opts)
+	return g.signAndPostBatch(ctx, []*pb.TransactionMessage{tx}, useBundle, opts)
 }
 
 // SubmitRaydiumSwapInstructions builds a Raydium Swap transaction then signs it, and submits to the network.
 func (g *GRPCClient) SubmitRaydiumSwapInstructions(ctx context.Context, request *pb.PostRaydiumSwapInstructionsRequest, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
-	swapInstructions, err := g.PostRaydiumSwapInstructions(ctx, request)
+	opts, err := withOwner(opts, request.OwnerAddress)
 	if err != nil {
 		return nil, err
 	}
 
-	instructions, err := utils.ConvertRaydiumInstructions(swapInstructions.Instructions)
-	if err != nil {
-		return nil, err
-	}
-	txBuilder := solana.NewTransactionBuilder()
-
-	for _, inst := range instructions {
-		txBuilder.AddInstruction(inst)
-	}
-
-	txBuilder.SetFeePayer(g.privateKey.PublicKey())
-	blockHash, err := g.RecentBlockHash(ctx)
-
-	if err != nil {
-		panic(fmt.Errorf("server error: could not retrieve block hash: %w", err))
-	}
-
-	hash, err := solana.HashFromBase58(blockHash.BlockHash)
-	if err != nil {
-		return nil, err
-	}
-
-	txBuilder.SetRecentBlockHash(hash)
-	tx, err := txBuilder.Build()
+	swapInstructions, err := g.PostRaydiumSwapInstructions(ctx, request)
 	if err != nil {
 		return nil, err
 	}
 
-	err = transaction.PartialSign(tx, g.privateKey.PublicKey(), make(map[solana.PublicKey]solana.PrivateKey))
+	instructions, err := utils.ConvertRaydiumInstructions(swapInstructions.Instructions)
 	if err != nil {
 		return nil, err
 	}
 
-	var txToBeSigned []*pb.TransactionMessage
-
-	txBase64, err := tx.ToBase64()
+	tx, err := g.instructionTxBuilder().build(ctx, instructions, nil, pb.Project_P_RAYDIUM, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	txToBeSigned = append(txToBeSigned, &pb.TransactionMessage{
-		Content:   txBase64,
-		IsCleanup: false,
-	})
-
-	return g.signAndSubmitBatch(ctx, txToBeSigned, useBundle, opts)
+	return g.signAndPostBatch(ctx, []*pb.TransactionMessage{tx}, useBundle, opts)
 }
 
 // SubmitJupiterRouteSwap builds a Jupiter RouteSwap transaction then signs it, and submits to the network.
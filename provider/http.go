@@ -20,7 +20,15 @@ type HTTPClient struct {
 	httpClient *http.Client
 	requestID  utils.RequestID
 	privateKey *solana.PrivateKey
+	keyring    *transaction.Keyring
 	authHeader string
+
+	priorityFeeStore     *priorityFeeStore
+	computeUnitEstimator computeUnitEstimator
+	signingPolicy        *transaction.Policy
+	lookupTableResolver  lookupTableResolver
+	dryRun               dryRunner
+	nonceProvider        nonceProvider
 }
 
 // NewHTTPClient connects to Mainnet Trader API
@@ -60,12 +68,20 @@ func NewHTTPClientWithOpts(client *http.Client, opts RPCOpts) *HTTPClient {
 		client = &http.Client{}
 	}
 
-	return &HTTPClient{
-		baseURL:    opts.Endpoint,
-		httpClient: client,
-		privateKey: opts.PrivateKey,
-		authHeader: opts.AuthHeader,
+	h := &HTTPClient{
+		baseURL:              opts.Endpoint,
+		httpClient:           client,
+		privateKey:           opts.PrivateKey,
+		keyring:              newKeyring(opts),
+		authHeader:           opts.AuthHeader,
+		computeUnitEstimator: newComputeUnitEstimator(opts.SolanaRPCEndpoint),
+		signingPolicy:        opts.SigningPolicy,
+		lookupTableResolver:  newLookupTableResolver(opts.SolanaRPCEndpoint),
+		dryRun:               newDryRunner(opts),
+		nonceProvider:        newNonceProvider(opts.SolanaRPCEndpoint),
 	}
+	h.priorityFeeStore = newPriorityFeeStore(h.GetPriorityFee, nil, opts)
+	return h
 }
 
 // GetRaydiumCLMMQuotes returns the CLMM quotes on Raydium
@@ -560,17 +576,23 @@ func (h *HTTPClient) PostSubmitBatchV2(ctx context.Context, request *pb.PostSubm
 	return &response, nil
 }
 
-// SignAndSubmit signs the given transaction and submits it.
+// SignAndSubmit signs the given transaction and submits it. If RPCOpts.DryRun is set, the transaction is simulated
+// instead and a simulated failure is returned as an error; SignAndSimulate dry-runs a single transaction and returns
+// its SimulationResult.
 func (h *HTTPClient) SignAndSubmit(ctx context.Context, tx *pb.TransactionMessage,
 	skipPreFlight bool, frontRunningProtection bool, useStakedRPCs bool) (string, error) {
-	if h.privateKey == nil {
+	if h.keyring.Len() == 0 {
 		return "", ErrPrivateKeyNotFound
 	}
-	txBase64, err := transaction.SignTxWithPrivateKey(tx.Content, *h.privateKey)
+	txBase64, err := h.txSigner().sign(ctx, tx.Content)
 	if err != nil {
 		return "", err
 	}
 
+	if h.dryRun.enabled {
+		return h.dryRun.simulateSignature(ctx, txBase64)
+	}
+
 	response, err := h.PostSubmit(ctx, txBase64, skipPreFlight, frontRunningProtection, useStakedRPCs)
 	if err != nil {
 		return "", err
@@ -579,13 +601,34 @@ func (h *HTTPClient) SignAndSubmit(ctx context.Context, tx *pb.TransactionMessag
 	return response.Signature, nil
 }
 
+// SignAndSimulate signs the given transaction and simulates it instead of submitting it, whether or not
+// RPCOpts.DryRun is set. The result is also passed to RPCOpts.OnSimulation.
+func (h *HTTPClient) SignAndSimulate(ctx context.Context, tx *pb.TransactionMessage) (*SimulationResult, error) {
+	if h.keyring.Len() == 0 {
+		return nil, ErrPrivateKeyNotFound
+	}
+	txBase64, err := h.txSigner().sign(ctx, tx.Content)
+	if err != nil {
+		return nil, err
+	}
+	return h.dryRun.simulate(ctx, txBase64)
+}
+
 // SignAndSubmitBatch signs the given transactions and submits them.
 func (h *HTTPClient) SignAndSubmitBatch(ctx context.Context, transactions []*pb.TransactionMessage, useBundle bool,
 	opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
-	if h.privateKey == nil {
+	if h.keyring.Len() == 0 {
 		return nil, ErrPrivateKeyNotFound
 	}
 
+	if h.dryRun.active(opts) {
+		batchRequest, err := buildBatchRequest(ctx, transactions, h.txSigner(), useBundle, opts)
+		if err != nil {
+			return nil, err
+		}
+		return h.dryRun.simulateBatch(ctx, batchRequest)
+	}
+
 	if len(transactions) == 1 {
 		signature, err := h.SignAndSubmit(ctx, transactions[0], *opts.SkipPreFlight, false, false)
 		if err != nil {
@@ -602,7 +645,7 @@ func (h *HTTPClient) SignAndSubmitBatch(ctx context.Context, transactions []*pb.
 		}, nil
 	}
 
-	batchRequest, err := buildBatchRequest(transactions, *h.privateKey, useBundle, opts)
+	batchRequest, err := buildBatchRequest(ctx, transactions, h.txSigner(), useBundle, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -629,6 +672,11 @@ func (h *HTTPClient) PostTradeSwap(ctx context.Context, ownerAddress, inToken, o
 	return &response, nil
 }
 
+// signAndPostBatch signs the given transactions and submits them as one batch request, even if there is only one
+func (h *HTTPClient) signAndPostBatch(ctx context.Context, transactions []*pb.TransactionMessage, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return signAndPostBatch(ctx, transactions, h.txSigner(), h.dryRun, h.PostSubmitBatch, useBundle, opts)
+}
+
 // SubmitTradeSwap builds a TradeSwap transaction then signs it, and submits to the network.
 func (h *HTTPClient) SubmitTradeSwap(ctx context.Context, owner, inToken, outToken string, inAmount, slippage float64, project pb.Project, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
 	resp, err := h.PostTradeSwap(ctx, owner, inToken, outToken, inAmount, slippage, project)
@@ -715,120 +763,57 @@ func (h *HTTPClient) SubmitJupiterSwap(ctx context.Context, request *pb.PostJupi
 
 // SubmitJupiterSwapInstructions builds a Jupiter Swap transaction then signs it, and submits to the network.
 func (h *HTTPClient) SubmitJupiterSwapInstructions(ctx context.Context, request *pb.PostJupiterSwapInstructionsRequest, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
-	swapInstructions, err := h.PostJupiterSwapInstructions(ctx, request)
-	if err != nil {
-		return nil, err
-	}
-
-	txBuilder := solana.NewTransactionBuilder()
-
-	addressLookupTable, err := utils.ConvertProtoAddressLookupTable(swapInstructions.AddressLookupTableAddresses)
+	opts, err := withOwner(opts, request.OwnerAddress)
 	if err != nil {
 		return nil, err
 	}
 
-	txBuilder.WithOpt(solana.TransactionAddressTables(addressLookupTable))
-
-	instructions, err := utils.ConvertJupiterInstructions(swapInstructions.Instructions)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, inst := range instructions {
-		txBuilder.AddInstruction(inst)
-	}
-
-	txBuilder.SetFeePayer(h.privateKey.PublicKey())
-	blockHash, err := h.GetRecentBlockHash(ctx)
-
-	if err != nil {
-		panic(fmt.Errorf("server error: could not retrieve block hash: %w", err))
-	}
-
-	hash, err := solana.HashFromBase58(blockHash.BlockHash)
+	swapInstructions, err := h.PostJupiterSwapInstructions(ctx, request)
 	if err != nil {
 		return nil, err
 	}
 
-	txBuilder.SetRecentBlockHash(hash)
-	tx, err := txBuilder.Build()
+	addressLookupTable, err := utils.ConvertProtoAddressLookupTable(swapInstructions.AddressLookupTableAddresses)
 	if err != nil {
 		return nil, err
 	}
 
-	err = transaction.PartialSign(tx, h.privateKey.PublicKey(), make(map[solana.PublicKey]solana.PrivateKey))
+	instructions, err := utils.ConvertJupiterInstructions(swapInstructions.Instructions)
 	if err != nil {
 		return nil, err
 	}
 
-	var txToBeSigned []*pb.TransactionMessage
-
-	txBase64, err := tx.ToBase64()
+	tx, err := h.instructionTxBuilder().build(ctx, instructions, addressLookupTable, pb.Project_P_JUPITER, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	txToBeSigned = append(txToBeSigned, &pb.TransactionMessage{
-		Content:   txBase64,
-		IsCleanup: false,
-	})
-
-	return h.SignAndSubmitBatch(ctx, txToBeSigned, useBundle, opts)
+	return h.signAndPostBatch(ctx, []*pb.TransactionMessage{tx}, useBundle, opts)
 }
 
 // SubmitRaydiumSwapInstructions builds a Raydium Swap transaction then signs it, and submits to the network.
 func (h *HTTPClient) SubmitRaydiumSwapInstructions(ctx context.Context, request *pb.PostRaydiumSwapInstructionsRequest, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
-	swapInstructions, err := h.PostRaydiumSwapInstructions(ctx, request)
-	if err != nil {
-		return nil, err
-	}
-
-	instructions, err := utils.ConvertRaydiumInstructions(swapInstructions.Instructions)
+	opts, err := withOwner(opts, request.OwnerAddress)
 	if err != nil {
 		return nil, err
 	}
 
-	txBuilder := solana.NewTransactionBuilder()
-	for _, inst := range instructions {
-		txBuilder.AddInstruction(inst)
-	}
-
-	txBuilder.SetFeePayer(h.privateKey.PublicKey())
-	blockHash, err := h.GetRecentBlockHash(ctx)
-
-	if err != nil {
-		panic(fmt.Errorf("server error: could not retrieve block hash: %w", err))
-	}
-
-	hash, err := solana.HashFromBase58(blockHash.BlockHash)
-	if err != nil {
-		return nil, err
-	}
-
-	txBuilder.SetRecentBlockHash(hash)
-	tx, err := txBuilder.Build()
+	swapInstructions, err := h.PostRaydiumSwapInstructions(ctx, request)
 	if err != nil {
 		return nil, err
 	}
 
-	err = transaction.PartialSign(tx, h.privateKey.PublicKey(), make(map[solana.PublicKey]solana.PrivateKey))
+	instructions, err := utils.ConvertRaydiumInstructions(swapInstructions.Instructions)
 	if err != nil {
 		return nil, err
 	}
 
-	var txToBeSigned []*pb.TransactionMessage
-
-	txBase64, err := tx.ToBase64()
+	tx, err := h.instructionTxBuilder().build(ctx, instructions, nil, pb.Project_P_RAYDIUM, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	txToBeSigned = append(txToBeSigned, &pb.TransactionMessage{
-		Content:   txBase64,
-		IsCleanup: false,
-	})
-
-	return h.SignAndSubmitBatch(ctx, txToBeSigned, useBundle, opts)
+	return h.signAndPostBatch(ctx, []*pb.TransactionMessage{tx}, useBundle, opts)
 }
 
 // SubmitJupiterRouteSwap builds a Jupiter RouteSwap transaction then signs it, and submits to the network.
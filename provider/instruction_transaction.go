@@ -0,0 +1,108 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+)
+
+// instructionTxBuilder builds transactions client-side from instructions returned by the API. The transactions are
+// left for SignAndSubmitBatch to sign.
+type instructionTxBuilder struct {
+	signer    txSigner
+	blockHash blockHashProvider
+	fees      *priorityFeeStore
+	estimator computeUnitEstimator
+	nonce     nonceProvider
+}
+
+func (w *WSClient) instructionTxBuilder() instructionTxBuilder {
+	return instructionTxBuilder{
+		signer:    w.txSigner(),
+		blockHash: w.RecentBlockHash,
+		fees:      w.priorityFeeStore,
+		estimator: w.computeUnitEstimator,
+		nonce:     w.nonceProvider,
+	}
+}
+
+func (g *GRPCClient) instructionTxBuilder() instructionTxBuilder {
+	return instructionTxBuilder{
+		signer:    g.txSigner(),
+		blockHash: g.RecentBlockHash,
+		fees:      g.priorityFeeStore,
+		estimator: g.computeUnitEstimator,
+		nonce:     g.nonceProvider,
+	}
+}
+
+func (h *HTTPClient) instructionTxBuilder() instructionTxBuilder {
+	return instructionTxBuilder{
+		signer:    h.txSigner(),
+		blockHash: h.GetRecentBlockHash,
+		fees:      h.priorityFeeStore,
+		estimator: h.computeUnitEstimator,
+		nonce:     h.nonceProvider,
+	}
+}
+
+// build assembles the instructions into a transaction paid by the owner of opts, applying the user build steps from opts
+// followed by the compute budget. The transaction uses opts.NonceAccount in place of a recent blockhash if set.
+func (b instructionTxBuilder) build(
+	ctx context.Context,
+	instructions []solana.Instruction,
+	addressLookupTables map[solana.PublicKey]solana.PublicKeySlice,
+	project pb.Project,
+	opts SubmitOpts,
+) (*pb.TransactionMessage, error) {
+	owner, err := b.signer.owner(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	feePayer := owner.PublicKey()
+	txBuilder := transaction.NewTxBuilder(feePayer).
+		AddInstructions(instructions...).
+		AddStep(transaction.AddressLookupTablesStep(addressLookupTables)).
+		AddStep(opts.BuildSteps...).
+		AddValidator(transaction.ValidateSize, transaction.ValidateAccounts)
+	if opts.ComputeBudget != nil {
+		txBuilder.AddStep(computeBudgetStep(project, *opts.ComputeBudget, b.fees, b.estimator))
+	}
+
+	var hash solana.Hash
+	if opts.NonceAccount != nil {
+		nonceAccount, err := getNonceAccount(ctx, b.nonce, *opts.NonceAccount)
+		if err != nil {
+			return nil, err
+		}
+		if !nonceAccount.Authority.Equals(feePayer) {
+			return nil, fmt.Errorf("nonce account %v is not controlled by %v", *opts.NonceAccount, feePayer)
+		}
+		txBuilder.AddStep(transaction.NonceStep(*opts.NonceAccount, feePayer))
+		hash = nonceAccount.Nonce
+	} else {
+		blockHash, err := b.blockHash(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("could not retrieve block hash: %w", err)
+		}
+		hash, err = solana.HashFromBase58(blockHash.BlockHash)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := txBuilder.Build(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	txBase64, err := tx.ToBase64()
+	if err != nil {
+		return nil, err
+	}
+	return &pb.TransactionMessage{Content: txBase64}, nil
+}
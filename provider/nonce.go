@@ -0,0 +1,132 @@
+package provider
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+	solanarpc "github.com/gagliardetto/solana-go/rpc"
+)
+
+var ErrNonceUnavailable = errors.New("durable nonces require RPCOpts.SolanaRPCEndpoint")
+
+type nonceProvider func(ctx context.Context, nonceAccount solana.PublicKey) (*transaction.NonceAccount, error)
+
+func newNonceProvider(endpoint string) nonceProvider {
+	if endpoint == "" {
+		return nil
+	}
+
+	client := solanarpc.New(endpoint)
+	return func(ctx context.Context, nonceAccount solana.PublicKey) (*transaction.NonceAccount, error) {
+		return transaction.GetNonceAccount(ctx, client, nonceAccount)
+	}
+}
+
+// GetNonceAccount returns the current state of a nonce account
+func (w *WSClient) GetNonceAccount(ctx context.Context, nonceAccount solana.PublicKey) (*transaction.NonceAccount, error) {
+	return getNonceAccount(ctx, w.nonceProvider, nonceAccount)
+}
+
+// GetNonceAccount returns the current state of a nonce account
+func (g *GRPCClient) GetNonceAccount(ctx context.Context, nonceAccount solana.PublicKey) (*transaction.NonceAccount, error) {
+	return getNonceAccount(ctx, g.nonceProvider, nonceAccount)
+}
+
+// GetNonceAccount returns the current state of a nonce account
+func (h *HTTPClient) GetNonceAccount(ctx context.Context, nonceAccount solana.PublicKey) (*transaction.NonceAccount, error) {
+	return getNonceAccount(ctx, h.nonceProvider, nonceAccount)
+}
+
+func getNonceAccount(ctx context.Context, nonce nonceProvider, nonceAccount solana.PublicKey) (*transaction.NonceAccount, error) {
+	if nonce == nil {
+		return nil, ErrNonceUnavailable
+	}
+	return nonce(ctx, nonceAccount)
+}
+
+// CreateNonceAccount creates a rent exempt nonce account with the owner of opts as its authority and submits it
+func (w *WSClient) CreateNonceAccount(ctx context.Context, nonceAccount solana.PrivateKey, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	tx, err := w.instructionTxBuilder().buildCreateNonceAccount(ctx, nonceAccount, opts)
+	if err != nil {
+		return nil, err
+	}
+	return w.signAndPostBatch(ctx, []*pb.TransactionMessage{tx}, false, opts)
+}
+
+// CreateNonceAccount creates a rent exempt nonce account with the owner of opts as its authority and submits it
+func (g *GRPCClient) CreateNonceAccount(ctx context.Context, nonceAccount solana.PrivateKey, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	tx, err := g.instructionTxBuilder().buildCreateNonceAccount(ctx, nonceAccount, opts)
+	if err != nil {
+		return nil, err
+	}
+	return g.signAndPostBatch(ctx, []*pb.TransactionMessage{tx}, false, opts)
+}
+
+// CreateNonceAccount creates a rent exempt nonce account with the owner of opts as its authority and submits it
+func (h *HTTPClient) CreateNonceAccount(ctx context.Context, nonceAccount solana.PrivateKey, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	tx, err := h.instructionTxBuilder().buildCreateNonceAccount(ctx, nonceAccount, opts)
+	if err != nil {
+		return nil, err
+	}
+	return h.signAndPostBatch(ctx, []*pb.TransactionMessage{tx}, false, opts)
+}
+
+// AdvanceNonceAccount advances a nonce account owned by the owner of opts, invalidating transactions signed with its
+// current nonce
+func (w *WSClient) AdvanceNonceAccount(ctx context.Context, nonceAccount solana.PublicKey, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	tx, err := w.instructionTxBuilder().buildAdvanceNonceAccount(ctx, nonceAccount, opts)
+	if err != nil {
+		return nil, err
+	}
+	return w.signAndPostBatch(ctx, []*pb.TransactionMessage{tx}, false, opts)
+}
+
+// AdvanceNonceAccount advances a nonce account owned by the owner of opts, invalidating transactions signed with its
+// current nonce
+func (g *GRPCClient) AdvanceNonceAccount(ctx context.Context, nonceAccount solana.PublicKey, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	tx, err := g.instructionTxBuilder().buildAdvanceNonceAccount(ctx, nonceAccount, opts)
+	if err != nil {
+		return nil, err
+	}
+	return g.signAndPostBatch(ctx, []*pb.TransactionMessage{tx}, false, opts)
+}
+
+// AdvanceNonceAccount advances a nonce account owned by the owner of opts, invalidating transactions signed with its
+// current nonce
+func (h *HTTPClient) AdvanceNonceAccount(ctx context.Context, nonceAccount solana.PublicKey, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	tx, err := h.instructionTxBuilder().buildAdvanceNonceAccount(ctx, nonceAccount, opts)
+	if err != nil {
+		return nil, err
+	}
+	return h.signAndPostBatch(ctx, []*pb.TransactionMessage{tx}, false, opts)
+}
+
+func (b instructionTxBuilder) buildCreateNonceAccount(ctx context.Context, nonceAccount solana.PrivateKey, opts SubmitOpts) (*pb.TransactionMessage, error) {
+	ownerKey, err := b.signer.owner(opts)
+	if err != nil {
+		return nil, err
+	}
+	owner := ownerKey.PublicKey()
+
+	// the new account can't use itself as a nonce, and its signature is added by the builder
+	opts.NonceAccount = nil
+	opts.BuildSteps = append(append([]transaction.BuildStep{}, opts.BuildSteps...), transaction.SignerStep(nonceAccount))
+
+	instructions := transaction.CreateNonceAccountInstructions(owner, nonceAccount.PublicKey(), owner, transaction.NonceAccountRentExemption)
+	return b.build(ctx, instructions, nil, pb.Project_P_UNKNOWN, opts)
+}
+
+func (b instructionTxBuilder) buildAdvanceNonceAccount(ctx context.Context, nonceAccount solana.PublicKey, opts SubmitOpts) (*pb.TransactionMessage, error) {
+	owner, err := b.signer.owner(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// advancing with a recent blockhash still works after the nonce has been used
+	opts.NonceAccount = nil
+	instructions := []solana.Instruction{transaction.CreateAdvanceNonceInstruction(nonceAccount, owner.PublicKey())}
+	return b.build(ctx, instructions, nil, pb.Project_P_UNKNOWN, opts)
+}
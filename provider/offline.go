@@ -0,0 +1,79 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// ExportOfflineFile packs the unsigned transactions of a Post* response (e.g. PostTradeSwap or PostRaydiumSwap) into
+// an offline file. All other fields of the response are kept as readable metadata.
+func ExportOfflineFile(description string, response proto.Message, transactions []*pb.TransactionMessage) (*transaction.OfflineFile, error) {
+	metadata, err := offlineMetadata(response)
+	if err != nil {
+		return nil, err
+	}
+
+	offlineTransactions := make([]transaction.OfflineTransaction, 0, len(transactions))
+	for _, tx := range transactions {
+		offlineTransactions = append(offlineTransactions, transaction.OfflineTransaction{
+			Content:   tx.Content,
+			IsCleanup: tx.IsCleanup,
+		})
+	}
+	return transaction.NewOfflineFile(description, metadata, time.Now(), offlineTransactions)
+}
+
+func offlineMetadata(response proto.Message) (json.RawMessage, error) {
+	if response == nil {
+		return nil, nil
+	}
+
+	// the transactions are stored separately
+	metadata := proto.Clone(response).ProtoReflect()
+	if field := metadata.Descriptor().Fields().ByName("transactions"); field != nil {
+		metadata.Clear(field)
+	}
+
+	b, err := protojson.MarshalOptions{Indent: "  "}.Marshal(metadata.Interface())
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// SubmitOfflineFile submits the signed transactions of an offline file through the submitter, e.g. the
+// PostSubmitBatch method of any client. The file must be fully signed and not expired.
+func SubmitOfflineFile(ctx context.Context, submit batchSubmitter, file *transaction.OfflineFile, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	if !file.Signed() {
+		return nil, transaction.ErrOfflineFileUnsigned
+	}
+	if err := file.CheckExpiry(time.Now()); err != nil {
+		return nil, err
+	}
+
+	skipPreFlight := true
+	if opts.SkipPreFlight != nil {
+		skipPreFlight = *opts.SkipPreFlight
+	}
+
+	request := &pb.PostSubmitBatchRequest{
+		SubmitStrategy: opts.SubmitStrategy,
+		UseBundle:      &useBundle,
+	}
+	for _, offlineTx := range file.Transactions {
+		request.Entries = append(request.Entries, &pb.PostSubmitRequestEntry{
+			Transaction: &pb.TransactionMessage{
+				Content:   offlineTx.Content,
+				IsCleanup: offlineTx.IsCleanup,
+			},
+			SkipPreFlight: skipPreFlight,
+		})
+	}
+	return submit(ctx, request)
+}
@@ -0,0 +1,617 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/bloXroute-Labs/solana-trader-proto/common"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultOrderFillBuffer       = 100
+	defaultOrderResubscribeDelay = time.Second
+)
+
+var (
+	// ErrOrderNotFound is returned for client order IDs the order manager doesn't know about
+	ErrOrderNotFound = errors.New("order not found")
+
+	// ErrOrderNotSeen is set on orders that never appeared on the market before their transaction expired
+	ErrOrderNotSeen = errors.New("order was not seen on the market before its transaction expired")
+)
+
+// OrderState is the lifecycle stage of an order tracked by the OrderManager
+type OrderState int
+
+const (
+	// OrderStateSubmitted orders were sent to the network but haven't been seen on the market yet
+	OrderStateSubmitted OrderState = iota
+	OrderStateOpen
+	OrderStatePartiallyFilled
+	OrderStateFilled
+	OrderStateCancelled
+
+	// OrderStateRejected orders failed to submit or never reached the market
+	OrderStateRejected
+
+	// OrderStateClosed orders left the book while the status stream was unavailable, so it's unknown whether they were
+	// filled or cancelled
+	OrderStateClosed
+)
+
+func (s OrderState) String() string {
+	switch s {
+	case OrderStateSubmitted:
+		return "submitted"
+	case OrderStateOpen:
+		return "open"
+	case OrderStatePartiallyFilled:
+		return "partially filled"
+	case OrderStateFilled:
+		return "filled"
+	case OrderStateCancelled:
+		return "cancelled"
+	case OrderStateRejected:
+		return "rejected"
+	case OrderStateClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// Terminal returns true if the order can't change anymore
+func (s OrderState) Terminal() bool {
+	return s == OrderStateFilled || s == OrderStateCancelled || s == OrderStateRejected || s == OrderStateClosed
+}
+
+// ManagedOrder is the local view of an order
+type ManagedOrder struct {
+	ClientOrderID uint64
+	OrderID       string
+	Market        string
+	Side          pb.Side
+	Price         float64
+
+	// Amount is the size the order was placed with. For orders found on startup it's the size remaining at the time.
+	Amount          float64
+	FilledAmount    float64
+	RemainingAmount float64
+
+	State           OrderState
+	CancelRequested bool
+
+	// Signature is the signature of the transaction that placed the order
+	Signature string
+	Err       error
+	UpdatedAt time.Time
+}
+
+// OrderFill is a fill of a tracked order
+type OrderFill struct {
+	ClientOrderID   uint64
+	OrderID         string
+	Market          string
+	Side            pb.Side
+	Price           float64
+	Amount          float64
+	RemainingAmount float64
+	Slot            int64
+	Time            time.Time
+}
+
+type OrderManagerOpts struct {
+	Market            string
+	Owner             string
+	Payer             string
+	OpenOrdersAddress string
+	Project           pb.Project
+	SkipPreFlight     *bool
+
+	// FirstClientOrderID is the first client order ID allocated. Defaults to the current Unix time in milliseconds, so
+	// IDs don't collide across restarts. IDs of orders found on startup are never reused.
+	FirstClientOrderID uint64
+
+	// RejectAfter is how long a submitted order may remain unseen on the market before it's marked rejected
+	RejectAfter time.Duration
+
+	// FillBuffer is the size of the channel returned by Fills
+	FillBuffer int
+}
+
+type orderPoster func(ctx context.Context, owner, payer, market string, side pb.Side, types []common.OrderType, amount, price float64, project pb.Project, opts PostOrderOpts) (*pb.PostOrderResponse, error)
+type orderReplacer func(ctx context.Context, orderID, owner, payer, market string, side pb.Side, types []common.OrderType, amount, price float64, project pb.Project, opts PostOrderOpts) (*pb.PostOrderResponse, error)
+type orderCanceller func(ctx context.Context, clientOrderID uint64, owner, market, openOrders string, project pb.Project) (*pb.PostCancelOrderResponse, error)
+type openOrdersProvider func(ctx context.Context, market string, owner string, openOrdersAddress string, project pb.Project) (*pb.GetOpenOrdersResponse, error)
+type orderStatusStreamProvider func(ctx context.Context, market, ownerAddress string, project pb.Project) (connections.Streamer[*pb.GetOrderStatusStreamResponse], error)
+type transactionSubmitter func(ctx context.Context, tx *pb.TransactionMessage, skipPreFlight bool, frontRunningProtection bool, useStakedRPCs bool) (string, error)
+
+type orderManagerAPI struct {
+	post         orderPoster
+	replace      orderReplacer
+	cancel       orderCanceller
+	openOrders   openOrdersProvider
+	statusStream orderStatusStreamProvider
+	submit       transactionSubmitter
+}
+
+// OrderManager places, replaces and cancels OpenBook orders for one market and owner, and keeps a local view of
+// them from GetOrderStatusStream. Open orders are reconciled with GetOpenOrders on startup and whenever the status
+// stream has to be reopened. It's only available on the WS and GRPC clients, since it depends on streaming.
+type OrderManager struct {
+	mutex            sync.Mutex
+	api              orderManagerAPI
+	opts             OrderManagerOpts
+	orders           map[uint64]*ManagedOrder
+	byOrderID        map[string]uint64
+	nextClientID     uint64
+	fills            chan OrderFill
+	resubscribeDelay time.Duration
+}
+
+func newOrderManager(api orderManagerAPI, opts OrderManagerOpts) *OrderManager {
+	if opts.FirstClientOrderID == 0 {
+		opts.FirstClientOrderID = uint64(time.Now().UnixMilli())
+	}
+	if opts.RejectAfter == 0 {
+		opts.RejectAfter = defaultBlockHashValidity
+	}
+	if opts.FillBuffer == 0 {
+		opts.FillBuffer = defaultOrderFillBuffer
+	}
+
+	return &OrderManager{
+		api:              api,
+		opts:             opts,
+		orders:           make(map[uint64]*ManagedOrder),
+		byOrderID:        make(map[string]uint64),
+		nextClientID:     opts.FirstClientOrderID,
+		fills:            make(chan OrderFill, opts.FillBuffer),
+		resubscribeDelay: defaultOrderResubscribeDelay,
+	}
+}
+
+// NewOrderManager subscribes to order status updates, reconciles open orders and tracks them until ctx is canceled
+func (w *WSClient) NewOrderManager(ctx context.Context, opts OrderManagerOpts) (*OrderManager, error) {
+	return startOrderManager(ctx, orderManagerAPI{
+		post:         w.PostOrder,
+		replace:      w.PostReplaceOrder,
+		cancel:       w.PostCancelByClientOrderID,
+		openOrders:   w.GetOpenOrders,
+		statusStream: w.GetOrderStatusStream,
+		submit:       w.SignAndSubmit,
+	}, opts)
+}
+
+// NewOrderManager subscribes to order status updates, reconciles open orders and tracks them until ctx is canceled
+func (g *GRPCClient) NewOrderManager(ctx context.Context, opts OrderManagerOpts) (*OrderManager, error) {
+	return startOrderManager(ctx, orderManagerAPI{
+		post:         g.PostOrder,
+		replace:      g.PostReplaceOrder,
+		cancel:       g.PostCancelByClientOrderID,
+		openOrders:   g.GetOpenOrders,
+		statusStream: g.GetOrderStatusStream,
+		submit:       g.SignAndSubmit,
+	}, opts)
+}
+
+func startOrderManager(ctx context.Context, api orderManagerAPI, opts OrderManagerOpts) (*OrderManager, error) {
+	m := newOrderManager(api, opts)
+
+	// subscribe before reconciling, so no update between the two is missed
+	updates, err := m.subscribe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.reconcile(ctx); err != nil {
+		return nil, err
+	}
+
+	go m.run(ctx, updates)
+	return m, nil
+}
+
+// Fills returns a channel on which every fill of a tracked order is published. Fills are dropped if the channel is
+// full.
+func (m *OrderManager) Fills() <-chan OrderFill {
+	return m.fills
+}
+
+// Order returns a copy of a tracked order
+func (m *OrderManager) Order(clientOrderID uint64) (ManagedOrder, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	order, ok := m.orders[clientOrderID]
+	if !ok {
+		return ManagedOrder{}, false
+	}
+	return *order, true
+}
+
+// Orders returns copies of all tracked orders
+func (m *OrderManager) Orders() []ManagedOrder {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	orders := make([]ManagedOrder, 0, len(m.orders))
+	for _, order := range m.orders {
+		orders = append(orders, *order)
+	}
+	return orders
+}
+
+// OpenOrders returns copies of all orders that are submitted, open or partially filled
+func (m *OrderManager) OpenOrders() []ManagedOrder {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var orders []ManagedOrder
+	for _, order := range m.orders {
+		if !order.State.Terminal() {
+			orders = append(orders, *order)
+		}
+	}
+	return orders
+}
+
+// PlaceOrder allocates a client order ID, then builds, signs and submits the order with PostOrder
+func (m *OrderManager) PlaceOrder(ctx context.Context, side pb.Side, types []common.OrderType, amount, price float64) (ManagedOrder, error) {
+	order := m.newOrder(side, amount, price)
+
+	response, err := m.api.post(ctx, m.opts.Owner, m.opts.Payer, m.opts.Market, side, types, amount, price, m.opts.Project, m.postOrderOpts(order.ClientOrderID))
+	return m.submitted(ctx, order.ClientOrderID, response, err)
+}
+
+// ReplaceOrder replaces a tracked order with PostReplaceOrder. The replacement gets a new client order ID, while the
+// replaced order is cancelled by the market. Orders that haven't been seen on the market yet have no order ID, so
+// they're cancelled by client order ID and the replacement is placed in a separate transaction instead.
+func (m *OrderManager) ReplaceOrder(ctx context.Context, clientOrderID uint64, types []common.OrderType, amount, price float64) (ManagedOrder, error) {
+	m.mutex.Lock()
+	existing, ok := m.orders[clientOrderID]
+	if !ok {
+		m.mutex.Unlock()
+		return ManagedOrder{}, fmt.Errorf("%w: %v", ErrOrderNotFound, clientOrderID)
+	}
+	orderID, side := existing.OrderID, existing.Side
+	m.mutex.Unlock()
+
+	if orderID == "" {
+		if _, err := m.CancelOrder(ctx, clientOrderID); err != nil {
+			return ManagedOrder{}, fmt.Errorf("could not cancel order %v without order ID before replacing it: %w", clientOrderID, err)
+		}
+		return m.PlaceOrder(ctx, side, types, amount, price)
+	}
+
+	order := m.newOrder(side, amount, price)
+
+	response, err := m.api.replace(ctx, orderID, m.opts.Owner, m.opts.Payer, m.opts.Market, side, types, amount, price, m.opts.Project, m.postOrderOpts(order.ClientOrderID))
+	replacement, err := m.submitted(ctx, order.ClientOrderID, response, err)
+	if err != nil {
+		return replacement, err
+	}
+
+	m.mutex.Lock()
+	existing.CancelRequested = true
+	existing.UpdatedAt = time.Now()
+	m.mutex.Unlock()
+	return replacement, nil
+}
+
+// CancelOrder cancels a tracked order with PostCancelByClientOrderID. The order is marked cancelled once the status
+// stream confirms it.
+func (m *OrderManager) CancelOrder(ctx context.Context, clientOrderID uint64) (string, error) {
+	m.mutex.Lock()
+	order, ok := m.orders[clientOrderID]
+	m.mutex.Unlock()
+	if !ok {
+		return "", fmt.Errorf("%w: %v", ErrOrderNotFound, clientOrderID)
+	}
+
+	response, err := m.api.cancel(ctx, clientOrderID, m.opts.Owner, m.opts.Market, m.openOrdersAddress(), m.opts.Project)
+	if err != nil {
+		return "", err
+	}
+	signature, err := m.api.submit(ctx, response.Transaction, m.skipPreFlight(), false, false)
+	if err != nil {
+		return "", err
+	}
+
+	m.mutex.Lock()
+	order.CancelRequested = true
+	order.UpdatedAt = time.Now()
+	m.mutex.Unlock()
+	return signature, nil
+}
+
+func (m *OrderManager) newOrder(side pb.Side, amount, price float64) *ManagedOrder {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	order := &ManagedOrder{
+		ClientOrderID:   m.nextClientID,
+		Market:          m.opts.Market,
+		Side:            side,
+		Price:           price,
+		Amount:          amount,
+		RemainingAmount: amount,
+		State:           OrderStateSubmitted,
+		UpdatedAt:       time.Now(),
+	}
+	m.nextClientID++
+	m.orders[order.ClientOrderID] = order
+	return order
+}
+
+// submitted signs and submits a built order transaction and records the outcome
+func (m *OrderManager) submitted(ctx context.Context, clientOrderID uint64, response *pb.PostOrderResponse, err error) (ManagedOrder, error) {
+	var signature string
+	if err == nil {
+		if response.OpenOrdersAddress != "" && m.opts.OpenOrdersAddress == "" {
+			m.mutex.Lock()
+			m.opts.OpenOrdersAddress = response.OpenOrdersAddress
+			m.mutex.Unlock()
+		}
+		signature, err = m.api.submit(ctx, response.Transaction, m.skipPreFlight(), false, false)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	order := m.orders[clientOrderID]
+	order.UpdatedAt = time.Now()
+	if err != nil {
+		order.State = OrderStateRejected
+		order.Err = err
+		return *order, err
+	}
+	order.Signature = signature
+	return *order, nil
+}
+
+func (m *OrderManager) postOrderOpts(clientOrderID uint64) PostOrderOpts {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return PostOrderOpts{
+		OpenOrdersAddress: m.opts.OpenOrdersAddress,
+		ClientOrderID:     clientOrderID,
+		SkipPreFlight:     m.opts.SkipPreFlight,
+	}
+}
+
+// openOrdersAddress returns the open orders account, which is learned from the first PostOrder response if not
+// configured
+func (m *OrderManager) openOrdersAddress() string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.opts.OpenOrdersAddress
+}
+
+func (m *OrderManager) skipPreFlight() bool {
+	if m.opts.SkipPreFlight == nil {
+		return true
+	}
+	return *m.opts.SkipPreFlight
+}
+
+func (m *OrderManager) subscribe(ctx context.Context) (chan *pb.GetOrderStatusStreamResponse, error) {
+	stream, err := m.api.statusStream(ctx, m.opts.Market, m.opts.Owner, m.opts.Project)
+	if err != nil {
+		return nil, fmt.Errorf("could not subscribe to order status stream: %w", err)
+	}
+	return stream.Channel(10), nil
+}
+
+// reconcile replaces the local view of resting orders with the orders currently on the book
+func (m *OrderManager) reconcile(ctx context.Context) error {
+	response, err := m.api.openOrders(ctx, m.opts.Market, m.opts.Owner, m.openOrdersAddress(), m.opts.Project)
+	if err != nil {
+		return fmt.Errorf("could not reconcile open orders: %w", err)
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := time.Now()
+	onBook := make(map[uint64]bool, len(response.Orders))
+	for _, openOrder := range response.Orders {
+		clientOrderID, err := strconv.ParseUint(openOrder.ClientOrderID, 10, 64)
+		if err != nil || clientOrderID == 0 {
+			log.Debugf("skipping open order %v without client order ID", openOrder.OrderID)
+			continue
+		}
+		onBook[clientOrderID] = true
+		if clientOrderID >= m.nextClientID {
+			m.nextClientID = clientOrderID + 1
+		}
+
+		order, ok := m.orders[clientOrderID]
+		if !ok {
+			order = &ManagedOrder{
+				ClientOrderID: clientOrderID,
+				Market:        openOrder.Market,
+				Side:          openOrder.Side,
+				Price:         openOrder.Price,
+				Amount:        openOrder.RemainingSize,
+			}
+			m.orders[clientOrderID] = order
+		}
+		order.OrderID = openOrder.OrderID
+		order.RemainingAmount = openOrder.RemainingSize
+		if order.FilledAmount > 0 || order.RemainingAmount < order.Amount {
+			order.State = OrderStatePartiallyFilled
+		} else {
+			order.State = OrderStateOpen
+		}
+		order.UpdatedAt = now
+		m.byOrderID[openOrder.OrderID] = clientOrderID
+	}
+
+	for clientOrderID, order := range m.orders {
+		if (order.State == OrderStateOpen || order.State == OrderStatePartiallyFilled) && !onBook[clientOrderID] {
+			order.State = OrderStateClosed
+			order.UpdatedAt = now
+		}
+	}
+	return nil
+}
+
+func (m *OrderManager) run(ctx context.Context, updates chan *pb.GetOrderStatusStreamResponse) {
+	ticker := time.NewTicker(m.opts.RejectAfter / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				log.Warn("order status stream closed, resubscribing")
+				updates = m.resubscribe(ctx)
+				if updates == nil {
+					return
+				}
+				continue
+			}
+			m.apply(update)
+		case <-ticker.C:
+			m.rejectUnseen(time.Now())
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// resubscribe reopens the status stream and reconciles the orders missed in between, retrying until ctx is canceled
+func (m *OrderManager) resubscribe(ctx context.Context) chan *pb.GetOrderStatusStreamResponse {
+	for {
+		select {
+		case <-time.After(m.resubscribeDelay):
+		case <-ctx.Done():
+			return nil
+		}
+
+		updates, err := m.subscribe(ctx)
+		if err != nil {
+			log.Errorf("can't resubscribe to order status stream: %v", err)
+			continue
+		}
+		if err := m.reconcile(ctx); err != nil {
+			log.Errorf("can't reconcile orders after resubscribing: %v", err)
+		}
+		return updates
+	}
+}
+
+func (m *OrderManager) apply(update *pb.GetOrderStatusStreamResponse) {
+	info := update.OrderInfo
+	if info == nil {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	clientOrderID := info.ClientOrderID
+	if clientOrderID == 0 {
+		clientOrderID = m.byOrderID[info.OrderID]
+	}
+	order, ok := m.orders[clientOrderID]
+	if !ok {
+		if clientOrderID == 0 {
+			log.Debugf("skipping status update for order %v without client order ID", info.OrderID)
+			return
+		}
+		order = &ManagedOrder{
+			ClientOrderID:   clientOrderID,
+			Market:          info.Market,
+			Side:            info.Side,
+			Price:           float64(info.OrderPrice),
+			Amount:          float64(info.QuantityRemaining),
+			RemainingAmount: float64(info.QuantityRemaining),
+		}
+		m.orders[clientOrderID] = order
+	}
+
+	now := time.Now()
+	if info.OrderID != "" {
+		order.OrderID = info.OrderID
+		m.byOrderID[info.OrderID] = clientOrderID
+	}
+	if order.State.Terminal() && order.State != OrderStateClosed {
+		return
+	}
+
+	remaining := float64(info.QuantityRemaining)
+	if info.OrderStatus == pb.OrderStatus_OS_FILLED {
+		remaining = 0
+	}
+	if filled := order.RemainingAmount - remaining; filled > 0 && info.OrderStatus != pb.OrderStatus_OS_CANCELLED {
+		order.FilledAmount += filled
+		order.RemainingAmount = remaining
+		m.publishFill(order, info, filled, update)
+	}
+
+	switch info.OrderStatus {
+	case pb.OrderStatus_OS_OPEN:
+		order.State = OrderStateOpen
+		if order.FilledAmount > 0 {
+			order.State = OrderStatePartiallyFilled
+		}
+	case pb.OrderStatus_OS_PARTIAL_FILL:
+		order.State = OrderStatePartiallyFilled
+	case pb.OrderStatus_OS_FILLED:
+		order.State = OrderStateFilled
+	case pb.OrderStatus_OS_CANCELLED:
+		order.State = OrderStateCancelled
+	}
+	order.UpdatedAt = now
+}
+
+func (m *OrderManager) publishFill(order *ManagedOrder, info *pb.GetOrderStatusResponse, amount float64, update *pb.GetOrderStatusStreamResponse) {
+	price := float64(info.FillPrice)
+	if price == 0 {
+		price = order.Price
+	}
+	fill := OrderFill{
+		ClientOrderID:   order.ClientOrderID,
+		OrderID:         order.OrderID,
+		Market:          order.Market,
+		Side:            order.Side,
+		Price:           price,
+		Amount:          amount,
+		RemainingAmount: order.RemainingAmount,
+		Slot:            update.Slot,
+		Time:            time.Now(),
+	}
+	if update.Timestamp != nil {
+		fill.Time = update.Timestamp.AsTime()
+	}
+
+	select {
+	case m.fills <- fill:
+	default:
+		log.Warnf("order fills channel full, dropping fill of %v at %v for client order ID %v", fill.Amount, fill.Price, fill.ClientOrderID)
+	}
+}
+
+// rejectUnseen marks submitted orders that haven't appeared on the market within RejectAfter as rejected
+func (m *OrderManager) rejectUnseen(now time.Time) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for _, order := range m.orders {
+		if order.State == OrderStateSubmitted && order.Signature != "" && now.Sub(order.UpdatedAt) > m.opts.RejectAfter {
+			order.State = OrderStateRejected
+			order.Err = ErrOrderNotSeen
+			order.UpdatedAt = now
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/bloXroute-Labs/solana-trader-proto/common"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOrderAPI records the requests of an OrderManager and sends status updates on its stream
+type fakeOrderAPI struct {
+	mutex     sync.Mutex
+	posted    []uint64
+	replaced  []string
+	cancelled []uint64
+	updates   chan *pb.GetOrderStatusStreamResponse
+}
+
+func (f *fakeOrderAPI) api() orderManagerAPI {
+	return orderManagerAPI{
+		post: func(_ context.Context, _, _, _ string, _ pb.Side, _ []common.OrderType, _, _ float64, _ pb.Project, opts PostOrderOpts) (*pb.PostOrderResponse, error) {
+			f.mutex.Lock()
+			defer f.mutex.Unlock()
+			f.posted = append(f.posted, opts.ClientOrderID)
+			return &pb.PostOrderResponse{Transaction: &pb.TransactionMessage{Content: fmt.Sprint(opts.ClientOrderID)}, OpenOrdersAddress: "open orders"}, nil
+		},
+		replace: func(_ context.Context, orderID, _, _, _ string, _ pb.Side, _ []common.OrderType, _, _ float64, _ pb.Project, opts PostOrderOpts) (*pb.PostOrderResponse, error) {
+			f.mutex.Lock()
+			defer f.mutex.Unlock()
+			f.replaced = append(f.replaced, orderID)
+			return &pb.PostOrderResponse{Transaction: &pb.TransactionMessage{Content: fmt.Sprint(opts.ClientOrderID)}}, nil
+		},
+		cancel: func(_ context.Context, clientOrderID uint64, _, _, openOrders string, _ pb.Project) (*pb.PostCancelOrderResponse, error) {
+			f.mutex.Lock()
+			defer f.mutex.Unlock()
+			if openOrders != "open orders" {
+				return nil, errors.New("open orders account not learned")
+			}
+			f.cancelled = append(f.cancelled, clientOrderID)
+			return &pb.PostCancelOrderResponse{Transaction: &pb.TransactionMessage{Content: "cancel"}}, nil
+		},
+		openOrders: func(_ context.Context, _, _, _ string, _ pb.Project) (*pb.GetOpenOrdersResponse, error) {
+			return &pb.GetOpenOrdersResponse{}, nil
+		},
+		statusStream: func(_ context.Context, _, _ string, _ pb.Project) (connections.Streamer[*pb.GetOrderStatusStreamResponse], error) {
+			return func() (*pb.GetOrderStatusStreamResponse, error) {
+				update, ok := <-f.updates
+				if !ok {
+					return nil, errors.New("closed")
+				}
+				return update, nil
+			}, nil
+		},
+		submit: func(_ context.Context, tx *pb.TransactionMessage, _ bool, _ bool, _ bool) (string, error) {
+			return "signature " + tx.Content, nil
+		},
+	}
+}
+
+func TestOrderManager(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	f := &fakeOrderAPI{updates: make(chan *pb.GetOrderStatusStreamResponse)}
+	m, err := startOrderManager(ctx, f.api(), OrderManagerOpts{Market: "SOL/USDC", Owner: "owner", FirstClientOrderID: 1})
+	require.NoError(t, err)
+
+	status := func(clientOrderID uint64, orderID string, status pb.OrderStatus, remaining float32) {
+		f.updates <- &pb.GetOrderStatusStreamResponse{Slot: 1, OrderInfo: &pb.GetOrderStatusResponse{
+			Market:            "SOL/USDC",
+			OrderID:           orderID,
+			ClientOrderID:     clientOrderID,
+			QuantityRemaining: remaining,
+			FillPrice:         100,
+			Side:              pb.Side_S_BID,
+			OrderStatus:       status,
+		}}
+	}
+	state := func(clientOrderID uint64) OrderState {
+		order, ok := m.Order(clientOrderID)
+		require.True(t, ok)
+		return order.State
+	}
+	eventuallyState := func(clientOrderID uint64, expected OrderState) {
+		require.Eventually(t, func() bool { return state(clientOrderID) == expected }, time.Second, time.Millisecond)
+	}
+
+	// placing
+	order, err := m.PlaceOrder(ctx, pb.Side_S_BID, []common.OrderType{common.OrderType_OT_LIMIT}, 2, 100)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), order.ClientOrderID)
+	require.Equal(t, OrderStateSubmitted, order.State)
+	require.Equal(t, "signature 1", order.Signature)
+
+	status(1, "order 1", pb.OrderStatus_OS_OPEN, 2)
+	eventuallyState(1, OrderStateOpen)
+
+	status(1, "", pb.OrderStatus_OS_PARTIAL_FILL, 0.5)
+	eventuallyState(1, OrderStatePartiallyFilled)
+	fill := <-m.Fills()
+	require.Equal(t, OrderFill{
+		ClientOrderID:   1,
+		OrderID:         "order 1",
+		Market:          "SOL/USDC",
+		Side:            pb.Side_S_BID,
+		Price:           100,
+		Amount:          1.5,
+		RemainingAmount: 0.5,
+		Slot:            1,
+		Time:            fill.Time,
+	}, fill)
+
+	// replacing an order on the book
+	replacement, err := m.ReplaceOrder(ctx, 1, []common.OrderType{common.OrderType_OT_LIMIT}, 1, 101)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), replacement.ClientOrderID)
+	require.Equal(t, []string{"order 1"}, f.replaced)
+	replaced, _ := m.Order(1)
+	require.True(t, replaced.CancelRequested)
+
+	status(1, "order 1", pb.OrderStatus_OS_CANCELLED, 0)
+	eventuallyState(1, OrderStateCancelled)
+	require.Empty(t, m.Fills())
+
+	// replacing an order that hasn't been seen on the market cancels it by client order ID and places a new one
+	replacement, err = m.ReplaceOrder(ctx, 2, []common.OrderType{common.OrderType_OT_LIMIT}, 1, 102)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), replacement.ClientOrderID)
+	require.Equal(t, []string{"order 1"}, f.replaced)
+	require.Equal(t, []uint64{2}, f.cancelled)
+	require.Equal(t, []uint64{1, 3}, f.posted)
+
+	// cancelling
+	_, err = m.CancelOrder(ctx, 3)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{2, 3}, f.cancelled)
+	cancelled, _ := m.Order(3)
+	require.True(t, cancelled.CancelRequested)
+	require.Equal(t, OrderStateSubmitted, cancelled.State)
+
+	status(3, "order 3", pb.OrderStatus_OS_FILLED, 0)
+	eventuallyState(3, OrderStateFilled)
+	fill = <-m.Fills()
+	require.Equal(t, uint64(3), fill.ClientOrderID)
+	require.Equal(t, 1.0, fill.Amount)
+
+	// updates after a terminal state are ignored
+	status(3, "order 3", pb.OrderStatus_OS_CANCELLED, 0)
+	status(2, "", pb.OrderStatus_OS_CANCELLED, 1)
+	eventuallyState(2, OrderStateCancelled)
+	require.Equal(t, OrderStateFilled, state(3))
+
+	_, err = m.CancelOrder(ctx, 42)
+	require.ErrorIs(t, err, ErrOrderNotFound)
+	_, err = m.ReplaceOrder(ctx, 42, nil, 1, 1)
+	require.ErrorIs(t, err, ErrOrderNotFound)
+
+	// submitted orders that never reach the market are rejected
+	unseen, err := m.PlaceOrder(ctx, pb.Side_S_ASK, []common.OrderType{common.OrderType_OT_LIMIT}, 1, 110)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(m.OpenOrders()))
+	m.rejectUnseen(time.Now().Add(m.opts.RejectAfter + time.Second))
+	rejected, _ := m.Order(unseen.ClientOrderID)
+	require.Equal(t, OrderStateRejected, rejected.State)
+	require.ErrorIs(t, rejected.Err, ErrOrderNotSeen)
+	require.Equal(t, OrderStateCancelled, state(1))
+	require.Empty(t, m.OpenOrders())
+}
@@ -0,0 +1,353 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultOrderbookChangeBuffer     = 1000
+	defaultOrderbookResubscribeDelay = time.Second
+)
+
+var ErrInsufficientLiquidity = errors.New("not enough liquidity on the book")
+
+type OrderbookChangeKind int
+
+const (
+	LevelAdded OrderbookChangeKind = iota
+	LevelRemoved
+	LevelResized
+)
+
+func (k OrderbookChangeKind) String() string {
+	switch k {
+	case LevelAdded:
+		return "added"
+	case LevelRemoved:
+		return "removed"
+	case LevelResized:
+		return "resized"
+	default:
+		return fmt.Sprintf("change(%d)", int(k))
+	}
+}
+
+// OrderbookChange is a price level that changed between two snapshots of a market's book
+type OrderbookChange struct {
+	Market  string
+	Slot    int64
+	Side    pb.Side
+	Kind    OrderbookChangeKind
+	Price   float64
+	OldSize float64
+	NewSize float64
+}
+
+// OrderbookLevel is the total size of the orders resting at a price
+type OrderbookLevel struct {
+	Price  float64
+	Size   float64
+	Orders int
+}
+
+// Orderbook is a snapshot of a market's book aggregated by price. Bids are sorted best (highest) first, asks best
+// (lowest) first.
+type Orderbook struct {
+	Market        string
+	MarketAddress string
+	Slot          int64
+	Bids          []OrderbookLevel
+	Asks          []OrderbookLevel
+}
+
+func (o Orderbook) levels(side pb.Side) []OrderbookLevel {
+	if side == pb.Side_S_BID {
+		return o.Bids
+	}
+	return o.Asks
+}
+
+// BestBid returns the highest bid
+func (o Orderbook) BestBid() (OrderbookLevel, bool) {
+	if len(o.Bids) == 0 {
+		return OrderbookLevel{}, false
+	}
+	return o.Bids[0], true
+}
+
+// BestAsk returns the lowest ask
+func (o Orderbook) BestAsk() (OrderbookLevel, bool) {
+	if len(o.Asks) == 0 {
+		return OrderbookLevel{}, false
+	}
+	return o.Asks[0], true
+}
+
+// Mid returns the midpoint of the best bid and ask
+func (o Orderbook) Mid() (float64, bool) {
+	bid, okBid := o.BestBid()
+	ask, okAsk := o.BestAsk()
+	if !okBid || !okAsk {
+		return 0, false
+	}
+	return (bid.Price + ask.Price) / 2, true
+}
+
+// Spread returns the difference between the best ask and bid
+func (o Orderbook) Spread() (float64, bool) {
+	bid, okBid := o.BestBid()
+	ask, okAsk := o.BestAsk()
+	if !okBid || !okAsk {
+		return 0, false
+	}
+	return ask.Price - bid.Price, true
+}
+
+// SpreadBps returns the spread in basis points of the mid
+func (o Orderbook) SpreadBps() (float64, bool) {
+	spread, ok := o.Spread()
+	if !ok {
+		return 0, false
+	}
+	mid, _ := o.Mid()
+	return spread / mid * 10_000, true
+}
+
+// DepthWithin returns the size resting on a side of the book within bps of the mid
+func (o Orderbook) DepthWithin(side pb.Side, bps float64) (float64, bool) {
+	mid, ok := o.Mid()
+	if !ok {
+		return 0, false
+	}
+
+	var depth float64
+	for _, level := range o.levels(side) {
+		if math.Abs(level.Price-mid)/mid*10_000 > bps {
+			break
+		}
+		depth += level.Size
+	}
+	return depth, true
+}
+
+// CostToFill returns the quote amount and average price of taking size from a side of the book, e.g. the asks for a
+// buy. Size must be positive. It returns ErrInsufficientLiquidity with the cost of the available size if the side is
+// too thin.
+func (o Orderbook) CostToFill(side pb.Side, size float64) (float64, float64, error) {
+	if size <= 0 {
+		return 0, 0, fmt.Errorf("size must be positive, got %v", size)
+	}
+
+	var cost, filled float64
+	for _, level := range o.levels(side) {
+		take := math.Min(level.Size, size-filled)
+		cost += take * level.Price
+		filled += take
+		if filled >= size {
+			return cost, cost / filled, nil
+		}
+	}
+
+	if filled == 0 {
+		return 0, 0, ErrInsufficientLiquidity
+	}
+	return cost, cost / filled, fmt.Errorf("%w: %v of %v available", ErrInsufficientLiquidity, filled, size)
+}
+
+type OrderbookCacheOpts struct {
+	Markets []string
+	Limit   uint32
+	Project pb.Project
+
+	// ChangeBuffer is the size of the channel returned by Changes. Defaults to 1000.
+	ChangeBuffer int
+}
+
+type orderbookStreamProvider func(ctx context.Context, markets []string, limit uint32, project pb.Project) (connections.Streamer[*pb.GetOrderbooksStreamResponse], error)
+
+// OrderbookCache keeps the books of markets current from GetOrderbooksStream snapshots. Snapshots older than the
+// cached book are dropped, and every level that differs from the previous snapshot is published as a change.
+type OrderbookCache struct {
+	mutex            sync.RWMutex
+	streamProvider   orderbookStreamProvider
+	opts             OrderbookCacheOpts
+	books            map[string]*Orderbook
+	changes          chan OrderbookChange
+	resubscribeDelay time.Duration
+}
+
+func newOrderbookCache(streamProvider orderbookStreamProvider, opts OrderbookCacheOpts) *OrderbookCache {
+	if opts.ChangeBuffer == 0 {
+		opts.ChangeBuffer = defaultOrderbookChangeBuffer
+	}
+	return &OrderbookCache{
+		streamProvider:   streamProvider,
+		opts:             opts,
+		books:            make(map[string]*Orderbook),
+		changes:          make(chan OrderbookChange, opts.ChangeBuffer),
+		resubscribeDelay: defaultOrderbookResubscribeDelay,
+	}
+}
+
+// NewOrderbookCache subscribes to the books of the markets and keeps them current until ctx is canceled
+func (w *WSClient) NewOrderbookCache(ctx context.Context, opts OrderbookCacheOpts) (*OrderbookCache, error) {
+	return startOrderbookCache(ctx, newOrderbookCache(w.GetOrderbooksStream, opts))
+}
+
+// NewOrderbookCache subscribes to the books of the markets and keeps them current until ctx is canceled
+func (g *GRPCClient) NewOrderbookCache(ctx context.Context, opts OrderbookCacheOpts) (*OrderbookCache, error) {
+	return startOrderbookCache(ctx, newOrderbookCache(g.GetOrderbookStream, opts))
+}
+
+func startOrderbookCache(ctx context.Context, c *OrderbookCache) (*OrderbookCache, error) {
+	updates, err := c.subscribe(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	go c.run(ctx, updates)
+	return c, nil
+}
+
+// Orderbook returns the current book of a market
+func (c *OrderbookCache) Orderbook(market string) (Orderbook, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	book, ok := c.books[market]
+	if !ok {
+		return Orderbook{}, false
+	}
+	return *book, true
+}
+
+// Changes returns a channel on which level changes are published. Changes are dropped if the channel is full.
+func (c *OrderbookCache) Changes() <-chan OrderbookChange {
+	return c.changes
+}
+
+func (c *OrderbookCache) subscribe(ctx context.Context) (chan *pb.GetOrderbooksStreamResponse, error) {
+	stream, err := c.streamProvider(ctx, c.opts.Markets, c.opts.Limit, c.opts.Project)
+	if err != nil {
+		return nil, err
+	}
+	return stream.Channel(100), nil
+}
+
+func (c *OrderbookCache) run(ctx context.Context, updates chan *pb.GetOrderbooksStreamResponse) {
+	for {
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				// the first snapshots of the new stream replace the cached books
+				log.Warn("orderbook stream closed, resubscribing")
+				updates = resubscribe(ctx, c.resubscribeDelay, "orderbook", c.subscribe)
+				if updates == nil {
+					return
+				}
+				continue
+			}
+			c.apply(update)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *OrderbookCache) apply(update *pb.GetOrderbooksStreamResponse) {
+	if update.Orderbook == nil {
+		return
+	}
+
+	book := &Orderbook{
+		Market:        update.Orderbook.Market,
+		MarketAddress: update.Orderbook.MarketAddress,
+		Slot:          update.Slot,
+		Bids:          aggregateLevels(update.Orderbook.Bids, pb.Side_S_BID),
+		Asks:          aggregateLevels(update.Orderbook.Asks, pb.Side_S_ASK),
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	previous, ok := c.books[book.Market]
+	if ok && book.Slot < previous.Slot {
+		log.Debugf("dropping orderbook of %v at slot %v older than cached slot %v", book.Market, book.Slot, previous.Slot)
+		return
+	}
+	if !ok {
+		previous = &Orderbook{}
+	}
+	c.books[book.Market] = book
+
+	c.publishChanges(book, pb.Side_S_BID, previous.Bids, book.Bids)
+	c.publishChanges(book, pb.Side_S_ASK, previous.Asks, book.Asks)
+}
+
+func (c *OrderbookCache) publishChanges(book *Orderbook, side pb.Side, previous, current []OrderbookLevel) {
+	sizes := make(map[float64]float64, len(previous))
+	for _, level := range previous {
+		sizes[level.Price] = level.Size
+	}
+
+	for _, level := range current {
+		oldSize, ok := sizes[level.Price]
+		delete(sizes, level.Price)
+		switch {
+		case !ok:
+			c.publish(OrderbookChange{Market: book.Market, Slot: book.Slot, Side: side, Kind: LevelAdded, Price: level.Price, NewSize: level.Size})
+		case oldSize != level.Size:
+			c.publish(OrderbookChange{Market: book.Market, Slot: book.Slot, Side: side, Kind: LevelResized, Price: level.Price, OldSize: oldSize, NewSize: level.Size})
+		}
+	}
+
+	removed := make([]float64, 0, len(sizes))
+	for price := range sizes {
+		removed = append(removed, price)
+	}
+	sort.Float64s(removed)
+	for _, price := range removed {
+		c.publish(OrderbookChange{Market: book.Market, Slot: book.Slot, Side: side, Kind: LevelRemoved, Price: price, OldSize: sizes[price]})
+	}
+}
+
+func (c *OrderbookCache) publish(change OrderbookChange) {
+	select {
+	case c.changes <- change:
+	default:
+		log.Warnf("orderbook changes channel full, dropping %v change of %v at %v", change.Kind, change.Market, change.Price)
+	}
+}
+
+// aggregateLevels sums the orders of a side by price, best price first
+func aggregateLevels(items []*pb.OrderbookItem, side pb.Side) []OrderbookLevel {
+	byPrice := make(map[float64]int, len(items))
+	levels := make([]OrderbookLevel, 0, len(items))
+	for _, item := range items {
+		if i, ok := byPrice[item.Price]; ok {
+			levels[i].Size += item.Size
+			levels[i].Orders++
+			continue
+		}
+		byPrice[item.Price] = len(levels)
+		levels = append(levels, OrderbookLevel{Price: item.Price, Size: item.Size, Orders: 1})
+	}
+
+	sort.Slice(levels, func(i, j int) bool {
+		if side == pb.Side_S_BID {
+			return levels[i].Price > levels[j].Price
+		}
+		return levels[i].Price < levels[j].Price
+	})
+	return levels
+}
@@ -0,0 +1,113 @@
+package provider
+
+import (
+	"testing"
+
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregateLevels(t *testing.T) {
+	items := []*pb.OrderbookItem{
+		{Price: 10, Size: 1},
+		{Price: 12, Size: 2},
+		{Price: 10, Size: 3},
+		{Price: 11, Size: 4},
+	}
+
+	require.Equal(t, []OrderbookLevel{
+		{Price: 12, Size: 2, Orders: 1},
+		{Price: 11, Size: 4, Orders: 1},
+		{Price: 10, Size: 4, Orders: 2},
+	}, aggregateLevels(items, pb.Side_S_BID))
+	require.Equal(t, []OrderbookLevel{
+		{Price: 10, Size: 4, Orders: 2},
+		{Price: 11, Size: 4, Orders: 1},
+		{Price: 12, Size: 2, Orders: 1},
+	}, aggregateLevels(items, pb.Side_S_ASK))
+	require.Empty(t, aggregateLevels(nil, pb.Side_S_ASK))
+}
+
+func TestOrderbookCacheApply(t *testing.T) {
+	c := newOrderbookCache(nil, OrderbookCacheOpts{Markets: []string{"SOL/USDC"}})
+	snapshot := func(slot int64, bids, asks []*pb.OrderbookItem) *pb.GetOrderbooksStreamResponse {
+		return &pb.GetOrderbooksStreamResponse{Slot: slot, Orderbook: &pb.GetOrderbookResponse{
+			Market: "SOL/USDC",
+			Bids:   bids,
+			Asks:   asks,
+		}}
+	}
+	changes := func() []OrderbookChange {
+		var changes []OrderbookChange
+		for {
+			select {
+			case change := <-c.Changes():
+				changes = append(changes, change)
+			default:
+				return changes
+			}
+		}
+	}
+
+	c.apply(snapshot(10,
+		[]*pb.OrderbookItem{{Price: 99, Size: 1}, {Price: 98, Size: 2}},
+		[]*pb.OrderbookItem{{Price: 101, Size: 3}},
+	))
+	require.Equal(t, []OrderbookChange{
+		{Market: "SOL/USDC", Slot: 10, Side: pb.Side_S_BID, Kind: LevelAdded, Price: 99, NewSize: 1},
+		{Market: "SOL/USDC", Slot: 10, Side: pb.Side_S_BID, Kind: LevelAdded, Price: 98, NewSize: 2},
+		{Market: "SOL/USDC", Slot: 10, Side: pb.Side_S_ASK, Kind: LevelAdded, Price: 101, NewSize: 3},
+	}, changes())
+
+	c.apply(snapshot(11,
+		[]*pb.OrderbookItem{{Price: 99, Size: 1}, {Price: 98, Size: 5}},
+		[]*pb.OrderbookItem{{Price: 102, Size: 3}},
+	))
+	require.Equal(t, []OrderbookChange{
+		{Market: "SOL/USDC", Slot: 11, Side: pb.Side_S_BID, Kind: LevelResized, Price: 98, OldSize: 2, NewSize: 5},
+		{Market: "SOL/USDC", Slot: 11, Side: pb.Side_S_ASK, Kind: LevelAdded, Price: 102, NewSize: 3},
+		{Market: "SOL/USDC", Slot: 11, Side: pb.Side_S_ASK, Kind: LevelRemoved, Price: 101, OldSize: 3},
+	}, changes())
+
+	// a snapshot older than the cached book is dropped without changes
+	c.apply(snapshot(9, nil, nil))
+	require.Empty(t, changes())
+	book, ok := c.Orderbook("SOL/USDC")
+	require.True(t, ok)
+	require.Equal(t, int64(11), book.Slot)
+	require.Equal(t, []OrderbookLevel{{Price: 99, Size: 1, Orders: 1}, {Price: 98, Size: 5, Orders: 1}}, book.Bids)
+
+	_, ok = c.Orderbook("ETH/USDC")
+	require.False(t, ok)
+}
+
+func TestOrderbookCostToFill(t *testing.T) {
+	book := Orderbook{
+		Bids: []OrderbookLevel{{Price: 99, Size: 1}, {Price: 98, Size: 2}},
+		Asks: []OrderbookLevel{{Price: 101, Size: 1}, {Price: 103, Size: 1}},
+	}
+
+	cost, price, err := book.CostToFill(pb.Side_S_ASK, 1.5)
+	require.NoError(t, err)
+	require.Equal(t, 152.5, cost)
+	require.InDelta(t, 101.6666, price, 1e-4)
+
+	cost, price, err = book.CostToFill(pb.Side_S_BID, 3)
+	require.NoError(t, err)
+	require.Equal(t, 295.0, cost)
+	require.InDelta(t, 98.3333, price, 1e-4)
+
+	cost, price, err = book.CostToFill(pb.Side_S_ASK, 3)
+	require.ErrorIs(t, err, ErrInsufficientLiquidity)
+	require.Equal(t, 204.0, cost)
+	require.Equal(t, 102.0, price)
+
+	_, _, err = Orderbook{}.CostToFill(pb.Side_S_ASK, 1)
+	require.ErrorIs(t, err, ErrInsufficientLiquidity)
+
+	for _, size := range []float64{0, -1} {
+		_, _, err = book.CostToFill(pb.Side_S_ASK, size)
+		require.Error(t, err)
+		require.NotErrorIs(t, err, ErrInsufficientLiquidity)
+	}
+}
@@ -0,0 +1,131 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultPriorityFeeTtl = 10 * time.Second
+
+type priorityFeeProvider func(ctx context.Context, project pb.Project, percentile *float64) (*pb.GetPriorityFeeResponse, error)
+type priorityFeeStreamProvider func(ctx context.Context, project pb.Project, percentile *float64) (connections.Streamer[*pb.GetPriorityFeeResponse], error)
+
+type priorityFeeKey struct {
+	project    pb.Project
+	percentile float64
+}
+
+type priorityFee struct {
+	fee     uint64
+	feeTime time.Time
+}
+
+// priorityFeeStore caches priority fees per project and percentile. If streaming is enabled, the first request for
+// a key subscribes to GetPriorityFeeStream so later requests are served from the stream, until the store is closed.
+type priorityFeeStore struct {
+	mutex          sync.RWMutex
+	feeProvider    priorityFeeProvider
+	streamProvider priorityFeeStreamProvider
+	fees           map[priorityFeeKey]priorityFee
+	streams        map[priorityFeeKey]bool
+	feeExpiry      time.Duration
+
+	// ctx ends all streams once canceled by close
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newPriorityFeeStore(feeProvider priorityFeeProvider, streamProvider priorityFeeStreamProvider, opts RPCOpts) *priorityFeeStore {
+	if !opts.CachePriorityFee {
+		streamProvider = nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &priorityFeeStore{
+		feeProvider:    feeProvider,
+		streamProvider: streamProvider,
+		fees:           make(map[priorityFeeKey]priorityFee),
+		streams:        make(map[priorityFeeKey]bool),
+		feeExpiry:      defaultPriorityFeeTtl,
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+}
+
+// close stops all fee streams. Later requests are served by feeProvider.
+func (s *priorityFeeStore) close() {
+	s.cancel()
+}
+
+func (s *priorityFeeStore) run(ctx context.Context, key priorityFeeKey) {
+	percentile := key.percentile
+	stream, err := s.streamProvider(ctx, key.project, &percentile)
+	if err != nil {
+		log.Errorf("can't open priority fee stream: %v", err)
+		s.mutex.Lock()
+		delete(s.streams, key)
+		s.mutex.Unlock()
+		return
+	}
+	ch := stream.Channel(1)
+	for {
+		select {
+		case fee, ok := <-ch:
+			if !ok {
+				s.mutex.Lock()
+				delete(s.streams, key)
+				s.mutex.Unlock()
+				return
+			}
+			s.update(key, fee.FeeAtPercentile)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *priorityFeeStore) update(key priorityFeeKey, fee uint64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.fees[key] = priorityFee{fee: fee, feeTime: time.Now()}
+}
+
+func (s *priorityFeeStore) get(ctx context.Context, project pb.Project, percentile float64) (uint64, error) {
+	key := priorityFeeKey{project: project, percentile: percentile}
+	if fee, ok := s.cached(key); ok {
+		return fee, nil
+	}
+
+	s.subscribe(key)
+
+	response, err := s.feeProvider(ctx, project, &percentile)
+	if err != nil {
+		return 0, err
+	}
+	s.update(key, response.FeeAtPercentile)
+	return response.FeeAtPercentile, nil
+}
+
+func (s *priorityFeeStore) subscribe(key priorityFeeKey) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.streamProvider == nil || s.ctx.Err() != nil || s.streams[key] {
+		return
+	}
+	s.streams[key] = true
+	go s.run(s.ctx, key)
+}
+
+func (s *priorityFeeStore) cached(key priorityFeeKey) (uint64, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	fee, ok := s.fees[key]
+	if !ok || time.Since(fee.feeTime) > s.feeExpiry {
+		return 0, false
+	}
+	return fee.fee, true
+}
@@ -0,0 +1,67 @@
+package provider
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedPriorityFees returns a store that always reports fee
+func fixedPriorityFees(fee uint64) *priorityFeeStore {
+	return newPriorityFeeStore(func(_ context.Context, _ pb.Project, _ *float64) (*pb.GetPriorityFeeResponse, error) {
+		return &pb.GetPriorityFeeResponse{FeeAtPercentile: fee}, nil
+	}, nil, RPCOpts{})
+}
+
+func TestPriorityFeeStoreClose(t *testing.T) {
+	var mutex sync.Mutex
+	subscriptions := 0
+	closed := make(chan struct{})
+	s := newPriorityFeeStore(func(_ context.Context, _ pb.Project, _ *float64) (*pb.GetPriorityFeeResponse, error) {
+		return &pb.GetPriorityFeeResponse{FeeAtPercentile: 1}, nil
+	}, func(ctx context.Context, _ pb.Project, _ *float64) (connections.Streamer[*pb.GetPriorityFeeResponse], error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		subscriptions++
+
+		sent := false
+		return func() (*pb.GetPriorityFeeResponse, error) {
+			if !sent {
+				sent = true
+				return &pb.GetPriorityFeeResponse{FeeAtPercentile: 2}, nil
+			}
+			<-ctx.Done()
+			close(closed)
+			return nil, ctx.Err()
+		}, nil
+	}, RPCOpts{CachePriorityFee: true})
+
+	ctx := context.Background()
+	fee, err := s.get(ctx, pb.Project_P_RAYDIUM, 50)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), fee)
+	require.Eventually(t, func() bool {
+		fee, err := s.get(ctx, pb.Project_P_RAYDIUM, 50)
+		return err == nil && fee == 2
+	}, time.Second, time.Millisecond)
+
+	s.close()
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("stream was not closed")
+	}
+
+	// once closed, fees are requested directly instead of reopening the stream
+	fee, err = s.get(ctx, pb.Project_P_JUPITER, 50)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), fee)
+	mutex.Lock()
+	defer mutex.Unlock()
+	require.Equal(t, 1, subscriptions)
+}
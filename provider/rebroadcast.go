@@ -0,0 +1,175 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+	solanarpc "github.com/gagliardetto/solana-go/rpc"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultRebroadcastInterval = 2 * time.Second
+
+// RebroadcastResult reports the outcome of a rebroadcast submission
+type RebroadcastResult struct {
+	Signature    string
+	Attempts     int
+	Confirmation ConfirmationResult
+}
+
+type batchSubmitter func(ctx context.Context, request *pb.PostSubmitBatchRequest) (*pb.PostSubmitBatchResponse, error)
+
+// SignAndSubmitWithRebroadcast signs the given transaction once and re-sends the identical signed transaction every
+// opts.RebroadcastInterval until it is confirmed or its blockhash expires.
+func (w *WSClient) SignAndSubmitWithRebroadcast(ctx context.Context, tx *pb.TransactionMessage, opts SubmitOpts) (*RebroadcastResult, error) {
+	if w.keyring.Len() == 0 {
+		return nil, ErrPrivateKeyNotFound
+	}
+
+	signedTx, err := w.txSigner().sign(ctx, tx.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	trackerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	tracker := w.NewConfirmationTracker(trackerCtx, rebroadcastTrackerOpts(opts))
+
+	return rebroadcast(ctx, w.PostSubmitBatch, w.dryRun, tracker, signedTx, opts)
+}
+
+// SignAndSubmitWithRebroadcast signs the given transaction once and re-sends the identical signed transaction every
+// opts.RebroadcastInterval until it is confirmed or its blockhash expires.
+func (g *GRPCClient) SignAndSubmitWithRebroadcast(ctx context.Context, tx *pb.TransactionMessage, opts SubmitOpts) (*RebroadcastResult, error) {
+	if g.keyring.Len() == 0 {
+		return nil, ErrPrivateKeyNotFound
+	}
+
+	signedTx, err := g.txSigner().sign(ctx, tx.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	trackerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	tracker := g.NewConfirmationTracker(trackerCtx, rebroadcastTrackerOpts(opts))
+
+	return rebroadcast(ctx, g.PostSubmitBatch, g.dryRun, tracker, signedTx, opts)
+}
+
+// SignAndSubmitWithRebroadcast signs the given transaction once and re-sends the identical signed transaction every
+// opts.RebroadcastInterval until it is confirmed or its blockhash expires.
+func (h *HTTPClient) SignAndSubmitWithRebroadcast(ctx context.Context, tx *pb.TransactionMessage, opts SubmitOpts) (*RebroadcastResult, error) {
+	if h.keyring.Len() == 0 {
+		return nil, ErrPrivateKeyNotFound
+	}
+
+	signedTx, err := h.txSigner().sign(ctx, tx.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	trackerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	tracker := h.NewConfirmationTracker(trackerCtx, rebroadcastTrackerOpts(opts))
+
+	return rebroadcast(ctx, h.PostSubmitBatch, h.dryRun, tracker, signedTx, opts)
+}
+
+func rebroadcastTrackerOpts(opts SubmitOpts) ConfirmationTrackerOpts {
+	trackerOpts := DefaultConfirmationTrackerOpts()
+	if opts.RebroadcastInterval != 0 && opts.RebroadcastInterval < trackerOpts.PollInterval {
+		trackerOpts.PollInterval = opts.RebroadcastInterval
+	}
+	return trackerOpts
+}
+
+func rebroadcast(ctx context.Context, submit batchSubmitter, dryRun dryRunner, tracker *ConfirmationTracker, signedTxBase64 string, opts SubmitOpts) (*RebroadcastResult, error) {
+	signature, err := transactionSignature(signedTxBase64)
+	if err != nil {
+		return nil, err
+	}
+
+	if dryRun.active(opts) {
+		simulation, err := dryRun.simulate(ctx, signedTxBase64)
+		if err != nil {
+			return nil, err
+		}
+		result := &RebroadcastResult{Signature: signature, Confirmation: ConfirmationResult{Signature: signature}}
+		if simulation.Err != "" {
+			result.Confirmation.Err = TransactionError{Signature: signature, Message: simulation.Err}
+		}
+		return result, result.Confirmation.Err
+	}
+
+	interval := opts.RebroadcastInterval
+	if interval == 0 {
+		interval = defaultRebroadcastInterval
+	}
+
+	skipPreFlight := true
+	if opts.SkipPreFlight != nil {
+		skipPreFlight = *opts.SkipPreFlight
+	}
+	useBundle := false
+	request := &pb.PostSubmitBatchRequest{
+		Entries: []*pb.PostSubmitRequestEntry{
+			{
+				Transaction:   &pb.TransactionMessage{Content: signedTxBase64},
+				SkipPreFlight: skipPreFlight,
+			},
+		},
+		SubmitStrategy: opts.SubmitStrategy,
+		UseBundle:      &useBundle,
+	}
+
+	result := &RebroadcastResult{Signature: signature}
+
+	// the first submission must succeed: errors here (e.g. failed preflight) mean the transaction will never land
+	result.Attempts++
+	response, err := submit(ctx, request)
+	if err != nil {
+		return result, err
+	}
+	if len(response.Transactions) == 1 && !response.Transactions[0].Submitted {
+		return result, fmt.Errorf("transaction was not submitted: %v", response.Transactions[0].Error)
+	}
+
+	future := tracker.Track(signature)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-future.Done():
+			result.Confirmation, _ = future.Wait(ctx)
+			return result, result.Confirmation.Err
+		case <-ticker.C:
+			result.Attempts++
+			if _, err := submit(ctx, request); err != nil {
+				log.Debugf("rebroadcast attempt %v of %v failed: %v", result.Attempts, signature, err)
+			}
+		case <-ctx.Done():
+			return result, ctx.Err()
+		}
+	}
+}
+
+func transactionSignature(txBase64 string) (string, error) {
+	txBytes, err := solanarpc.DataBytesOrJSONFromBase64(txBase64)
+	if err != nil {
+		return "", err
+	}
+
+	solanaTx, err := solanarpc.TransactionWithMeta{Transaction: txBytes}.GetTransaction()
+	if err != nil {
+		return "", err
+	}
+
+	if len(solanaTx.Signatures) == 0 || solanaTx.Signatures[0] == (solana.Signature{}) {
+		return "", fmt.Errorf("transaction is not signed by its fee payer")
+	}
+	return solanaTx.Signatures[0].String(), nil
+}
@@ -19,6 +19,7 @@ type recentBlockHashStore struct {
 	hash               string
 	hashTime           time.Time
 	hashExpiryDuration time.Duration
+	running            bool
 }
 
 func newRecentBlockHashStore(
@@ -36,7 +37,27 @@ func newRecentBlockHashStore(
 	}
 }
 
+// warm keeps the hash current from the stream until ctx is canceled, unless the stream is already running
+func (s *recentBlockHashStore) warm(ctx context.Context) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.running {
+		return
+	}
+	s.running = true
+	go s.run(ctx)
+}
+
 func (s *recentBlockHashStore) run(ctx context.Context) {
+	s.mutex.Lock()
+	s.running = true
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		s.running = false
+		s.mutex.Unlock()
+	}()
+
 	stream, err := s.hashStreamProvider(ctx)
 	if err != nil {
 		log.Error("can't open recent block hash stream")
@@ -0,0 +1,560 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/bloXroute-Labs/solana-trader-proto/common"
+	"github.com/gagliardetto/solana-go"
+)
+
+var (
+	ErrNoRoute           = errors.New("no route found")
+	ErrRouteBelowMinimum = errors.New("route output below minimum")
+)
+
+// RouteVenue is the AMM a Route swaps through
+type RouteVenue int
+
+const (
+	VenueRaydium RouteVenue = iota
+	VenueRaydiumCPMM
+	VenueRaydiumCLMM
+	VenueJupiter
+	VenuePumpFun
+)
+
+var allRouteVenues = []RouteVenue{VenueRaydium, VenueRaydiumCPMM, VenueRaydiumCLMM, VenueJupiter, VenuePumpFun}
+
+func (v RouteVenue) String() string {
+	switch v {
+	case VenueRaydium:
+		return "raydium"
+	case VenueRaydiumCPMM:
+		return "raydium-cpmm"
+	case VenueRaydiumCLMM:
+		return "raydium-clmm"
+	case VenueJupiter:
+		return "jupiter"
+	case VenuePumpFun:
+		return "pumpfun"
+	default:
+		return fmt.Sprintf("venue(%d)", int(v))
+	}
+}
+
+// project is the project whose priority fees apply to swaps on the venue. The API has no Pump.fun project, so its
+// swaps are priced with the fees of all projects rather than those of Raydium.
+func (v RouteVenue) project() pb.Project {
+	switch v {
+	case VenueJupiter:
+		return pb.Project_P_JUPITER
+	case VenuePumpFun:
+		return pb.Project_P_ALL
+	default:
+		return pb.Project_P_RAYDIUM
+	}
+}
+
+// defaultRouteComputeUnits approximates the compute units used by a swap on each venue
+var defaultRouteComputeUnits = map[RouteVenue]uint32{
+	VenueRaydium:     100_000,
+	VenueRaydiumCPMM: 150_000,
+	VenueRaydiumCLMM: 200_000,
+	VenueJupiter:     300_000,
+	VenuePumpFun:     100_000,
+}
+
+// PumpFunToken identifies a PumpFun token so the router can quote its bonding curve
+type PumpFunToken struct {
+	Mint                string
+	BondingCurveAddress string
+}
+
+type RouteRequest struct {
+	Owner    string
+	InToken  string
+	OutToken string
+	InAmount float64
+
+	// Slippage in percent, passed to the quotes and swaps of every venue
+	Slippage float64
+
+	// MinOutAmount rejects routes whose minimum output after slippage is lower. Disabled if 0.
+	MinOutAmount float64
+
+	// PumpFun enables quoting the bonding curve of a PumpFun token. One of InToken and OutToken must be SOL and the
+	// other the token's mint.
+	PumpFun *PumpFunToken
+
+	// Venues restricts the venues that are quoted. All venues are quoted if empty.
+	Venues []RouteVenue
+}
+
+// Route is a quote from one venue, normalized so quotes of different venues can be compared
+type Route struct {
+	Venue        RouteVenue
+	InToken      string
+	OutToken     string
+	InAmount     float64
+	OutAmount    float64
+	OutAmountMin float64
+
+	// PriceImpactPercent is summed over the steps of the route. It is infinite if any step has an infinite price
+	// impact.
+	PriceImpactPercent float64
+	Fees               []*common.Fee
+
+	// ComputeUnitPrice (in micro-lamports) and ComputeUnitLimit are set on the swap transaction. PriorityFee is the
+	// resulting fee in lamports.
+	ComputeUnitPrice uint64
+	ComputeUnitLimit uint32
+	PriorityFee      uint64
+
+	// NetOutAmount is the output amount less the priority fee, valued in the output token when possible
+	NetOutAmount float64
+}
+
+type RouterOpts struct {
+	// Percentile of recent priority fees used as compute unit price. Defaults to 50.
+	Percentile float64
+
+	// ComputeUnits overrides the estimated compute units of a swap on a venue
+	ComputeUnits map[RouteVenue]uint32
+
+	// OutTokensPerSOL values priority fees in the output token when neither side of the swap is SOL. Priority fees
+	// are not netted from such routes if 0.
+	OutTokensPerSOL float64
+
+	// Tip is added to the swap transactions of venues that support it
+	Tip *uint64
+}
+
+type raydiumQuoter func(ctx context.Context, request *pb.GetRaydiumQuotesRequest) (*pb.GetRaydiumQuotesResponse, error)
+type raydiumCPMMQuoter func(ctx context.Context, request *pb.GetRaydiumCPMMQuotesRequest) (*pb.GetRaydiumCPMMQuotesResponse, error)
+type raydiumCLMMQuoter func(ctx context.Context, request *pb.GetRaydiumCLMMQuotesRequest) (*pb.GetRaydiumCLMMQuotesResponse, error)
+type jupiterQuoter func(ctx context.Context, request *pb.GetJupiterQuotesRequest) (*pb.GetJupiterQuotesResponse, error)
+type pumpFunQuoter func(ctx context.Context, request *pb.GetPumpFunQuotesRequest) (*pb.GetPumpFunQuotesResponse, error)
+type raydiumSwapSubmitter func(ctx context.Context, request *pb.PostRaydiumSwapRequest, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error)
+type raydiumCPMMSwapBuilder func(ctx context.Context, request *pb.PostRaydiumCPMMSwapRequest) (*pb.PostRaydiumCPMMSwapResponse, error)
+type jupiterSwapSubmitter func(ctx context.Context, request *pb.PostJupiterSwapRequest, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error)
+type pumpFunSwapBuilder func(ctx context.Context, request *pb.PostPumpFunSwapRequest) (*pb.PostPumpFunSwapResponse, error)
+
+type routerAPI struct {
+	raydiumQuotes     raydiumQuoter
+	raydiumCPMMQuotes raydiumCPMMQuoter
+	raydiumCLMMQuotes raydiumCLMMQuoter
+	jupiterQuotes     jupiterQuoter
+	pumpFunQuotes     pumpFunQuoter
+	raydiumSwap       raydiumSwapSubmitter
+	raydiumCPMMSwap   raydiumCPMMSwapBuilder
+	raydiumCLMMSwap   raydiumSwapSubmitter
+	jupiterSwap       jupiterSwapSubmitter
+	pumpFunSwap       pumpFunSwapBuilder
+
+	// submit signs and submits the transactions of venues that are only built, so they honor SubmitOpts
+	submit signedBatchSubmitter
+}
+
+// Router quotes a swap on every AMM venue concurrently, ranks the routes by output net of priority fees, and executes
+// the swap on the best venue
+type Router struct {
+	api  routerAPI
+	fees *priorityFeeStore
+	opts RouterOpts
+}
+
+func newRouter(api routerAPI, fees *priorityFeeStore, opts RouterOpts) *Router {
+	if opts.Percentile == 0 {
+		opts.Percentile = defaultPriorityFeePercentile
+	}
+	return &Router{api: api, fees: fees, opts: opts}
+}
+
+// NewRouter creates a router quoting and swapping through the websocket connection
+func (w *WSClient) NewRouter(opts RouterOpts) *Router {
+	return newRouter(routerAPI{
+		raydiumQuotes:     w.GetRaydiumQuotes,
+		raydiumCPMMQuotes: w.GetRaydiumQuotesCPMM,
+		raydiumCLMMQuotes: w.GetRaydiumCLMMQuotes,
+		jupiterQuotes:     w.GetJupiterQuotes,
+		pumpFunQuotes:     w.GetPumpFunQuotes,
+		raydiumSwap:       w.SubmitRaydiumSwap,
+		raydiumCPMMSwap:   w.PostRaydiumSwapCPMM,
+		raydiumCLMMSwap:   w.SubmitRaydiumCLMMSwap,
+		jupiterSwap:       w.SubmitJupiterSwap,
+		pumpFunSwap:       w.PostPumpFunSwap,
+		submit:            w.signAndPostBatch,
+	}, w.priorityFeeStore, opts)
+}
+
+// NewRouter creates a router quoting and swapping through the GRPC connection
+func (g *GRPCClient) NewRouter(opts RouterOpts) *Router {
+	return newRouter(routerAPI{
+		raydiumQuotes:     g.GetRaydiumQuotes,
+		raydiumCPMMQuotes: g.GetRaydiumQuotesCPMM,
+		raydiumCLMMQuotes: g.GetRaydiumCLMMQuotes,
+		jupiterQuotes:     g.GetJupiterQuotes,
+		pumpFunQuotes:     g.GetPumpFunQuotes,
+		raydiumSwap:       g.SubmitRaydiumSwap,
+		raydiumCPMMSwap:   g.PostRaydiumSwapCPMM,
+		raydiumCLMMSwap:   g.SubmitRaydiumCLMMSwap,
+		jupiterSwap:       g.SubmitJupiterSwap,
+		pumpFunSwap:       g.PostPumpFunSwap,
+		submit:            g.signAndPostBatch,
+	}, g.priorityFeeStore, opts)
+}
+
+// NewRouter creates a router quoting and swapping through HTTP
+func (h *HTTPClient) NewRouter(opts RouterOpts) *Router {
+	return newRouter(routerAPI{
+		raydiumQuotes:     h.GetRaydiumQuotes,
+		raydiumCPMMQuotes: h.GetRaydiumQuotesCPMM,
+		raydiumCLMMQuotes: h.GetRaydiumCLMMQuotes,
+		jupiterQuotes:     h.GetJupiterQuotes,
+		pumpFunQuotes:     h.GetPumpFunQuotes,
+		raydiumSwap:       h.SubmitRaydiumSwap,
+		raydiumCPMMSwap:   h.PostRaydiumCPMMSwap,
+		raydiumCLMMSwap:   h.SubmitRaydiumCLMMSwap,
+		jupiterSwap:       h.SubmitJupiterSwap,
+		pumpFunSwap:       h.PostPumpFunSwap,
+		submit:            h.signAndPostBatch,
+	}, h.priorityFeeStore, opts)
+}
+
+// Quote returns the routes of every venue that can fill the request, best first. Venues that fail to quote are
+// skipped; an error is only returned if no venue returned a route.
+func (r *Router) Quote(ctx context.Context, request RouteRequest) ([]*Route, error) {
+	venues := request.Venues
+	if len(venues) == 0 {
+		venues = allRouteVenues
+	}
+
+	var (
+		mutex  sync.Mutex
+		wg     sync.WaitGroup
+		routes []*Route
+		errs   []error
+	)
+	for _, venue := range venues {
+		if venue == VenuePumpFun && request.PumpFun == nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func(venue RouteVenue) {
+			defer wg.Done()
+
+			venueRoutes, err := r.quote(ctx, venue, request)
+			if err == nil {
+				for _, route := range venueRoutes {
+					if err = r.addPriorityFee(ctx, route); err != nil {
+						break
+					}
+				}
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%v: %w", venue, err))
+				return
+			}
+			routes = append(routes, venueRoutes...)
+		}(venue)
+	}
+	wg.Wait()
+
+	routes = filterRoutes(routes, request.MinOutAmount)
+	if len(routes) == 0 {
+		return nil, errors.Join(append([]error{ErrNoRoute}, errs...)...)
+	}
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		return routes[i].NetOutAmount > routes[j].NetOutAmount
+	})
+	return routes, nil
+}
+
+// Best returns the route with the highest output net of priority fees
+func (r *Router) Best(ctx context.Context, request RouteRequest) (*Route, error) {
+	routes, err := r.Quote(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return routes[0], nil
+}
+
+// Swap quotes the request on every venue and executes it on the best route
+func (r *Router) Swap(ctx context.Context, request RouteRequest, opts SubmitOpts) (*Route, *pb.PostSubmitBatchResponse, error) {
+	route, err := r.Best(ctx, request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	response, err := r.Execute(ctx, request, route, opts)
+	return route, response, err
+}
+
+// Execute swaps through the route's venue with the request's slippage and the route's compute budget
+func (r *Router) Execute(ctx context.Context, request RouteRequest, route *Route, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	if request.MinOutAmount != 0 && route.OutAmountMin < request.MinOutAmount {
+		return nil, fmt.Errorf("%w: %v < %v", ErrRouteBelowMinimum, route.OutAmountMin, request.MinOutAmount)
+	}
+
+	switch route.Venue {
+	case VenueRaydium, VenueRaydiumCLMM:
+		swapRequest := &pb.PostRaydiumSwapRequest{
+			OwnerAddress: request.Owner,
+			InToken:      route.InToken,
+			OutToken:     route.OutToken,
+			InAmount:     route.InAmount,
+			Slippage:     request.Slippage,
+			ComputeLimit: route.ComputeUnitLimit,
+			ComputePrice: route.ComputeUnitPrice,
+			Tip:          r.opts.Tip,
+		}
+		if route.Venue == VenueRaydiumCLMM {
+			return r.api.raydiumCLMMSwap(ctx, swapRequest, opts)
+		}
+		return r.api.raydiumSwap(ctx, swapRequest, opts)
+	case VenueRaydiumCPMM:
+		response, err := r.api.raydiumCPMMSwap(ctx, &pb.PostRaydiumCPMMSwapRequest{
+			OwnerAddress: request.Owner,
+			InToken:      route.InToken,
+			OutToken:     route.OutToken,
+			InAmount:     route.InAmount,
+			Slippage:     request.Slippage,
+			ComputeLimit: route.ComputeUnitLimit,
+			ComputePrice: route.ComputeUnitPrice,
+			Tip:          r.opts.Tip,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return r.api.submit(ctx, []*pb.TransactionMessage{response.Transaction}, false, opts)
+	case VenueJupiter:
+		return r.api.jupiterSwap(ctx, &pb.PostJupiterSwapRequest{
+			OwnerAddress: request.Owner,
+			InToken:      route.InToken,
+			OutToken:     route.OutToken,
+			InAmount:     route.InAmount,
+			Slippage:     request.Slippage,
+			ComputeLimit: route.ComputeUnitLimit,
+			ComputePrice: route.ComputeUnitPrice,
+			Tip:          r.opts.Tip,
+		}, opts)
+	case VenuePumpFun:
+		if request.PumpFun == nil {
+			return nil, errors.New("pumpfun route requires a PumpFun token")
+		}
+		swapRequest := &pb.PostPumpFunSwapRequest{
+			UserAddress:         request.Owner,
+			BondingCurveAddress: request.PumpFun.BondingCurveAddress,
+			TokenAddress:        request.PumpFun.Mint,
+			IsBuy:               isSOLToken(route.InToken),
+			ComputeLimit:        route.ComputeUnitLimit,
+			ComputePrice:        route.ComputeUnitPrice,
+			Tip:                 r.opts.Tip,
+		}
+		// the bonding curve swaps an exact token amount, bounded by the SOL spent on buys or received on sells
+		if swapRequest.IsBuy {
+			swapRequest.TokenAmount = route.OutAmount
+			swapRequest.SolThreshold = route.InAmount * (1 + request.Slippage/100)
+		} else {
+			swapRequest.TokenAmount = route.InAmount
+			swapRequest.SolThreshold = route.OutAmountMin
+		}
+		response, err := r.api.pumpFunSwap(ctx, swapRequest)
+		if err != nil {
+			return nil, err
+		}
+		return r.api.submit(ctx, []*pb.TransactionMessage{{Content: response.Transaction.Content}}, false, opts)
+	default:
+		return nil, fmt.Errorf("unknown venue %v", route.Venue)
+	}
+}
+
+func (r *Router) quote(ctx context.Context, venue RouteVenue, request RouteRequest) ([]*Route, error) {
+	switch venue {
+	case VenueRaydium:
+		response, err := r.api.raydiumQuotes(ctx, &pb.GetRaydiumQuotesRequest{
+			InToken:  request.InToken,
+			OutToken: request.OutToken,
+			InAmount: request.InAmount,
+			Slippage: request.Slippage,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return raydiumRoutes(venue, response.InTokenAddress, response.OutTokenAddress, response.Routes), nil
+	case VenueRaydiumCPMM:
+		response, err := r.api.raydiumCPMMQuotes(ctx, &pb.GetRaydiumCPMMQuotesRequest{
+			InToken:  request.InToken,
+			OutToken: request.OutToken,
+			InAmount: request.InAmount,
+			Slippage: request.Slippage,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return raydiumRoutes(venue, response.InTokenAddress, response.OutTokenAddress, response.Routes), nil
+	case VenueRaydiumCLMM:
+		response, err := r.api.raydiumCLMMQuotes(ctx, &pb.GetRaydiumCLMMQuotesRequest{
+			InToken:  request.InToken,
+			OutToken: request.OutToken,
+			InAmount: request.InAmount,
+			Slippage: request.Slippage,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return raydiumRoutes(venue, response.InTokenAddress, response.OutTokenAddress, response.Routes), nil
+	case VenueJupiter:
+		response, err := r.api.jupiterQuotes(ctx, &pb.GetJupiterQuotesRequest{
+			InToken:  request.InToken,
+			OutToken: request.OutToken,
+			InAmount: request.InAmount,
+			Slippage: request.Slippage,
+		})
+		if err != nil {
+			return nil, err
+		}
+		routes := make([]*Route, 0, len(response.Routes))
+		for _, quoteRoute := range response.Routes {
+			route := &Route{
+				Venue:        venue,
+				InToken:      response.InTokenAddress,
+				OutToken:     response.OutTokenAddress,
+				InAmount:     quoteRoute.InAmount,
+				OutAmount:    quoteRoute.OutAmount,
+				OutAmountMin: quoteRoute.OutAmountMin,
+			}
+			for _, step := range quoteRoute.Steps {
+				route.addStep(step.PriceImpactPercent, step.Fee)
+			}
+			routes = append(routes, route)
+		}
+		return routes, nil
+	case VenuePumpFun:
+		return r.quotePumpFun(ctx, request)
+	default:
+		return nil, fmt.Errorf("unknown venue %v", venue)
+	}
+}
+
+func (r *Router) quotePumpFun(ctx context.Context, request RouteRequest) ([]*Route, error) {
+	var quoteType string
+	switch {
+	case isSOLToken(request.InToken) && request.OutToken == request.PumpFun.Mint:
+		quoteType = "buy"
+	case request.InToken == request.PumpFun.Mint && isSOLToken(request.OutToken):
+		quoteType = "sell"
+	default:
+		return nil, nil
+	}
+
+	response, err := r.api.pumpFunQuotes(ctx, &pb.GetPumpFunQuotesRequest{
+		QuoteType:           quoteType,
+		MintAddress:         request.PumpFun.Mint,
+		BondingCurveAddress: request.PumpFun.BondingCurveAddress,
+		Amount:              request.InAmount,
+		Slippage:            request.Slippage,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return []*Route{{
+		Venue:        VenuePumpFun,
+		InToken:      request.InToken,
+		OutToken:     request.OutToken,
+		InAmount:     response.InAmount,
+		OutAmount:    response.OutAmount,
+		OutAmountMin: response.OutAmount * (1 - request.Slippage/100),
+	}}, nil
+}
+
+// addPriorityFee prices the route's compute units at the current priority fee and nets the fee from its output
+func (r *Router) addPriorityFee(ctx context.Context, route *Route) error {
+	price, err := r.fees.get(ctx, route.Venue.project(), r.opts.Percentile)
+	if err != nil {
+		return fmt.Errorf("could not retrieve priority fee: %w", err)
+	}
+
+	limit, ok := r.opts.ComputeUnits[route.Venue]
+	if !ok {
+		limit = defaultRouteComputeUnits[route.Venue]
+	}
+
+	route.ComputeUnitPrice = price
+	route.ComputeUnitLimit = limit
+	route.PriorityFee = priorityFeeLamports(limit, price)
+
+	feeSOL := float64(route.PriorityFee) / float64(solana.LAMPORTS_PER_SOL)
+	switch {
+	case isSOLToken(route.OutToken):
+		route.NetOutAmount = route.OutAmount - feeSOL
+	case isSOLToken(route.InToken) && route.InAmount > 0:
+		route.NetOutAmount = route.OutAmount - feeSOL*route.OutAmount/route.InAmount
+	default:
+		route.NetOutAmount = route.OutAmount - feeSOL*r.opts.OutTokensPerSOL
+	}
+	return nil
+}
+
+func (route *Route) addStep(priceImpact *common.PriceImpactPercentV2, fee *common.Fee) {
+	if priceImpact != nil {
+		switch priceImpact.Infinity {
+		case "POSITIVE":
+			route.PriceImpactPercent = math.Inf(1)
+		case "NEGATIVE":
+			route.PriceImpactPercent = math.Inf(-1)
+		default:
+			route.PriceImpactPercent += priceImpact.Percent
+		}
+	}
+	if fee != nil {
+		route.Fees = append(route.Fees, fee)
+	}
+}
+
+func raydiumRoutes(venue RouteVenue, inToken, outToken string, quoteRoutes []*pb.RaydiumQuoteRoute) []*Route {
+	routes := make([]*Route, 0, len(quoteRoutes))
+	for _, quoteRoute := range quoteRoutes {
+		route := &Route{
+			Venue:        venue,
+			InToken:      inToken,
+			OutToken:     outToken,
+			InAmount:     quoteRoute.InAmount,
+			OutAmount:    quoteRoute.OutAmount,
+			OutAmountMin: quoteRoute.OutAmountMin,
+		}
+		for _, step := range quoteRoute.Steps {
+			route.addStep(step.PriceImpactPercent, step.Fee)
+		}
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+func filterRoutes(routes []*Route, minOutAmount float64) []*Route {
+	filtered := routes[:0]
+	for _, route := range routes {
+		if route.OutAmount <= 0 || route.OutAmountMin < minOutAmount {
+			continue
+		}
+		filtered = append(filtered, route)
+	}
+	return filtered
+}
+
+func isSOLToken(token string) bool {
+	return strings.EqualFold(token, "SOL") || strings.EqualFold(token, "WSOL") || token == solana.SolMint.String()
+}
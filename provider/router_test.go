@@ -0,0 +1,234 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRouterAPI quotes each venue at a fixed output amount with 1% slippage, or fails venues that have no output
+func fakeRouterAPI(outAmounts map[RouteVenue]float64) routerAPI {
+	quoteErr := errors.New("no pool")
+	raydiumRoute := func(venue RouteVenue, inAmount float64) ([]*pb.RaydiumQuoteRoute, error) {
+		out, ok := outAmounts[venue]
+		if !ok {
+			return nil, quoteErr
+		}
+		return []*pb.RaydiumQuoteRoute{{InAmount: inAmount, OutAmount: out, OutAmountMin: out * 0.99}}, nil
+	}
+
+	return routerAPI{
+		raydiumQuotes: func(_ context.Context, request *pb.GetRaydiumQuotesRequest) (*pb.GetRaydiumQuotesResponse, error) {
+			routes, err := raydiumRoute(VenueRaydium, request.InAmount)
+			return &pb.GetRaydiumQuotesResponse{InTokenAddress: request.InToken, OutTokenAddress: request.OutToken, Routes: routes}, err
+		},
+		raydiumCPMMQuotes: func(_ context.Context, request *pb.GetRaydiumCPMMQuotesRequest) (*pb.GetRaydiumCPMMQuotesResponse, error) {
+			routes, err := raydiumRoute(VenueRaydiumCPMM, request.InAmount)
+			return &pb.GetRaydiumCPMMQuotesResponse{InTokenAddress: request.InToken, OutTokenAddress: request.OutToken, Routes: routes}, err
+		},
+		raydiumCLMMQuotes: func(_ context.Context, request *pb.GetRaydiumCLMMQuotesRequest) (*pb.GetRaydiumCLMMQuotesResponse, error) {
+			routes, err := raydiumRoute(VenueRaydiumCLMM, request.InAmount)
+			return &pb.GetRaydiumCLMMQuotesResponse{InTokenAddress: request.InToken, OutTokenAddress: request.OutToken, Routes: routes}, err
+		},
+		jupiterQuotes: func(_ context.Context, request *pb.GetJupiterQuotesRequest) (*pb.GetJupiterQuotesResponse, error) {
+			out, ok := outAmounts[VenueJupiter]
+			if !ok {
+				return nil, quoteErr
+			}
+			return &pb.GetJupiterQuotesResponse{InTokenAddress: request.InToken, OutTokenAddress: request.OutToken, Routes: []*pb.JupiterQuoteRoute{
+				{InAmount: request.InAmount, OutAmount: out, OutAmountMin: out * 0.99},
+			}}, nil
+		},
+		pumpFunQuotes: func(_ context.Context, request *pb.GetPumpFunQuotesRequest) (*pb.GetPumpFunQuotesResponse, error) {
+			out, ok := outAmounts[VenuePumpFun]
+			if !ok {
+				return nil, quoteErr
+			}
+			return &pb.GetPumpFunQuotesResponse{QuoteType: request.QuoteType, InAmount: request.Amount, OutAmount: out}, nil
+		},
+	}
+}
+
+func TestRouterQuote(t *testing.T) {
+	sell := RouteRequest{Owner: "owner", InToken: "token", OutToken: "SOL", InAmount: 100, Slippage: 1}
+	buy := RouteRequest{Owner: "owner", InToken: "SOL", OutToken: "token", InAmount: 1, Slippage: 1}
+	with := func(request RouteRequest, update func(*RouteRequest)) RouteRequest {
+		update(&request)
+		return request
+	}
+
+	// at 10,000,000 micro-lamports per compute unit, a swap costs 0.001 SOL on Raydium, 0.0015 SOL on Raydium CPMM,
+	// 0.002 SOL on Raydium CLMM, 0.001 SOL on PumpFun and 0.003 SOL on Jupiter
+	tests := []struct {
+		name       string
+		request    RouteRequest
+		outAmounts map[RouteVenue]float64
+		venues     []RouteVenue
+		netOut     float64
+		err        error
+	}{
+		{
+			name:       "highest output first",
+			request:    sell,
+			outAmounts: map[RouteVenue]float64{VenueRaydium: 1, VenueRaydiumCPMM: 1.001, VenueJupiter: 0.9},
+			venues:     []RouteVenue{VenueRaydiumCPMM, VenueRaydium, VenueJupiter},
+			netOut:     0.9995,
+		},
+		{
+			name:       "priority fee outweighs a higher output",
+			request:    sell,
+			outAmounts: map[RouteVenue]float64{VenueRaydium: 1, VenueJupiter: 1.0015},
+			venues:     []RouteVenue{VenueRaydium, VenueJupiter},
+			netOut:     0.999,
+		},
+		{
+			// on buys the fee is valued in the output token at the quoted price
+			name:       "fee valued in output token",
+			request:    buy,
+			outAmounts: map[RouteVenue]float64{VenueRaydium: 1000, VenueRaydiumCLMM: 1000.5},
+			venues:     []RouteVenue{VenueRaydium, VenueRaydiumCLMM},
+			netOut:     999,
+		},
+		{
+			name:       "minimum output",
+			request:    with(sell, func(r *RouteRequest) { r.MinOutAmount = 0.99 }),
+			outAmounts: map[RouteVenue]float64{VenueRaydium: 1, VenueJupiter: 0.999},
+			venues:     []RouteVenue{VenueRaydium},
+			netOut:     0.999,
+		},
+		{
+			name:       "restricted venues",
+			request:    with(sell, func(r *RouteRequest) { r.Venues = []RouteVenue{VenueJupiter} }),
+			outAmounts: map[RouteVenue]float64{VenueRaydium: 2, VenueJupiter: 1},
+			venues:     []RouteVenue{VenueJupiter},
+			netOut:     0.997,
+		},
+		{
+			name:       "pumpfun quoted for its token",
+			request:    with(sell, func(r *RouteRequest) { r.PumpFun = &PumpFunToken{Mint: "token", BondingCurveAddress: "curve"} }),
+			outAmounts: map[RouteVenue]float64{VenueRaydium: 1, VenuePumpFun: 1.2},
+			venues:     []RouteVenue{VenuePumpFun, VenueRaydium},
+			netOut:     1.199,
+		},
+		{
+			name:       "pumpfun skipped without token",
+			request:    sell,
+			outAmounts: map[RouteVenue]float64{VenueRaydium: 1, VenuePumpFun: 1.2},
+			venues:     []RouteVenue{VenueRaydium},
+			netOut:     0.999,
+		},
+		{
+			name:       "pumpfun skipped for other tokens",
+			request:    with(sell, func(r *RouteRequest) { r.PumpFun = &PumpFunToken{Mint: "other", BondingCurveAddress: "curve"} }),
+			outAmounts: map[RouteVenue]float64{VenueRaydium: 1, VenuePumpFun: 1.2},
+			venues:     []RouteVenue{VenueRaydium},
+			netOut:     0.999,
+		},
+		{
+			name:       "no venue quotes",
+			request:    sell,
+			outAmounts: map[RouteVenue]float64{VenueJupiter: 0},
+			err:        ErrNoRoute,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newRouter(fakeRouterAPI(test.outAmounts), fixedPriorityFees(10_000_000), RouterOpts{})
+			routes, err := r.Quote(context.Background(), test.request)
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err)
+				require.Contains(t, err.Error(), "no pool")
+				return
+			}
+			require.NoError(t, err)
+
+			venues := make([]RouteVenue, 0, len(routes))
+			for _, route := range routes {
+				venues = append(venues, route.Venue)
+			}
+			require.Equal(t, test.venues, venues)
+			require.InDelta(t, test.netOut, routes[0].NetOutAmount, 1e-9)
+			require.Equal(t, uint64(10_000_000), routes[0].ComputeUnitPrice)
+			require.Equal(t, defaultRouteComputeUnits[routes[0].Venue], routes[0].ComputeUnitLimit)
+		})
+	}
+}
+
+func TestRouterExecute(t *testing.T) {
+	ctx := context.Background()
+	var (
+		pumpFunRequest *pb.PostPumpFunSwapRequest
+		submitted      []*pb.TransactionMessage
+	)
+	api := fakeRouterAPI(map[RouteVenue]float64{VenueRaydium: 1000, VenuePumpFun: 1100})
+	api.pumpFunSwap = func(_ context.Context, request *pb.PostPumpFunSwapRequest) (*pb.PostPumpFunSwapResponse, error) {
+		pumpFunRequest = request
+		return &pb.PostPumpFunSwapResponse{Transaction: &pb.TransactionMessageV2{Content: "pumpfun"}}, nil
+	}
+	api.submit = func(_ context.Context, transactions []*pb.TransactionMessage, _ bool, _ SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+		submitted = transactions
+		return &pb.PostSubmitBatchResponse{Transactions: []*pb.PostSubmitBatchResponseEntry{{Signature: "signature", Submitted: true}}}, nil
+	}
+	r := newRouter(api, fixedPriorityFees(10_000_000), RouterOpts{ComputeUnits: map[RouteVenue]uint32{VenuePumpFun: 50_000}})
+
+	request := RouteRequest{
+		Owner:    "owner",
+		InToken:  "SOL",
+		OutToken: "token",
+		InAmount: 1,
+		Slippage: 2,
+		PumpFun:  &PumpFunToken{Mint: "token", BondingCurveAddress: "curve"},
+	}
+	route, response, err := r.Swap(ctx, request, SubmitOpts{})
+	require.NoError(t, err)
+	require.Equal(t, VenuePumpFun, route.Venue)
+	require.Equal(t, []*pb.PostSubmitBatchResponseEntry{{Signature: "signature", Submitted: true}}, response.Transactions)
+	require.Equal(t, []*pb.TransactionMessage{{Content: "pumpfun"}}, submitted)
+
+	// buys swap the quoted token amount, spending at most the input amount plus slippage
+	require.True(t, pumpFunRequest.IsBuy)
+	require.Equal(t, 1100.0, pumpFunRequest.TokenAmount)
+	require.InDelta(t, 1.02, pumpFunRequest.SolThreshold, 1e-9)
+	require.Equal(t, uint32(50_000), pumpFunRequest.ComputeLimit)
+	require.Equal(t, uint64(10_000_000), pumpFunRequest.ComputePrice)
+
+	route.OutAmountMin = 1000
+	request.MinOutAmount = 1050
+	_, err = r.Execute(ctx, request, route, SubmitOpts{})
+	require.ErrorIs(t, err, ErrRouteBelowMinimum)
+}
+
+func TestRouterExecuteDryRun(t *testing.T) {
+	ctx := context.Background()
+	key := solana.NewWallet().PrivateKey
+	signer := txSigner{privateKey: &key, keyring: newKeyring(RPCOpts{PrivateKey: &key})}
+
+	api := fakeRouterAPI(map[RouteVenue]float64{VenueRaydiumCPMM: 1})
+	api.raydiumCPMMSwap = func(_ context.Context, _ *pb.PostRaydiumCPMMSwapRequest) (*pb.PostRaydiumCPMMSwapResponse, error) {
+		return &pb.PostRaydiumCPMMSwapResponse{Transaction: &pb.TransactionMessage{Content: unsignedTransfer(t, key.PublicKey(), 1)}}, nil
+	}
+	api.submit = func(ctx context.Context, transactions []*pb.TransactionMessage, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+		post := func(_ context.Context, _ *pb.PostSubmitBatchRequest) (*pb.PostSubmitBatchResponse, error) {
+			return nil, errors.New("submitted live")
+		}
+		return signAndPostBatch(ctx, transactions, signer, dryRunner{simulator: failingSimulator(2)}, post, useBundle, opts)
+	}
+	r := newRouter(api, fixedPriorityFees(10_000_000), RouterOpts{})
+
+	request := RouteRequest{Owner: key.PublicKey().String(), InToken: "token", OutToken: "SOL", InAmount: 100, Slippage: 1}
+	route, response, err := r.Swap(ctx, request, SubmitOpts{DryRun: true})
+	require.NoError(t, err)
+	require.Equal(t, VenueRaydiumCPMM, route.Venue)
+	require.Equal(t, 1, len(response.Transactions))
+	require.False(t, response.Transactions[0].Submitted)
+	require.NotEmpty(t, response.Transactions[0].Signature)
+
+	// without DryRun the swap is submitted
+	_, err = r.Execute(ctx, request, route, SubmitOpts{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "submitted live")
+}
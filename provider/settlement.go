@@ -0,0 +1,346 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultSettlementInterval     = 30 * time.Second
+	defaultSettlementReportBuffer = 100
+)
+
+type SettlementOpts struct {
+	Owner   string
+	Markets []string
+	Project pb.Project
+
+	// UseSettleV2 builds settle transactions with PostSettleV2 instead of PostSettle
+	UseSettleV2 bool
+
+	// MinAmount is the unsettled base or quote amount an open orders account needs before it's settled
+	MinAmount float64
+
+	// Interval is how often unsettled balances are polled. Defaults to 30 seconds.
+	Interval time.Duration
+
+	// WatchOrderStatus checks for unsettled balances whenever an order of the owner is filled (WS and GRPC only)
+	WatchOrderStatus bool
+
+	SubmitOpts SubmitOpts
+
+	// ReportBuffer is the size of the channel returned by Reports
+	ReportBuffer int
+}
+
+// Settlement is the outcome of settling one open orders account
+type Settlement struct {
+	Market            string
+	OpenOrdersAccount string
+	BaseMint          string
+	BaseAmount        float64
+	QuoteMint         string
+	QuoteAmount       float64
+	Signature         string
+	Err               error
+}
+
+// SettlementReport lists the accounts settled in one settlement run
+type SettlementReport struct {
+	Time        time.Time
+	Settlements []Settlement
+}
+
+type unsettledProvider func(ctx context.Context, market string, ownerAddress string, project pb.Project) (*pb.GetUnsettledResponse, error)
+type tokenAccountsProvider func(ctx context.Context, req *pb.GetTokenAccountsRequest) (*pb.GetTokenAccountsResponse, error)
+type settleBuilder func(ctx context.Context, owner, market, baseTokenWallet, quoteTokenWallet, openOrdersAccount string, project pb.Project) (*pb.PostSettleResponse, error)
+type settleV2Builder func(ctx context.Context, owner, market, baseTokenWallet, quoteTokenWallet, openOrdersAccount string) (*pb.PostSettleResponse, error)
+type signedBatchSubmitter func(ctx context.Context, transactions []*pb.TransactionMessage, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error)
+
+type settlementAPI struct {
+	unsettled     unsettledProvider
+	tokenAccounts tokenAccountsProvider
+	settle        settleBuilder
+	settleV2      settleV2Builder
+	submit        signedBatchSubmitter
+	statusStream  orderStatusStreamProvider
+}
+
+// SettlementService settles unsettled OpenBook funds of an owner across markets. Unsettled balances are polled on a
+// schedule and, if enabled, whenever an order is filled. Token wallets are resolved with GetTokenAccounts, with SOL
+// settled to the owner's wallet if it has no wrapped SOL account, and the settle transactions of all markets are
+// submitted in one batch.
+type SettlementService struct {
+	mutex   sync.Mutex
+	api     settlementAPI
+	opts    SettlementOpts
+	trigger chan struct{}
+	reports chan SettlementReport
+}
+
+func newSettlementService(api settlementAPI, opts SettlementOpts) *SettlementService {
+	if opts.Interval == 0 {
+		opts.Interval = defaultSettlementInterval
+	}
+	if opts.ReportBuffer == 0 {
+		opts.ReportBuffer = defaultSettlementReportBuffer
+	}
+	if opts.SubmitOpts.SubmitStrategy == pb.SubmitStrategy_P_UKNOWN {
+		opts.SubmitOpts.SubmitStrategy = pb.SubmitStrategy_P_SUBMIT_ALL
+	}
+	if opts.SubmitOpts.SkipPreFlight == nil {
+		skipPreFlight := true
+		opts.SubmitOpts.SkipPreFlight = &skipPreFlight
+	}
+
+	return &SettlementService{
+		api:     api,
+		opts:    opts,
+		trigger: make(chan struct{}, 1),
+		reports: make(chan SettlementReport, opts.ReportBuffer),
+	}
+}
+
+// NewSettlementService starts settling the owner's unsettled funds until ctx is canceled
+func (w *WSClient) NewSettlementService(ctx context.Context, opts SettlementOpts) *SettlementService {
+	s := newSettlementService(settlementAPI{
+		unsettled:     w.GetUnsettled,
+		tokenAccounts: w.GetTokenAccounts,
+		settle:        w.PostSettle,
+		settleV2:      w.PostSettleV2,
+		submit:        w.signAndPostBatch,
+		statusStream:  w.GetOrderStatusStream,
+	}, opts)
+	go s.run(ctx)
+	return s
+}
+
+// NewSettlementService starts settling the owner's unsettled funds until ctx is canceled
+func (g *GRPCClient) NewSettlementService(ctx context.Context, opts SettlementOpts) *SettlementService {
+	s := newSettlementService(settlementAPI{
+		unsettled:     g.GetUnsettled,
+		tokenAccounts: g.GetTokenAccounts,
+		settle:        g.PostSettle,
+		settleV2:      g.PostSettleV2,
+		submit:        g.signAndPostBatch,
+		statusStream:  g.GetOrderStatusStream,
+	}, opts)
+	go s.run(ctx)
+	return s
+}
+
+// NewSettlementService starts settling the owner's unsettled funds until ctx is canceled. HTTP has no order status
+// stream, so WatchOrderStatus is ignored and balances are only polled.
+func (h *HTTPClient) NewSettlementService(ctx context.Context, opts SettlementOpts) *SettlementService {
+	s := newSettlementService(settlementAPI{
+		unsettled:     h.GetUnsettled,
+		tokenAccounts: h.GetTokenAccounts,
+		settle:        h.PostSettle,
+		settleV2:      h.PostSettleV2,
+		submit:        h.signAndPostBatch,
+	}, opts)
+	go s.run(ctx)
+	return s
+}
+
+// Reports returns a channel on which a report is published for every run that settled at least one account. Reports
+// are dropped if the channel is full.
+func (s *SettlementService) Reports() <-chan SettlementReport {
+	return s.reports
+}
+
+// Trigger requests a settlement run as soon as possible, e.g. after a fill seen by other means
+func (s *SettlementService) Trigger() {
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// Settle settles every account of the configured markets with enough unsettled funds, returning what was settled
+func (s *SettlementService) Settle(ctx context.Context) (*SettlementReport, error) {
+	// runs triggered by the schedule, fills and callers must not settle the same balances twice
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	report := &SettlementReport{Time: time.Now()}
+	var errs []error
+	for _, market := range s.opts.Markets {
+		unsettled, err := s.api.unsettled(ctx, market, s.opts.Owner, s.opts.Project)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("could not get unsettled funds of market %v: %w", market, err))
+			continue
+		}
+		for _, account := range unsettled.Unsettled {
+			settlement := Settlement{Market: market, OpenOrdersAccount: account.Account}
+			if account.BaseToken != nil {
+				settlement.BaseMint, settlement.BaseAmount = account.BaseToken.Address, account.BaseToken.Amount
+			}
+			if account.QuoteToken != nil {
+				settlement.QuoteMint, settlement.QuoteAmount = account.QuoteToken.Address, account.QuoteToken.Amount
+			}
+			if s.shouldSettle(settlement) {
+				report.Settlements = append(report.Settlements, settlement)
+			}
+		}
+	}
+	if len(report.Settlements) == 0 {
+		return report, errors.Join(errs...)
+	}
+
+	wallets, err := s.tokenWallets(ctx)
+	if err != nil {
+		return report, errors.Join(append(errs, err)...)
+	}
+
+	var (
+		transactions []*pb.TransactionMessage
+		built        []int
+	)
+	for i := range report.Settlements {
+		settlement := &report.Settlements[i]
+		response, err := s.buildSettle(ctx, settlement, wallets)
+		if err != nil {
+			settlement.Err = err
+			continue
+		}
+		transactions = append(transactions, response.Transaction)
+		built = append(built, i)
+	}
+
+	if len(transactions) != 0 {
+		response, err := s.api.submit(ctx, transactions, false, s.opts.SubmitOpts)
+		for j, i := range built {
+			settlement := &report.Settlements[i]
+			switch {
+			case err != nil:
+				settlement.Err = err
+			case j >= len(response.Transactions):
+				settlement.Err = errors.New("settle transaction missing from batch response")
+			case !response.Transactions[j].Submitted:
+				settlement.Signature = response.Transactions[j].Signature
+				settlement.Err = fmt.Errorf("settle transaction was not submitted: %v", response.Transactions[j].Error)
+			default:
+				settlement.Signature = response.Transactions[j].Signature
+			}
+		}
+	}
+
+	s.publish(*report)
+	return report, errors.Join(errs...)
+}
+
+func (s *SettlementService) shouldSettle(settlement Settlement) bool {
+	if settlement.BaseAmount <= 0 && settlement.QuoteAmount <= 0 {
+		return false
+	}
+	return settlement.BaseAmount >= s.opts.MinAmount || settlement.QuoteAmount >= s.opts.MinAmount
+}
+
+// tokenWallets maps the owner's token mints to their token accounts
+func (s *SettlementService) tokenWallets(ctx context.Context) (map[string]string, error) {
+	response, err := s.api.tokenAccounts(ctx, &pb.GetTokenAccountsRequest{OwnerAddress: s.opts.Owner})
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve token accounts of %v: %w", s.opts.Owner, err)
+	}
+
+	wallets := make(map[string]string, len(response.Accounts))
+	for _, account := range response.Accounts {
+		wallets[account.TokenMint] = account.TokenAccount
+	}
+	return wallets, nil
+}
+
+func (s *SettlementService) buildSettle(ctx context.Context, settlement *Settlement, wallets map[string]string) (*pb.PostSettleResponse, error) {
+	baseWallet, ok := s.settleWallet(settlement.BaseMint, wallets)
+	if !ok {
+		return nil, fmt.Errorf("%v has no token account for base mint %q", s.opts.Owner, settlement.BaseMint)
+	}
+	quoteWallet, ok := s.settleWallet(settlement.QuoteMint, wallets)
+	if !ok {
+		return nil, fmt.Errorf("%v has no token account for quote mint %q", s.opts.Owner, settlement.QuoteMint)
+	}
+
+	if s.opts.UseSettleV2 {
+		return s.api.settleV2(ctx, s.opts.Owner, settlement.Market, baseWallet, quoteWallet, settlement.OpenOrdersAccount)
+	}
+	return s.api.settle(ctx, s.opts.Owner, settlement.Market, baseWallet, quoteWallet, settlement.OpenOrdersAccount, s.opts.Project)
+}
+
+// settleWallet returns the owner's token account for the mint. SOL is settled to the owner's wallet if the owner has
+// no wrapped SOL account.
+func (s *SettlementService) settleWallet(mint string, wallets map[string]string) (string, bool) {
+	if wallet, ok := wallets[mint]; ok {
+		return wallet, true
+	}
+	if isSOLToken(mint) {
+		return s.opts.Owner, true
+	}
+	return "", false
+}
+
+func (s *SettlementService) publish(report SettlementReport) {
+	select {
+	case s.reports <- report:
+	default:
+		log.Warnf("settlement reports channel full, dropping report of %v settlements", len(report.Settlements))
+	}
+}
+
+func (s *SettlementService) run(ctx context.Context) {
+	if s.opts.WatchOrderStatus && s.api.statusStream != nil {
+		for _, market := range s.opts.Markets {
+			go s.watchFills(ctx, market)
+		}
+	}
+
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.trigger:
+		case <-ctx.Done():
+			return
+		}
+
+		if _, err := s.Settle(ctx); err != nil {
+			log.Errorf("settlement failed: %v", err)
+		}
+	}
+}
+
+// watchFills triggers a settlement run whenever an order of the owner on the market is filled
+func (s *SettlementService) watchFills(ctx context.Context, market string) {
+	stream, err := s.api.statusStream(ctx, market, s.opts.Owner, s.opts.Project)
+	if err != nil {
+		log.Errorf("can't watch order status of market %v for settlement, falling back to polling: %v", market, err)
+		return
+	}
+
+	ch := stream.Channel(10)
+	for {
+		select {
+		case update, ok := <-ch:
+			if !ok {
+				log.Warnf("order status stream of market %v closed, falling back to polling for settlement", market)
+				return
+			}
+			if update.OrderInfo == nil {
+				continue
+			}
+			status := update.OrderInfo.OrderStatus
+			if status == pb.OrderStatus_OS_FILLED || status == pb.OrderStatus_OS_PARTIAL_FILL {
+				s.Trigger()
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSettlementServiceSettle(t *testing.T) {
+	account := func(address, baseMint string, baseAmount float64, quoteMint string, quoteAmount float64) *pb.UnsettledAccount {
+		return &pb.UnsettledAccount{
+			Account:    address,
+			BaseToken:  &pb.UnsettledAccountToken{Address: baseMint, Amount: baseAmount},
+			QuoteToken: &pb.UnsettledAccountToken{Address: quoteMint, Amount: quoteAmount},
+		}
+	}
+
+	var settled []string
+	var submitted SubmitOpts
+	s := newSettlementService(settlementAPI{
+		unsettled: func(_ context.Context, market string, _ string, _ pb.Project) (*pb.GetUnsettledResponse, error) {
+			return &pb.GetUnsettledResponse{Market: market, Unsettled: []*pb.UnsettledAccount{
+				account("dust", "sol", 0.001, "usdc", 0),
+				account("filled", "sol", 2, "usdc", 0),
+				account("no wallet", "ray", 5, "usdc", 0),
+				account("rejected", "sol", 0, "usdc", 10),
+				account("native", solana.SolMint.String(), 3, "usdc", 0),
+			}}, nil
+		},
+		tokenAccounts: func(_ context.Context, _ *pb.GetTokenAccountsRequest) (*pb.GetTokenAccountsResponse, error) {
+			return &pb.GetTokenAccountsResponse{Accounts: []*pb.TokenAccount{
+				{TokenMint: "sol", TokenAccount: "sol wallet"},
+				{TokenMint: "usdc", TokenAccount: "usdc wallet"},
+			}}, nil
+		},
+		settle: func(_ context.Context, _, _, baseTokenWallet, quoteTokenWallet, openOrdersAccount string, _ pb.Project) (*pb.PostSettleResponse, error) {
+			// without a wrapped SOL account, SOL is settled to the owner
+			if openOrdersAccount == "native" {
+				require.Equal(t, "owner", baseTokenWallet)
+			} else {
+				require.Equal(t, "sol wallet", baseTokenWallet)
+			}
+			require.Equal(t, "usdc wallet", quoteTokenWallet)
+			settled = append(settled, openOrdersAccount)
+			return &pb.PostSettleResponse{Transaction: &pb.TransactionMessage{Content: openOrdersAccount}}, nil
+		},
+		submit: func(_ context.Context, transactions []*pb.TransactionMessage, _ bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+			submitted = opts
+			require.Equal(t, 3, len(transactions))
+			return &pb.PostSubmitBatchResponse{Transactions: []*pb.PostSubmitBatchResponseEntry{
+				{Signature: "filled signature", Submitted: true},
+				{Signature: "rejected signature", Error: "blockhash expired"},
+				{Signature: "native signature", Submitted: true},
+			}}, nil
+		},
+	}, SettlementOpts{Owner: "owner", Markets: []string{"SOL/USDC"}, MinAmount: 0.01})
+
+	report, err := s.Settle(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"filled", "rejected", "native"}, settled)
+	require.True(t, *submitted.SkipPreFlight)
+	require.Equal(t, pb.SubmitStrategy_P_SUBMIT_ALL, submitted.SubmitStrategy)
+
+	// the dust account is below MinAmount and left out of the report
+	require.Equal(t, 4, len(report.Settlements))
+
+	filled := report.Settlements[0]
+	require.Equal(t, "filled", filled.OpenOrdersAccount)
+	require.Equal(t, "SOL/USDC", filled.Market)
+	require.Equal(t, 2.0, filled.BaseAmount)
+	require.Equal(t, "filled signature", filled.Signature)
+	require.NoError(t, filled.Err)
+
+	noWallet := report.Settlements[1]
+	require.Equal(t, "no wallet", noWallet.OpenOrdersAccount)
+	require.Error(t, noWallet.Err)
+	require.Contains(t, noWallet.Err.Error(), `no token account for base mint "ray"`)
+	require.Empty(t, noWallet.Signature)
+
+	rejected := report.Settlements[2]
+	require.Equal(t, "rejected signature", rejected.Signature)
+	require.Error(t, rejected.Err)
+	require.Contains(t, rejected.Err.Error(), "blockhash expired")
+
+	native := report.Settlements[3]
+	require.Equal(t, "native signature", native.Signature)
+	require.NoError(t, native.Err)
+}
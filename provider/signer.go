@@ -0,0 +1,128 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
+	"github.com/gagliardetto/solana-go"
+	addresslookuptable "github.com/gagliardetto/solana-go/programs/address-lookup-table"
+	solanarpc "github.com/gagliardetto/solana-go/rpc"
+)
+
+type lookupTableResolver func(ctx context.Context, tables []solana.PublicKey) (map[solana.PublicKey]solana.PublicKeySlice, error)
+
+func newLookupTableResolver(endpoint string) lookupTableResolver {
+	if endpoint == "" {
+		return func(context.Context, []solana.PublicKey) (map[solana.PublicKey]solana.PublicKeySlice, error) {
+			return nil, fmt.Errorf("%w: RPCOpts.SolanaRPCEndpoint is not configured", transaction.ErrLookupTablesRequired)
+		}
+	}
+
+	client := solanarpc.New(endpoint)
+	return func(ctx context.Context, tables []solana.PublicKey) (map[solana.PublicKey]solana.PublicKeySlice, error) {
+		resolved := make(map[solana.PublicKey]solana.PublicKeySlice, len(tables))
+		for _, table := range tables {
+			state, err := addresslookuptable.GetAddressLookupTable(ctx, client, table)
+			if err != nil {
+				return nil, fmt.Errorf("could not retrieve address lookup table %v: %w", table, err)
+			}
+			resolved[table] = state.Addresses
+		}
+		return resolved, nil
+	}
+}
+
+// newKeyring returns a copy of the keyring of RPCOpts, or a new one, holding the default private key as well. The
+// caller's keyring is left unchanged.
+func newKeyring(opts RPCOpts) *transaction.Keyring {
+	keyring := transaction.NewKeyring()
+	if opts.Keyring != nil {
+		keyring = opts.Keyring.Clone()
+	}
+	if opts.PrivateKey != nil {
+		keyring.Add(*opts.PrivateKey)
+	}
+	return keyring
+}
+
+// txSigner signs transactions with every key of the client's keyring that the transaction requires, enforcing the
+// signing policy if one is configured. The default private key pays for transactions the client creates itself.
+type txSigner struct {
+	privateKey   *solana.PrivateKey
+	keyring      *transaction.Keyring
+	policy       *transaction.Policy
+	lookupTables lookupTableResolver
+}
+
+func (w *WSClient) txSigner() txSigner {
+	return txSigner{privateKey: w.privateKey, keyring: w.keyring, policy: w.signingPolicy, lookupTables: w.lookupTableResolver}
+}
+
+func (g *GRPCClient) txSigner() txSigner {
+	return txSigner{privateKey: g.privateKey, keyring: g.keyring, policy: g.signingPolicy, lookupTables: g.lookupTableResolver}
+}
+
+func (h *HTTPClient) txSigner() txSigner {
+	return txSigner{privateKey: h.privateKey, keyring: h.keyring, policy: h.signingPolicy, lookupTables: h.lookupTableResolver}
+}
+
+func (s txSigner) sign(ctx context.Context, txBase64 string) (string, error) {
+	if s.policy == nil {
+		return transaction.SignTxWithKeyring(txBase64, s.keyring)
+	}
+
+	tx, err := transaction.DecodeTransaction(txBase64)
+	if err != nil {
+		return "", err
+	}
+	// without a Solana RPC endpoint the policy is checked without the accounts loaded from lookup tables
+	lookupTables, err := s.resolveLookupTables(ctx, tx)
+	if err != nil && !errors.Is(err, transaction.ErrLookupTablesRequired) {
+		return "", err
+	}
+
+	return transaction.SignTxWithKeyringAndPolicy(txBase64, s.keyring, *s.policy, lookupTables)
+}
+
+// resolveLookupTables returns the address lookup tables the transaction references, failing with
+// transaction.ErrLookupTablesRequired if it references any and no Solana RPC endpoint is configured
+func (s txSigner) resolveLookupTables(ctx context.Context, tx *solana.Transaction) (map[solana.PublicKey]solana.PublicKeySlice, error) {
+	tableIDs := tx.Message.GetAddressTableLookups().GetTableIDs()
+	if len(tableIDs) == 0 {
+		return nil, nil
+	}
+	return s.lookupTables(ctx, tableIDs)
+}
+
+// owner returns the key that pays for and signs transactions the client builds itself: opts.Owner if set, otherwise
+// the default private key
+func (s txSigner) owner(opts SubmitOpts) (solana.PrivateKey, error) {
+	if opts.Owner == nil {
+		if s.privateKey == nil {
+			return nil, ErrPrivateKeyNotFound
+		}
+		return *s.privateKey, nil
+	}
+
+	privateKey, ok := s.keyring.Get(*opts.Owner)
+	if !ok {
+		return nil, fmt.Errorf("%w: no key for owner %v", ErrPrivateKeyNotFound, *opts.Owner)
+	}
+	return privateKey, nil
+}
+
+// withOwner sets opts.Owner from the owner address of a request, unless it's already set
+func withOwner(opts SubmitOpts, ownerAddress string) (SubmitOpts, error) {
+	if opts.Owner != nil || ownerAddress == "" {
+		return opts, nil
+	}
+
+	owner, err := solana.PublicKeyFromBase58(ownerAddress)
+	if err != nil {
+		return opts, fmt.Errorf("invalid owner address %v: %w", ownerAddress, err)
+	}
+	opts.Owner = &owner
+	return opts, nil
+}
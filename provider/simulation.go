@@ -0,0 +1,257 @@
+package provider
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+	solanarpc "github.com/gagliardetto/solana-go/rpc"
+	log "github.com/sirupsen/logrus"
+)
+
+// token account layout: mint (32 bytes), owner (32 bytes), amount (8 bytes), ...
+const (
+	tokenAccountAmountOffset = 64
+	tokenAccountMinSize      = 165
+)
+
+var ErrNoSimulator = errors.New("dry run requires RPCOpts.Simulator or RPCOpts.SolanaRPCEndpoint")
+
+// BalanceChange is the change of an account's balance in a simulated transaction. Token fields are only set for
+// token accounts.
+type BalanceChange struct {
+	Account      solana.PublicKey
+	PreLamports  uint64
+	PostLamports uint64
+
+	Mint            *solana.PublicKey
+	PreTokenAmount  uint64
+	PostTokenAmount uint64
+}
+
+// SimulationResult is the outcome of a dry-run transaction
+type SimulationResult struct {
+	Signature      solana.Signature
+	Logs           []string
+	ComputeUnits   uint64
+	BalanceChanges []BalanceChange
+
+	// Err is the transaction error reported by the simulation, empty if the transaction would have succeeded
+	Err string
+}
+
+// Simulator simulates signed transactions. NewRPCSimulator can target any Solana RPC node, including a local test
+// validator standing in for mainnet.
+type Simulator interface {
+	Simulate(ctx context.Context, tx *solana.Transaction) (*SimulationResult, error)
+}
+
+// SimulatorFunc adapts a function to the Simulator interface
+type SimulatorFunc func(ctx context.Context, tx *solana.Transaction) (*SimulationResult, error)
+
+func (f SimulatorFunc) Simulate(ctx context.Context, tx *solana.Transaction) (*SimulationResult, error) {
+	return f(ctx, tx)
+}
+
+type rpcSimulator struct {
+	client       *solanarpc.Client
+	lookupTables lookupTableResolver
+}
+
+// NewRPCSimulator simulates transactions with simulateTransaction against the provided Solana RPC endpoint
+func NewRPCSimulator(endpoint string) Simulator {
+	return &rpcSimulator{
+		client:       solanarpc.New(endpoint),
+		lookupTables: newLookupTableResolver(endpoint),
+	}
+}
+
+func (s *rpcSimulator) Simulate(ctx context.Context, tx *solana.Transaction) (*SimulationResult, error) {
+	var lookupTables map[solana.PublicKey]solana.PublicKeySlice
+	if tableIDs := tx.Message.GetAddressTableLookups().GetTableIDs(); len(tableIDs) != 0 {
+		var err error
+		lookupTables, err = s.lookupTables(ctx, tableIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	accounts, err := transaction.WritableAccounts(tx, lookupTables)
+	if err != nil {
+		return nil, err
+	}
+
+	pre, err := s.client.GetMultipleAccountsWithOpts(ctx, accounts, &solanarpc.GetMultipleAccountsOpts{
+		Encoding:   solana.EncodingBase64,
+		Commitment: solanarpc.CommitmentProcessed,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve accounts: %w", err)
+	}
+
+	response, err := s.client.SimulateTransactionWithOpts(ctx, tx, &solanarpc.SimulateTransactionOpts{
+		ReplaceRecentBlockhash: true,
+		Commitment:             solanarpc.CommitmentProcessed,
+		Accounts: &solanarpc.SimulateTransactionAccountsOpts{
+			Encoding:  solana.EncodingBase64,
+			Addresses: accounts,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not simulate transaction: %w", err)
+	}
+	if response.Value == nil {
+		return nil, errors.New("empty simulation response")
+	}
+
+	result := &SimulationResult{Logs: response.Value.Logs}
+	if len(tx.Signatures) != 0 {
+		result.Signature = tx.Signatures[0]
+	}
+	if response.Value.UnitsConsumed != nil {
+		result.ComputeUnits = *response.Value.UnitsConsumed
+	}
+	if response.Value.Err != nil {
+		result.Err = fmt.Sprint(response.Value.Err)
+		return result, nil
+	}
+
+	for i, account := range accounts {
+		var preAccount, postAccount *solanarpc.Account
+		if i < len(pre.Value) {
+			preAccount = pre.Value[i]
+		}
+		if i < len(response.Value.Accounts) {
+			postAccount = response.Value.Accounts[i]
+		}
+		if change, ok := balanceChange(account, preAccount, postAccount); ok {
+			result.BalanceChanges = append(result.BalanceChanges, change)
+		}
+	}
+	return result, nil
+}
+
+// balanceChange compares the lamports and token amounts of an account before and after simulation
+func balanceChange(key solana.PublicKey, pre *solanarpc.Account, post *solanarpc.Account) (BalanceChange, bool) {
+	change := BalanceChange{Account: key}
+	var preData, postData []byte
+	if pre != nil {
+		change.PreLamports = pre.Lamports
+		preData = accountData(pre)
+	}
+	if post != nil {
+		change.PostLamports = post.Lamports
+		postData = accountData(post)
+	}
+
+	if isTokenAccount(pre) || isTokenAccount(post) {
+		data := postData
+		if len(data) < tokenAccountMinSize {
+			data = preData
+		}
+		if len(data) >= tokenAccountMinSize {
+			mint := solana.PublicKeyFromBytes(data[:32])
+			change.Mint = &mint
+		}
+		change.PreTokenAmount = tokenAmount(preData)
+		change.PostTokenAmount = tokenAmount(postData)
+	}
+
+	changed := change.PreLamports != change.PostLamports || change.PreTokenAmount != change.PostTokenAmount
+	return change, changed
+}
+
+func accountData(account *solanarpc.Account) []byte {
+	if account.Data == nil {
+		return nil
+	}
+	return account.Data.GetBinary()
+}
+
+func isTokenAccount(account *solanarpc.Account) bool {
+	return account != nil && (account.Owner.Equals(solana.TokenProgramID) || account.Owner.Equals(transaction.Token2022ProgramID))
+}
+
+func tokenAmount(data []byte) uint64 {
+	if len(data) < tokenAccountMinSize {
+		return 0
+	}
+	return binary.LittleEndian.Uint64(data[tokenAccountAmountOffset:])
+}
+
+// dryRunner simulates signed transactions in place of submitting them
+type dryRunner struct {
+	enabled   bool
+	simulator Simulator
+	handler   func(*SimulationResult)
+}
+
+func newDryRunner(opts RPCOpts) dryRunner {
+	simulator := opts.Simulator
+	if simulator == nil && opts.SolanaRPCEndpoint != "" {
+		simulator = NewRPCSimulator(opts.SolanaRPCEndpoint)
+	}
+	return dryRunner{
+		enabled:   opts.DryRun,
+		simulator: simulator,
+		handler:   opts.OnSimulation,
+	}
+}
+
+func (d dryRunner) active(opts SubmitOpts) bool {
+	return d.enabled || opts.DryRun
+}
+
+// simulate runs the signed transaction through the simulator, returning an error if it would have failed
+func (d dryRunner) simulate(ctx context.Context, signedTxBase64 string) (*SimulationResult, error) {
+	if d.simulator == nil {
+		return nil, ErrNoSimulator
+	}
+
+	tx, err := transaction.DecodeTransaction(signedTxBase64)
+	if err != nil {
+		return nil, err
+	}
+	result, err := d.simulator.Simulate(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debugf("dry run of transaction %v consumed %v compute units", result.Signature, result.ComputeUnits)
+	if d.handler != nil {
+		d.handler(result)
+	}
+	return result, nil
+}
+
+// simulateSignature simulates a transaction like simulate, in place of a submission returning its signature
+func (d dryRunner) simulateSignature(ctx context.Context, signedTxBase64 string) (string, error) {
+	result, err := d.simulate(ctx, signedTxBase64)
+	if err != nil {
+		return "", err
+	}
+	if result.Err != "" {
+		return result.Signature.String(), fmt.Errorf("simulated transaction failed: %v", result.Err)
+	}
+	return result.Signature.String(), nil
+}
+
+// simulateBatch simulates every transaction of a signed batch request, reporting none of them as submitted
+func (d dryRunner) simulateBatch(ctx context.Context, request *pb.PostSubmitBatchRequest) (*pb.PostSubmitBatchResponse, error) {
+	response := &pb.PostSubmitBatchResponse{}
+	for _, entry := range request.Entries {
+		result, err := d.simulate(ctx, entry.Transaction.Content)
+		if err != nil {
+			return nil, err
+		}
+		response.Transactions = append(response.Transactions, &pb.PostSubmitBatchResponseEntry{
+			Signature: result.Signature.String(),
+			Error:     result.Err,
+			Submitted: false,
+		})
+	}
+	return response, nil
+}
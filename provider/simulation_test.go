@@ -0,0 +1,130 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/stretchr/testify/require"
+)
+
+// failingSimulator reports transactions transferring failLamports as failed and all others as successful
+func failingSimulator(failLamports uint64) SimulatorFunc {
+	return func(_ context.Context, tx *solana.Transaction) (*SimulationResult, error) {
+		result := &SimulationResult{Signature: tx.Signatures[0], ComputeUnits: 150}
+		instruction, err := system.DecodeInstruction(nil, tx.Message.Instructions[0].Data)
+		if err != nil {
+			return nil, err
+		}
+		if *instruction.Impl.(*system.Transfer).Lamports == failLamports {
+			result.Err = "insufficient funds"
+		}
+		return result, nil
+	}
+}
+
+func unsignedTransfer(t *testing.T, payer solana.PublicKey, lamports uint64) string {
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		system.NewTransferInstruction(lamports, payer, solana.NewWallet().PublicKey()).Build(),
+	}, solana.Hash{1}, solana.TransactionPayer(payer))
+	require.NoError(t, err)
+	txBase64, err := tx.ToBase64()
+	require.NoError(t, err)
+	return txBase64
+}
+
+func TestDryRunner(t *testing.T) {
+	ctx := context.Background()
+	key := solana.NewWallet().PrivateKey
+	signer := txSigner{privateKey: &key, keyring: newKeyring(RPCOpts{PrivateKey: &key})}
+	sign := func(lamports uint64) string {
+		signed, err := signer.sign(ctx, unsignedTransfer(t, key.PublicKey(), lamports))
+		require.NoError(t, err)
+		return signed
+	}
+
+	require.False(t, dryRunner{}.active(SubmitOpts{}))
+	require.True(t, dryRunner{}.active(SubmitOpts{DryRun: true}))
+	require.True(t, dryRunner{enabled: true}.active(SubmitOpts{}))
+
+	_, err := dryRunner{}.simulate(ctx, sign(1))
+	require.ErrorIs(t, err, ErrNoSimulator)
+
+	var handled []*SimulationResult
+	d := dryRunner{simulator: failingSimulator(2), handler: func(result *SimulationResult) {
+		handled = append(handled, result)
+	}}
+
+	result, err := d.simulate(ctx, sign(1))
+	require.NoError(t, err)
+	require.Equal(t, uint64(150), result.ComputeUnits)
+	require.Empty(t, result.Err)
+	require.Equal(t, []*SimulationResult{result}, handled)
+
+	signature, err := d.simulateSignature(ctx, sign(1))
+	require.NoError(t, err)
+	require.NotEmpty(t, signature)
+
+	signed := sign(2)
+	signature, err = d.simulateSignature(ctx, signed)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "insufficient funds")
+	tx, err := transaction.DecodeTransaction(signed)
+	require.NoError(t, err)
+	require.Equal(t, tx.Signatures[0].String(), signature)
+
+	response, err := d.simulateBatch(ctx, &pb.PostSubmitBatchRequest{Entries: []*pb.PostSubmitRequestEntry{
+		{Transaction: &pb.TransactionMessage{Content: sign(1)}},
+		{Transaction: &pb.TransactionMessage{Content: signed}},
+	}})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(response.Transactions))
+	require.False(t, response.Transactions[0].Submitted)
+	require.Empty(t, response.Transactions[0].Error)
+	require.False(t, response.Transactions[1].Submitted)
+	require.Equal(t, "insufficient funds", response.Transactions[1].Error)
+	require.Equal(t, signature, response.Transactions[1].Signature)
+	require.Equal(t, 5, len(handled))
+
+	simulatorErr := errors.New("simulator unavailable")
+	_, err = dryRunner{simulator: SimulatorFunc(func(_ context.Context, _ *solana.Transaction) (*SimulationResult, error) {
+		return nil, simulatorErr
+	})}.simulateBatch(ctx, &pb.PostSubmitBatchRequest{Entries: []*pb.PostSubmitRequestEntry{
+		{Transaction: &pb.TransactionMessage{Content: sign(1)}},
+	}})
+	require.ErrorIs(t, err, simulatorErr)
+}
+
+func TestClientDryRun(t *testing.T) {
+	ctx := context.Background()
+	key := solana.NewWallet().PrivateKey
+	tx := &pb.TransactionMessage{Content: unsignedTransfer(t, key.PublicKey(), 1)}
+
+	// no endpoint is configured, so anything that isn't simulated fails to submit
+	client := NewHTTPClientWithOpts(nil, RPCOpts{PrivateKey: &key, Simulator: failingSimulator(2)})
+
+	result, err := client.SignAndSimulate(ctx, tx)
+	require.NoError(t, err)
+	require.False(t, result.Signature.IsZero())
+
+	response, err := client.SignAndSubmitBatch(ctx, []*pb.TransactionMessage{tx}, false, SubmitOpts{DryRun: true})
+	require.NoError(t, err)
+	require.Equal(t, result.Signature.String(), response.Transactions[0].Signature)
+	require.False(t, response.Transactions[0].Submitted)
+
+	_, err = client.SignAndSubmit(ctx, tx, true, false, false)
+	require.Error(t, err)
+
+	client = NewHTTPClientWithOpts(nil, RPCOpts{PrivateKey: &key, Simulator: failingSimulator(2), DryRun: true})
+	signature, err := client.SignAndSubmit(ctx, tx, true, false, false)
+	require.NoError(t, err)
+	require.Equal(t, result.Signature.String(), signature)
+
+	_, err = client.SignAndSubmit(ctx, &pb.TransactionMessage{Content: unsignedTransfer(t, key.PublicKey(), 2)}, true, false, false)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "simulated transaction failed")
+}
@@ -0,0 +1,650 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/amm"
+	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	solanarpc "github.com/gagliardetto/solana-go/rpc"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultSniperSlippage         = 10
+	defaultSniperComputeUnits     = 200_000
+	defaultSniperDecisionBuffer   = 1000
+	defaultSniperResubscribeDelay = time.Second
+
+	solDecimals = 9
+)
+
+var ErrMintCheckUnavailable = errors.New("mint authority checks require RPCOpts.SolanaRPCEndpoint")
+
+type mintProvider func(ctx context.Context, mint solana.PublicKey) (*token.Mint, error)
+
+func newMintProvider(endpoint string) mintProvider {
+	if endpoint == "" {
+		return nil
+	}
+
+	client := solanarpc.New(endpoint)
+	return func(ctx context.Context, mint solana.PublicKey) (*token.Mint, error) {
+		// new tokens are checked as soon as they are seen, before they are confirmed
+		account, err := client.GetAccountInfoWithOpts(ctx, mint, &solanarpc.GetAccountInfoOpts{
+			Encoding:   solana.EncodingBase64,
+			Commitment: solanarpc.CommitmentProcessed,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		var m token.Mint
+		if err := m.UnmarshalWithDecoder(bin.NewBinDecoder(account.GetBinary())); err != nil {
+			return nil, fmt.Errorf("could not decode mint %v: %w", mint, err)
+		}
+		return &m, nil
+	}
+}
+
+// SniperSource is the stream a snipe event came from
+type SniperSource int
+
+const (
+	SniperSourceRaydium SniperSource = iota
+	SniperSourcePumpFun
+)
+
+func (s SniperSource) String() string {
+	switch s {
+	case SniperSourceRaydium:
+		return "raydium"
+	case SniperSourcePumpFun:
+		return "pumpfun"
+	default:
+		return "unknown"
+	}
+}
+
+// SnipeEvent is a new Raydium pool or Pump.fun token
+type SnipeEvent struct {
+	Source SniperSource
+	Slot   int64
+	Time   time.Time
+
+	// Mint is the new token, QuoteMint the token it's paired with. Only tokens paired with SOL are sniped.
+	Mint      string
+	QuoteMint string
+
+	// Pool is the Raydium pool or the Pump.fun bonding curve
+	Pool string
+
+	// PoolType is the type of a Raydium pool, e.g. CPMM
+	PoolType string
+
+	Name   string
+	Symbol string
+
+	// Creator of a Pump.fun token. Raydium pools don't report their creator.
+	Creator string
+
+	// Liquidity is the SOL in a Raydium pool. Pump.fun curves start from the same virtual reserves, so their
+	// liquidity is 0.
+	Liquidity float64
+}
+
+// SniperRules are checked, in order of their cost, against every event
+type SniperRules struct {
+	// MinLiquidity in SOL rejects Raydium pools with less SOL
+	MinLiquidity float64
+
+	// RequireMintAuthorityRevoked and RequireFreezeAuthorityRevoked reject tokens whose supply can still be minted or
+	// whose accounts can be frozen. The mint is read from RPCOpts.SolanaRPCEndpoint.
+	RequireMintAuthorityRevoked   bool
+	RequireFreezeAuthorityRevoked bool
+
+	// BlockedCreators rejects Pump.fun tokens created by these addresses
+	BlockedCreators []string
+
+	// NamePatterns, if set, requires the token's name or symbol to match one of them. ExcludedNamePatterns rejects
+	// tokens whose name or symbol matches any of them.
+	NamePatterns         []*regexp.Regexp
+	ExcludedNamePatterns []*regexp.Regexp
+
+	// MaxBuySOL caps the SOL spent on one snipe
+	MaxBuySOL float64
+}
+
+type SniperOpts struct {
+	// Owner pays for and signs the swaps with the client's private key
+	Owner string
+
+	Rules SniperRules
+
+	// Raydium and PumpFun select the streams to snipe. IncludeCPMM also streams new Raydium CPMM pools.
+	Raydium     bool
+	IncludeCPMM bool
+	PumpFun     bool
+
+	// BuySOL is the SOL spent on each accepted event, capped at Rules.MaxBuySOL
+	BuySOL float64
+
+	// Slippage in percent. Defaults to 10.
+	Slippage float64
+
+	// ComputeUnitLimit of the swaps. Defaults to 200,000.
+	ComputeUnitLimit uint32
+
+	// Percentile of recent priority fees used to price the swaps. Defaults to 50.
+	Percentile float64
+
+	// Tip in lamports, if set, is added to the swaps
+	Tip uint64
+
+	// SubmitOpts are used for the swaps of every source. The compute budget, build steps and nonce only apply to
+	// Raydium AMM swaps, which are built locally. SkipPreFlight defaults to true.
+	SubmitOpts SubmitOpts
+
+	// DryRun evaluates and logs events without submitting swaps
+	DryRun bool
+
+	// MaxSnipes stops buying after this many accepted events, if set
+	MaxSnipes int
+
+	// DecisionBuffer is the size of the channel returned by Decisions. Defaults to 1000.
+	DecisionBuffer int
+}
+
+// SnipeDecision is the outcome of an event: whether it passed the rules and, if so, the swap submitted for it
+type SnipeDecision struct {
+	Event    SnipeEvent
+	Accepted bool
+
+	// Reason is the rule that rejected the event
+	Reason string
+
+	BuySOL    float64
+	Signature string
+	Err       error
+
+	// Latency is the time from the event to the swap's submission
+	Latency time.Duration
+}
+
+type newRaydiumPoolsStreamProvider func(ctx context.Context, includeCPMM bool) (connections.Streamer[*pb.GetNewRaydiumPoolsResponse], error)
+type pumpFunNewTokensStreamProvider func(ctx context.Context, request *pb.GetPumpFunNewTokensStreamRequest) (connections.Streamer[*pb.GetPumpFunNewTokensStreamResponse], error)
+
+type sniperAPI struct {
+	newPoolsStream  newRaydiumPoolsStreamProvider
+	newTokensStream pumpFunNewTokensStreamProvider
+	mint            mintProvider
+	composer        swapComposerProvider
+	cpmmSwap        raydiumCPMMSwapBuilder
+	pumpFunSwap     pumpFunSwapBuilder
+	submit          signedBatchSubmitter
+	blockHash       *recentBlockHashStore
+}
+
+// Sniper evaluates new Raydium pools from GetNewRaydiumPoolsStream and new Pump.fun tokens from
+// GetPumpFunNewTokensStream against a set of rules, and buys the tokens that pass them. The recent block hash and
+// priority fees are kept warm from their streams so accepted events are submitted without further requests, and
+// every decision is logged and published on Decisions.
+type Sniper struct {
+	mutex            sync.Mutex
+	api              sniperAPI
+	fees             *priorityFeeStore
+	opts             SniperOpts
+	blockedCreators  map[string]bool
+	sniped           map[string]bool
+	decisions        chan *SnipeDecision
+	resubscribeDelay time.Duration
+}
+
+func newSniper(api sniperAPI, fees *priorityFeeStore, opts SniperOpts) (*Sniper, error) {
+	if opts.Owner == "" {
+		return nil, errors.New("sniper owner is required")
+	}
+	if !opts.Raydium && !opts.PumpFun {
+		return nil, errors.New("sniper needs Raydium or PumpFun events")
+	}
+	if opts.BuySOL <= 0 {
+		return nil, errors.New("sniper buy amount must be positive")
+	}
+	if (opts.Rules.RequireMintAuthorityRevoked || opts.Rules.RequireFreezeAuthorityRevoked) && api.mint == nil {
+		return nil, ErrMintCheckUnavailable
+	}
+	if opts.Rules.MaxBuySOL > 0 {
+		opts.BuySOL = math.Min(opts.BuySOL, opts.Rules.MaxBuySOL)
+	}
+	if opts.Slippage == 0 {
+		opts.Slippage = defaultSniperSlippage
+	}
+	if opts.ComputeUnitLimit == 0 {
+		opts.ComputeUnitLimit = defaultSniperComputeUnits
+	}
+	if opts.Percentile == 0 {
+		opts.Percentile = defaultPriorityFeePercentile
+	}
+	if opts.DecisionBuffer == 0 {
+		opts.DecisionBuffer = defaultSniperDecisionBuffer
+	}
+	if opts.SubmitOpts.SkipPreFlight == nil {
+		// snipes race other buyers, so preflight is skipped unless asked for
+		skipPreFlight := true
+		opts.SubmitOpts.SkipPreFlight = &skipPreFlight
+	}
+
+	blockedCreators := make(map[string]bool, len(opts.Rules.BlockedCreators))
+	for _, creator := range opts.Rules.BlockedCreators {
+		blockedCreators[creator] = true
+	}
+
+	return &Sniper{
+		api:              api,
+		fees:             fees,
+		opts:             opts,
+		blockedCreators:  blockedCreators,
+		sniped:           make(map[string]bool),
+		decisions:        make(chan *SnipeDecision, opts.DecisionBuffer),
+		resubscribeDelay: defaultSniperResubscribeDelay,
+	}, nil
+}
+
+// NewSniper subscribes to new pools and tokens and snipes those passing the rules until ctx is canceled
+func (w *WSClient) NewSniper(ctx context.Context, opts SniperOpts) (*Sniper, error) {
+	s, err := newSniper(sniperAPI{
+		newPoolsStream:  w.GetNewRaydiumPoolsStream,
+		newTokensStream: w.GetPumpFunNewTokensStream,
+		mint:            w.mintProvider,
+		composer:        w.NewSwapComposer,
+		cpmmSwap:        w.PostRaydiumSwapCPMM,
+		pumpFunSwap:     w.PostPumpFunSwap,
+		submit:          w.signAndPostBatch,
+		blockHash:       w.recentBlockHashStore,
+	}, w.priorityFeeStore, opts)
+	if err != nil {
+		return nil, err
+	}
+	return startSniper(ctx, s)
+}
+
+// NewSniper subscribes to new pools and tokens and snipes those passing the rules until ctx is canceled
+func (g *GRPCClient) NewSniper(ctx context.Context, opts SniperOpts) (*Sniper, error) {
+	s, err := newSniper(sniperAPI{
+		newPoolsStream:  g.GetNewRaydiumPoolsStream,
+		newTokensStream: g.GetPumpFunNewTokensStream,
+		mint:            g.mintProvider,
+		composer:        g.NewSwapComposer,
+		cpmmSwap:        g.PostRaydiumSwapCPMM,
+		pumpFunSwap:     g.PostPumpFunSwap,
+		submit:          g.signAndPostBatch,
+		blockHash:       g.recentBlockHashStore,
+	}, g.priorityFeeStore, opts)
+	if err != nil {
+		return nil, err
+	}
+	return startSniper(ctx, s)
+}
+
+func startSniper(ctx context.Context, s *Sniper) (*Sniper, error) {
+	if err := s.warm(ctx); err != nil {
+		return nil, err
+	}
+
+	var pools chan *pb.GetNewRaydiumPoolsResponse
+	var tokens chan *pb.GetPumpFunNewTokensStreamResponse
+	var err error
+	if s.opts.Raydium {
+		pools, err = s.subscribePools(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if s.opts.PumpFun {
+		tokens, err = s.subscribeTokens(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	go s.run(ctx, pools, tokens)
+	return s, nil
+}
+
+// warm starts the block hash stream and subscribes to priority fees, so swaps don't wait on either. Raydium's fees
+// price Pump.fun swaps too.
+func (s *Sniper) warm(ctx context.Context) error {
+	if s.opts.Raydium {
+		s.api.blockHash.warm(ctx)
+	}
+	if _, err := s.fees.get(ctx, pb.Project_P_RAYDIUM, s.opts.Percentile); err != nil {
+		return fmt.Errorf("could not retrieve priority fee: %w", err)
+	}
+	return nil
+}
+
+// Decisions returns a channel on which the decision on every event is published. Decisions are dropped if the
+// channel is full.
+func (s *Sniper) Decisions() <-chan *SnipeDecision {
+	return s.decisions
+}
+
+func (s *Sniper) subscribePools(ctx context.Context) (chan *pb.GetNewRaydiumPoolsResponse, error) {
+	stream, err := s.api.newPoolsStream(ctx, s.opts.IncludeCPMM)
+	if err != nil {
+		return nil, err
+	}
+	return stream.Channel(100), nil
+}
+
+func (s *Sniper) subscribeTokens(ctx context.Context) (chan *pb.GetPumpFunNewTokensStreamResponse, error) {
+	stream, err := s.api.newTokensStream(ctx, &pb.GetPumpFunNewTokensStreamRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return stream.Channel(100), nil
+}
+
+func (s *Sniper) run(ctx context.Context, pools chan *pb.GetNewRaydiumPoolsResponse, tokens chan *pb.GetPumpFunNewTokensStreamResponse) {
+	for {
+		select {
+		case response, ok := <-pools:
+			if !ok {
+				log.Warn("new raydium pools stream closed, resubscribing")
+				pools = resubscribe(ctx, s.resubscribeDelay, "new raydium pools", s.subscribePools)
+				if pools == nil {
+					return
+				}
+				continue
+			}
+			if event, ok := raydiumSnipeEvent(response); ok {
+				go s.handle(ctx, event)
+			}
+		case response, ok := <-tokens:
+			if !ok {
+				log.Warn("pumpfun new tokens stream closed, resubscribing")
+				tokens = resubscribe(ctx, s.resubscribeDelay, "pumpfun new tokens", s.subscribeTokens)
+				if tokens == nil {
+					return
+				}
+				continue
+			}
+			go s.handle(ctx, pumpFunSnipeEvent(response))
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func raydiumSnipeEvent(response *pb.GetNewRaydiumPoolsResponse) (SnipeEvent, bool) {
+	pool := response.Pool
+	if pool == nil {
+		return SnipeEvent{}, false
+	}
+
+	event := SnipeEvent{
+		Source:   SniperSourceRaydium,
+		Slot:     response.Slot,
+		Time:     streamTime(response.Timestamp),
+		Pool:     pool.PoolAddress,
+		PoolType: pool.PoolType,
+	}
+	switch {
+	case isSOLToken(pool.Token1MintAddress):
+		event.Mint = pool.Token2MintAddress
+		event.QuoteMint = pool.Token1MintAddress
+		event.Symbol = pool.Token2MintSymbol
+		event.Liquidity = float64(pool.Token1Reserves) / math.Pow10(solDecimals)
+	case isSOLToken(pool.Token2MintAddress):
+		event.Mint = pool.Token1MintAddress
+		event.QuoteMint = pool.Token2MintAddress
+		event.Symbol = pool.Token1MintSymbol
+		event.Liquidity = float64(pool.Token2Reserves) / math.Pow10(solDecimals)
+	default:
+		// the pool is still evaluated, and rejected, so that every event has a decision
+		event.Mint = pool.Token1MintAddress
+		event.QuoteMint = pool.Token2MintAddress
+		event.Symbol = pool.Token1MintSymbol
+	}
+	return event, true
+}
+
+func pumpFunSnipeEvent(response *pb.GetPumpFunNewTokensStreamResponse) SnipeEvent {
+	return SnipeEvent{
+		Source:    SniperSourcePumpFun,
+		Slot:      response.Slot,
+		Time:      streamTime(response.Timestamp),
+		Mint:      response.Mint,
+		QuoteMint: solana.SolMint.String(),
+		Pool:      response.BondingCurve,
+		Name:      response.Name,
+		Symbol:    response.Symbol,
+		Creator:   response.Creator,
+	}
+}
+
+// handle decides on an event, buys the token if it's accepted and publishes the decision
+func (s *Sniper) handle(ctx context.Context, event SnipeEvent) {
+	decision := s.Evaluate(ctx, event)
+	if decision.Accepted {
+		decision.Reason = s.reserve(event)
+		decision.Accepted = decision.Reason == ""
+	}
+	if !decision.Accepted {
+		decision.BuySOL = 0
+	}
+	if decision.Accepted && !s.opts.DryRun {
+		decision.Signature, decision.Err = s.buy(ctx, event)
+		decision.Latency = time.Since(event.Time)
+		if decision.Err != nil {
+			s.release(event)
+		}
+	}
+	s.publish(decision)
+}
+
+// Evaluate checks an event against the rules, without buying it
+func (s *Sniper) Evaluate(ctx context.Context, event SnipeEvent) *SnipeDecision {
+	decision := &SnipeDecision{Event: event}
+	decision.Reason, decision.Err = s.check(ctx, event)
+	decision.Accepted = decision.Reason == "" && decision.Err == nil
+	if decision.Accepted {
+		decision.BuySOL = s.opts.BuySOL
+	}
+	return decision
+}
+
+// check returns the reason the event is rejected, or an empty reason if it passes the rules. Rules that need no
+// requests are checked first.
+func (s *Sniper) check(ctx context.Context, event SnipeEvent) (string, error) {
+	rules := s.opts.Rules
+
+	if !isSOLToken(event.QuoteMint) {
+		return fmt.Sprintf("token is paired with %v, not SOL", event.QuoteMint), nil
+	}
+	if event.Source == SniperSourceRaydium && event.Liquidity < rules.MinLiquidity {
+		return fmt.Sprintf("liquidity %v SOL is below %v SOL", event.Liquidity, rules.MinLiquidity), nil
+	}
+	if s.blockedCreators[event.Creator] {
+		return fmt.Sprintf("creator %v is blocked", event.Creator), nil
+	}
+	for _, pattern := range rules.ExcludedNamePatterns {
+		if matchesName(pattern, event) {
+			return fmt.Sprintf("name %q or symbol %q matches excluded pattern %v", event.Name, event.Symbol, pattern), nil
+		}
+	}
+	if len(rules.NamePatterns) != 0 {
+		matched := false
+		for _, pattern := range rules.NamePatterns {
+			if matchesName(pattern, event) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Sprintf("name %q or symbol %q matches no pattern", event.Name, event.Symbol), nil
+		}
+	}
+
+	if !rules.RequireMintAuthorityRevoked && !rules.RequireFreezeAuthorityRevoked {
+		return "", nil
+	}
+	mintKey, err := solana.PublicKeyFromBase58(event.Mint)
+	if err != nil {
+		return "invalid mint", err
+	}
+	mint, err := s.api.mint(ctx, mintKey)
+	if err != nil {
+		return "could not read mint", err
+	}
+	if rules.RequireMintAuthorityRevoked && mint.MintAuthority != nil {
+		return fmt.Sprintf("mint authority %v is not revoked", mint.MintAuthority), nil
+	}
+	if rules.RequireFreezeAuthorityRevoked && mint.FreezeAuthority != nil {
+		return fmt.Sprintf("freeze authority %v is not revoked", mint.FreezeAuthority), nil
+	}
+	return "", nil
+}
+
+func matchesName(pattern *regexp.Regexp, event SnipeEvent) bool {
+	return pattern.MatchString(event.Name) || pattern.MatchString(event.Symbol)
+}
+
+// reserve records an accepted event's token, so each token is bought once and at most MaxSnipes tokens are bought,
+// returning the reason the event is rejected otherwise
+func (s *Sniper) reserve(event SnipeEvent) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.sniped[event.Mint] {
+		return "token already sniped"
+	}
+	if s.opts.MaxSnipes > 0 && len(s.sniped) >= s.opts.MaxSnipes {
+		return fmt.Sprintf("reached maximum of %v snipes", s.opts.MaxSnipes)
+	}
+	s.sniped[event.Mint] = true
+	return ""
+}
+
+// release removes the reservation of a token whose buy failed, so it can be retried and doesn't count as a snipe
+func (s *Sniper) release(event SnipeEvent) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.sniped, event.Mint)
+}
+
+// buy submits a swap of BuySOL SOL for the event's token, priced from the warm priority fee store
+func (s *Sniper) buy(ctx context.Context, event SnipeEvent) (string, error) {
+	var tip *uint64
+	if s.opts.Tip > 0 {
+		tip = &s.opts.Tip
+	}
+	computePrice, err := s.fees.get(ctx, pb.Project_P_RAYDIUM, s.opts.Percentile)
+	if err != nil {
+		return "", fmt.Errorf("could not retrieve priority fee: %w", err)
+	}
+
+	var response *pb.PostSubmitBatchResponse
+	switch {
+	case event.Source == SniperSourcePumpFun:
+		request, err := amm.NewBondingCurve(event.Mint, event.Pool).BuySOLWorth(s.opts.Owner, s.opts.BuySOL, s.opts.Slippage)
+		if err != nil {
+			return "", err
+		}
+		request.ComputeLimit = s.opts.ComputeUnitLimit
+		request.ComputePrice = computePrice
+		request.Tip = tip
+		swap, err := s.api.pumpFunSwap(ctx, request)
+		if err != nil {
+			return "", err
+		}
+		response, err = s.api.submit(ctx, []*pb.TransactionMessage{{Content: swap.Transaction.Content}}, false, s.opts.SubmitOpts)
+		if err != nil {
+			return "", err
+		}
+	case strings.EqualFold(event.PoolType, "cpmm"):
+		swap, err := s.api.cpmmSwap(ctx, &pb.PostRaydiumCPMMSwapRequest{
+			OwnerAddress: s.opts.Owner,
+			InToken:      solana.SolMint.String(),
+			OutToken:     event.Mint,
+			InAmount:     s.opts.BuySOL,
+			Slippage:     s.opts.Slippage,
+			PoolAddress:  event.Pool,
+			ComputeLimit: s.opts.ComputeUnitLimit,
+			ComputePrice: computePrice,
+			Tip:          tip,
+		})
+		if err != nil {
+			return "", err
+		}
+		response, err = s.api.submit(ctx, []*pb.TransactionMessage{swap.Transaction}, false, s.opts.SubmitOpts)
+		if err != nil {
+			return "", err
+		}
+	default:
+		composer := s.api.composer(s.opts.Owner)
+		_, err = composer.AddRaydiumSwap(ctx, &pb.PostRaydiumSwapInstructionsRequest{
+			InToken:  solana.SolMint.String(),
+			OutToken: event.Mint,
+			InAmount: s.opts.BuySOL,
+			Slippage: s.opts.Slippage,
+			Tip:      tip,
+		})
+		if err != nil {
+			return "", err
+		}
+
+		opts := s.opts.SubmitOpts
+		if opts.ComputeBudget == nil {
+			opts.ComputeBudget = &ComputeBudgetOpts{ComputeUnitLimit: s.opts.ComputeUnitLimit, Percentile: s.opts.Percentile}
+		}
+		response, err = composer.Submit(ctx, false, opts)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if len(response.Transactions) == 0 {
+		return "", errors.New("no transaction submitted")
+	}
+	// dry runs are simulated instead of submitted, so they only fail if the simulation did
+	entry := response.Transactions[0]
+	if entry.Error != "" || !entry.Submitted && !s.opts.SubmitOpts.DryRun {
+		return "", fmt.Errorf("transaction not submitted: %v", entry.Error)
+	}
+	return entry.Signature, nil
+}
+
+// publish logs the decision and sends it on the decisions channel
+func (s *Sniper) publish(decision *SnipeDecision) {
+	event := decision.Event
+	switch {
+	case decision.Err != nil && decision.Accepted:
+		log.Errorf("snipe %v %v (%v) slot %v: buy of %v SOL failed after %v: %v", event.Source, event.Mint, event.Symbol, event.Slot, decision.BuySOL, decision.Latency, decision.Err)
+	case decision.Err != nil:
+		log.Warnf("snipe %v %v (%v) slot %v: rejected, %v: %v", event.Source, event.Mint, event.Symbol, event.Slot, decision.Reason, decision.Err)
+	case decision.Accepted && s.opts.DryRun:
+		log.Infof("snipe %v %v (%v) slot %v: accepted, dry run buy of %v SOL", event.Source, event.Mint, event.Symbol, event.Slot, decision.BuySOL)
+	case decision.Accepted:
+		log.Infof("snipe %v %v (%v) slot %v: accepted, bought %v SOL in %v: %v", event.Source, event.Mint, event.Symbol, event.Slot, decision.BuySOL, decision.Latency, decision.Signature)
+	default:
+		log.Infof("snipe %v %v (%v) slot %v: rejected, %v", event.Source, event.Mint, event.Symbol, event.Slot, decision.Reason)
+	}
+
+	select {
+	case s.decisions <- decision:
+	default:
+		log.Warnf("sniper decisions channel full, dropping decision on %v", event.Mint)
+	}
+}
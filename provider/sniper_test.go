@@ -0,0 +1,227 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSniperRules(t *testing.T) {
+	creator := solana.NewWallet().PublicKey().String()
+	authority := solana.NewWallet().PublicKey()
+	mints := map[string]*token.Mint{}
+	newMint := func(m *token.Mint) string {
+		mint := solana.NewWallet().PublicKey().String()
+		mints[mint] = m
+		return mint
+	}
+
+	s, err := newSniper(sniperAPI{
+		mint: func(_ context.Context, mint solana.PublicKey) (*token.Mint, error) {
+			return mints[mint.String()], nil
+		},
+	}, nil, SniperOpts{
+		Owner:   solana.NewWallet().PublicKey().String(),
+		Raydium: true,
+		PumpFun: true,
+		BuySOL:  1,
+		Rules: SniperRules{
+			MinLiquidity:                  10,
+			RequireMintAuthorityRevoked:   true,
+			RequireFreezeAuthorityRevoked: true,
+			BlockedCreators:               []string{creator},
+			NamePatterns:                  []*regexp.Regexp{regexp.MustCompile(`(?i)cat`)},
+			ExcludedNamePatterns:          []*regexp.Regexp{regexp.MustCompile(`(?i)scam`)},
+			MaxBuySOL:                     0.5,
+		},
+	})
+	require.NoError(t, err)
+
+	sol := solana.SolMint.String()
+	tests := []struct {
+		name   string
+		event  SnipeEvent
+		reason string
+	}{
+		{
+			name:   "not paired with SOL",
+			event:  SnipeEvent{Source: SniperSourceRaydium, QuoteMint: usdcMint, Liquidity: 100, Symbol: "CAT"},
+			reason: "token is paired with " + usdcMint + ", not SOL",
+		},
+		{
+			name:   "low liquidity",
+			event:  SnipeEvent{Source: SniperSourceRaydium, QuoteMint: sol, Liquidity: 5, Symbol: "CAT"},
+			reason: "liquidity 5 SOL is below 10 SOL",
+		},
+		{
+			name:   "blocked creator",
+			event:  SnipeEvent{Source: SniperSourcePumpFun, QuoteMint: sol, Creator: creator, Symbol: "CAT"},
+			reason: "creator " + creator + " is blocked",
+		},
+		{
+			name:   "excluded name",
+			event:  SnipeEvent{Source: SniperSourcePumpFun, QuoteMint: sol, Name: "Cat Scam", Symbol: "CAT"},
+			reason: `name "Cat Scam" or symbol "CAT" matches excluded pattern (?i)scam`,
+		},
+		{
+			name:   "no matching name",
+			event:  SnipeEvent{Source: SniperSourcePumpFun, QuoteMint: sol, Name: "Dog", Symbol: "DOG"},
+			reason: `name "Dog" or symbol "DOG" matches no pattern`,
+		},
+		{
+			name:   "mint authority",
+			event:  SnipeEvent{Source: SniperSourcePumpFun, QuoteMint: sol, Symbol: "CAT", Mint: newMint(&token.Mint{MintAuthority: &authority})},
+			reason: "mint authority " + authority.String() + " is not revoked",
+		},
+		{
+			name:   "freeze authority",
+			event:  SnipeEvent{Source: SniperSourceRaydium, QuoteMint: sol, Liquidity: 10, Symbol: "CAT", Mint: newMint(&token.Mint{FreezeAuthority: &authority})},
+			reason: "freeze authority " + authority.String() + " is not revoked",
+		},
+		{
+			name:  "accepted",
+			event: SnipeEvent{Source: SniperSourcePumpFun, QuoteMint: sol, Name: "Catcoin", Mint: newMint(&token.Mint{})},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			decision := s.Evaluate(context.Background(), test.event)
+			require.NoError(t, decision.Err)
+			require.Equal(t, test.reason, decision.Reason)
+			require.Equal(t, test.reason == "", decision.Accepted)
+			if decision.Accepted {
+				require.Equal(t, 0.5, decision.BuySOL)
+			}
+		})
+	}
+}
+
+func TestSniperReserve(t *testing.T) {
+	s, err := newSniper(sniperAPI{}, nil, SniperOpts{Owner: "owner", PumpFun: true, BuySOL: 1, MaxSnipes: 2})
+	require.NoError(t, err)
+
+	require.Equal(t, "", s.reserve(SnipeEvent{Mint: "a"}))
+	require.Equal(t, "token already sniped", s.reserve(SnipeEvent{Mint: "a"}))
+	require.Equal(t, "", s.reserve(SnipeEvent{Mint: "b"}))
+	require.Equal(t, "reached maximum of 2 snipes", s.reserve(SnipeEvent{Mint: "c"}))
+
+	_, err = newSniper(sniperAPI{}, nil, SniperOpts{Owner: "owner", PumpFun: true, BuySOL: 1, Rules: SniperRules{RequireMintAuthorityRevoked: true}})
+	require.ErrorIs(t, err, ErrMintCheckUnavailable)
+}
+
+func TestSnipeEvents(t *testing.T) {
+	sol := solana.SolMint.String()
+
+	event, ok := raydiumSnipeEvent(&pb.GetNewRaydiumPoolsResponse{Slot: 7, Pool: &pb.ProjectPool{
+		PoolAddress:       "pool",
+		PoolType:          "cpmm",
+		Token1MintAddress: "token",
+		Token1MintSymbol:  "TKN",
+		Token1Reserves:    1_000_000,
+		Token2MintAddress: sol,
+		Token2Reserves:    25_000_000_000,
+	}})
+	require.True(t, ok)
+	require.Equal(t, SniperSourceRaydium, event.Source)
+	require.Equal(t, int64(7), event.Slot)
+	require.Equal(t, "token", event.Mint)
+	require.Equal(t, sol, event.QuoteMint)
+	require.Equal(t, "TKN", event.Symbol)
+	require.Equal(t, 25.0, event.Liquidity)
+	require.Equal(t, "cpmm", event.PoolType)
+
+	event, ok = raydiumSnipeEvent(&pb.GetNewRaydiumPoolsResponse{Pool: &pb.ProjectPool{
+		Token1MintAddress: sol,
+		Token1Reserves:    3_000_000_000,
+		Token2MintAddress: "token",
+	}})
+	require.True(t, ok)
+	require.Equal(t, "token", event.Mint)
+	require.Equal(t, 3.0, event.Liquidity)
+
+	// pools not paired with SOL are still reported, so they get a decision
+	event, ok = raydiumSnipeEvent(&pb.GetNewRaydiumPoolsResponse{Pool: &pb.ProjectPool{
+		Token1MintAddress: "token",
+		Token2MintAddress: usdcMint,
+	}})
+	require.True(t, ok)
+	require.Equal(t, usdcMint, event.QuoteMint)
+	require.Equal(t, 0.0, event.Liquidity)
+
+	_, ok = raydiumSnipeEvent(&pb.GetNewRaydiumPoolsResponse{})
+	require.False(t, ok)
+
+	event = pumpFunSnipeEvent(&pb.GetPumpFunNewTokensStreamResponse{
+		Slot:         9,
+		Mint:         "token",
+		BondingCurve: "curve",
+		Name:         "Token",
+		Symbol:       "TKN",
+		Creator:      "creator",
+	})
+	require.Equal(t, SnipeEvent{
+		Source:    SniperSourcePumpFun,
+		Slot:      9,
+		Time:      event.Time,
+		Mint:      "token",
+		QuoteMint: sol,
+		Pool:      "curve",
+		Name:      "Token",
+		Symbol:    "TKN",
+		Creator:   "creator",
+	}, event)
+}
+
+func TestSniperBuy(t *testing.T) {
+	var (
+		submitOpts []SubmitOpts
+		submitErr  error
+	)
+	s, err := newSniper(sniperAPI{
+		pumpFunSwap: func(_ context.Context, _ *pb.PostPumpFunSwapRequest) (*pb.PostPumpFunSwapResponse, error) {
+			return &pb.PostPumpFunSwapResponse{Transaction: &pb.TransactionMessageV2{Content: "pumpfun"}}, nil
+		},
+		cpmmSwap: func(_ context.Context, _ *pb.PostRaydiumCPMMSwapRequest) (*pb.PostRaydiumCPMMSwapResponse, error) {
+			return &pb.PostRaydiumCPMMSwapResponse{Transaction: &pb.TransactionMessage{Content: "cpmm"}}, nil
+		},
+		submit: func(_ context.Context, transactions []*pb.TransactionMessage, _ bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+			submitOpts = append(submitOpts, opts)
+			if submitErr != nil {
+				return nil, submitErr
+			}
+			// dry runs report the simulated transaction as not submitted
+			return &pb.PostSubmitBatchResponse{Transactions: []*pb.PostSubmitBatchResponseEntry{
+				{Signature: "signature " + transactions[0].Content, Submitted: !opts.DryRun},
+			}}, nil
+		},
+	}, fixedPriorityFees(1000), SniperOpts{Owner: solana.NewWallet().PublicKey().String(), Raydium: true, PumpFun: true, BuySOL: 1, MaxSnipes: 1, SubmitOpts: SubmitOpts{DryRun: true}})
+	require.NoError(t, err)
+
+	pumpFun := SnipeEvent{Source: SniperSourcePumpFun, QuoteMint: solana.SolMint.String(), Mint: solana.NewWallet().PublicKey().String(), Pool: solana.NewWallet().PublicKey().String()}
+	signature, err := s.buy(context.Background(), pumpFun)
+	require.NoError(t, err)
+	require.Equal(t, "signature pumpfun", signature)
+
+	cpmm := SnipeEvent{Source: SniperSourceRaydium, QuoteMint: solana.SolMint.String(), Mint: "token", Pool: "pool", PoolType: "cpmm"}
+	signature, err = s.buy(context.Background(), cpmm)
+	require.NoError(t, err)
+	require.Equal(t, "signature cpmm", signature)
+	for _, opts := range submitOpts {
+		require.True(t, opts.DryRun)
+		require.True(t, *opts.SkipPreFlight)
+	}
+
+	// a failed buy releases its reservation, so the token can be retried and doesn't use up a snipe
+	submitErr = errors.New("submit failed")
+	s.handle(context.Background(), cpmm)
+	decision := <-s.Decisions()
+	require.True(t, decision.Accepted)
+	require.ErrorIs(t, decision.Err, submitErr)
+	require.Equal(t, "", s.reserve(cpmm))
+}
@@ -0,0 +1,193 @@
+package provider
+
+import (
+	"context"
+	"errors"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
+	"github.com/bloXroute-Labs/solana-trader-client-go/utils"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+)
+
+// defaultSwapComputeUnits is assumed for swap instructions that don't set a compute unit limit
+const defaultSwapComputeUnits = 200_000
+
+type jupiterInstructionsProvider func(ctx context.Context, request *pb.PostJupiterSwapInstructionsRequest) (*pb.PostJupiterSwapInstructionsResponse, error)
+type raydiumInstructionsProvider func(ctx context.Context, request *pb.PostRaydiumSwapInstructionsRequest) (*pb.PostRaydiumSwapInstructionsResponse, error)
+type instructionTxBuildFunc func(ctx context.Context, instructions []solana.Instruction, addressLookupTables map[solana.PublicKey]solana.PublicKeySlice, project pb.Project, opts SubmitOpts) (*pb.TransactionMessage, error)
+
+type swapComposerAPI struct {
+	jupiterInstructions jupiterInstructionsProvider
+	raydiumInstructions raydiumInstructionsProvider
+	build               instructionTxBuildFunc
+	submit              signedBatchSubmitter
+}
+
+// SwapComposer combines the instructions of several swaps, and any other instructions, into one v0 transaction so
+// the swaps land atomically. The compute budget instructions of each swap are replaced by one for the whole
+// transaction, repeated associated token account creations are dropped and the swaps' address lookup tables merged.
+type SwapComposer struct {
+	api                 swapComposerAPI
+	owner               string
+	project             pb.Project
+	instructions        []solana.Instruction
+	addressLookupTables []map[solana.PublicKey]solana.PublicKeySlice
+	computeUnits        uint32
+}
+
+func newSwapComposer(api swapComposerAPI, owner string) *SwapComposer {
+	return &SwapComposer{api: api, owner: owner}
+}
+
+// NewSwapComposer creates a composer of swaps paid for and signed by the owner
+func (w *WSClient) NewSwapComposer(owner string) *SwapComposer {
+	return newSwapComposer(swapComposerAPI{
+		jupiterInstructions: w.PostJupiterSwapInstructions,
+		raydiumInstructions: w.PostRaydiumSwapInstructions,
+		build:               w.instructionTxBuilder().build,
+		submit:              w.signAndPostBatch,
+	}, owner)
+}
+
+// NewSwapComposer creates a composer of swaps paid for and signed by the owner
+func (g *GRPCClient) NewSwapComposer(owner string) *SwapComposer {
+	return newSwapComposer(swapComposerAPI{
+		jupiterInstructions: g.PostJupiterSwapInstructions,
+		raydiumInstructions: g.PostRaydiumSwapInstructions,
+		build:               g.instructionTxBuilder().build,
+		submit:              g.signAndPostBatch,
+	}, owner)
+}
+
+// NewSwapComposer creates a composer of swaps paid for and signed by the owner
+func (h *HTTPClient) NewSwapComposer(owner string) *SwapComposer {
+	return newSwapComposer(swapComposerAPI{
+		jupiterInstructions: h.PostJupiterSwapInstructions,
+		raydiumInstructions: h.PostRaydiumSwapInstructions,
+		build:               h.instructionTxBuilder().build,
+		submit:              h.signAndPostBatch,
+	}, owner)
+}
+
+// AddJupiterSwap requests the instructions of a Jupiter swap and adds them. The owner defaults to the composer's.
+func (c *SwapComposer) AddJupiterSwap(ctx context.Context, request *pb.PostJupiterSwapInstructionsRequest) (*pb.PostJupiterSwapInstructionsResponse, error) {
+	if request.OwnerAddress == "" {
+		request.OwnerAddress = c.owner
+	}
+	response, err := c.api.jupiterInstructions(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return response, c.AddJupiterInstructions(response)
+}
+
+// AddRaydiumSwap requests the instructions of a Raydium swap and adds them. The owner defaults to the composer's.
+func (c *SwapComposer) AddRaydiumSwap(ctx context.Context, request *pb.PostRaydiumSwapInstructionsRequest) (*pb.PostRaydiumSwapInstructionsResponse, error) {
+	if request.OwnerAddress == "" {
+		request.OwnerAddress = c.owner
+	}
+	response, err := c.api.raydiumInstructions(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return response, c.AddRaydiumInstructions(response)
+}
+
+// AddJupiterInstructions adds the instructions of a PostJupiterSwapInstructions response
+func (c *SwapComposer) AddJupiterInstructions(response *pb.PostJupiterSwapInstructionsResponse) error {
+	addressLookupTables, err := utils.ConvertProtoAddressLookupTable(response.AddressLookupTableAddresses)
+	if err != nil {
+		return err
+	}
+	instructions, err := utils.ConvertJupiterInstructions(response.Instructions)
+	if err != nil {
+		return err
+	}
+	return c.addSwap(pb.Project_P_JUPITER, instructions, addressLookupTables)
+}
+
+// AddRaydiumInstructions adds the instructions of a PostRaydiumSwapInstructions response
+func (c *SwapComposer) AddRaydiumInstructions(response *pb.PostRaydiumSwapInstructionsResponse) error {
+	instructions, err := utils.ConvertRaydiumInstructions(response.Instructions)
+	if err != nil {
+		return err
+	}
+	return c.addSwap(pb.Project_P_RAYDIUM, instructions, nil)
+}
+
+// AddInstructions appends user instructions after the swaps added so far. Their compute units are assumed to be
+// covered by the swaps' compute unit limit.
+func (c *SwapComposer) AddInstructions(instructions ...solana.Instruction) *SwapComposer {
+	c.instructions = append(c.instructions, transaction.RemoveComputeBudgetInstructions(instructions)...)
+	return c
+}
+
+func (c *SwapComposer) addSwap(project pb.Project, instructions []solana.Instruction, addressLookupTables map[solana.PublicKey]solana.PublicKeySlice) error {
+	limit, ok, err := transaction.ComputeUnitLimit(instructions)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		limit = defaultSwapComputeUnits
+	}
+
+	// priority fees are selected for the project of the first swap
+	if c.project == pb.Project_P_UNKNOWN {
+		c.project = project
+	}
+	c.computeUnits += limit
+	c.instructions = append(c.instructions, transaction.RemoveComputeBudgetInstructions(instructions)...)
+	if len(addressLookupTables) != 0 {
+		c.addressLookupTables = append(c.addressLookupTables, addressLookupTables)
+	}
+	return nil
+}
+
+// Build assembles the transaction for SignAndSubmitBatch to sign. Unless opts.ComputeBudget is set, the compute unit
+// limit is the sum of the swaps' limits, priced at the median recent priority fee. The transaction is checked
+// against the size and account limits.
+func (c *SwapComposer) Build(ctx context.Context, opts SubmitOpts) (*pb.TransactionMessage, error) {
+	if len(c.instructions) == 0 {
+		return nil, errors.New("no swaps to compose")
+	}
+
+	opts, err := withOwner(opts, c.owner)
+	if err != nil {
+		return nil, err
+	}
+
+	instructions, err := transaction.DedupeInstructions(c.instructions)
+	if err != nil {
+		return nil, err
+	}
+	addressLookupTables, err := transaction.MergeAddressLookupTables(c.addressLookupTables...)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.ComputeBudget == nil {
+		opts.ComputeBudget = &ComputeBudgetOpts{ComputeUnitLimit: min(c.computeUnits, transaction.MaxComputeUnitLimit)}
+	}
+	opts.BuildSteps = append([]transaction.BuildStep{transaction.VersionedStep()}, opts.BuildSteps...)
+
+	project := c.project
+	if project == pb.Project_P_UNKNOWN {
+		project = pb.Project_P_RAYDIUM
+	}
+	return c.api.build(ctx, instructions, addressLookupTables, project, opts)
+}
+
+// Submit builds the transaction, signs it with the owner's key and submits it
+func (c *SwapComposer) Submit(ctx context.Context, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	opts, err := withOwner(opts, c.owner)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := c.Build(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return c.api.submit(ctx, []*pb.TransactionMessage{tx}, useBundle, opts)
+}
@@ -0,0 +1,70 @@
+package provider
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
+	pb "github.com/bloXroute-Labs/solana-trader-proto/api"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSwapComposerSubmitDefaultOpts(t *testing.T) {
+	ctx := context.Background()
+	owner := solana.NewWallet().PrivateKey
+	signer := txSigner{privateKey: &owner, keyring: transaction.NewKeyring(owner)}
+
+	var built SubmitOpts
+	var posted *pb.PostSubmitBatchRequest
+	composer := newSwapComposer(swapComposerAPI{
+		raydiumInstructions: func(_ context.Context, request *pb.PostRaydiumSwapInstructionsRequest) (*pb.PostRaydiumSwapInstructionsResponse, error) {
+			require.Equal(t, owner.PublicKey().String(), request.OwnerAddress)
+			return &pb.PostRaydiumSwapInstructionsResponse{Instructions: []*pb.InstructionRaydium{{
+				ProgramID: solana.SystemProgramID.String(),
+				Accounts: []*pb.AccountMeta{
+					{ProgramID: owner.PublicKey().String(), IsWritable: true, IsSigner: true},
+					{ProgramID: solana.NewWallet().PublicKey().String(), IsWritable: true},
+				},
+				Data: []byte{2, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0},
+			}}}, nil
+		},
+		build: func(ctx context.Context, instructions []solana.Instruction, _ map[solana.PublicKey]solana.PublicKeySlice, _ pb.Project, opts SubmitOpts) (*pb.TransactionMessage, error) {
+			built = opts
+			tx, err := transaction.NewTxBuilder(owner.PublicKey()).AddInstructions(instructions...).Build(ctx, solana.Hash{})
+			if err != nil {
+				return nil, err
+			}
+			content, err := tx.ToBase64()
+			if err != nil {
+				return nil, err
+			}
+			return &pb.TransactionMessage{Content: content}, nil
+		},
+		submit: func(ctx context.Context, transactions []*pb.TransactionMessage, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+			return signAndPostBatch(ctx, transactions, signer, dryRunner{}, func(_ context.Context, request *pb.PostSubmitBatchRequest) (*pb.PostSubmitBatchResponse, error) {
+				posted = request
+				return &pb.PostSubmitBatchResponse{Transactions: []*pb.PostSubmitBatchResponseEntry{{Submitted: true}}}, nil
+			}, useBundle, opts)
+		},
+	}, owner.PublicKey().String())
+
+	_, err := composer.AddRaydiumSwap(ctx, &pb.PostRaydiumSwapInstructionsRequest{})
+	require.NoError(t, err)
+	composer.AddInstructions(system.NewTransferInstruction(1, owner.PublicKey(), solana.NewWallet().PublicKey()).Build())
+
+	// a single composed transaction is still submitted as a batch, honouring useBundle with default options
+	_, err = composer.Submit(ctx, true, SubmitOpts{})
+	require.NoError(t, err)
+	require.NotNil(t, posted)
+	require.True(t, posted.GetUseBundle())
+	require.Equal(t, 1, len(posted.Entries))
+	require.True(t, posted.Entries[0].SkipPreFlight)
+	require.Equal(t, owner.PublicKey(), *built.Owner)
+	require.Equal(t, uint32(defaultSwapComputeUnits), built.ComputeBudget.ComputeUnitLimit)
+
+	tx, err := transaction.DecodeTransaction(posted.Entries[0].Transaction.Content)
+	require.NoError(t, err)
+	require.NoError(t, tx.VerifySignatures())
+}
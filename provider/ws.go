@@ -3,7 +3,6 @@ package provider
 import (
 	"context"
 	"errors"
-	"fmt"
 
 	"github.com/bloXroute-Labs/solana-trader-client-go/connections"
 	"github.com/bloXroute-Labs/solana-trader-client-go/transaction"
@@ -19,7 +18,16 @@ type WSClient struct {
 	addr                 string
 	conn                 *connections.WS
 	privateKey           *solana.PrivateKey
+	keyring              *transaction.Keyring
 	recentBlockHashStore *recentBlockHashStore
+	priorityFeeStore     *priorityFeeStore
+	bundleTipStore       *bundleTipStore
+	computeUnitEstimator computeUnitEstimator
+	signingPolicy        *transaction.Policy
+	lookupTableResolver  lookupTableResolver
+	dryRun               dryRunner
+	nonceProvider        nonceProvider
+	mintProvider         mintProvider
 }
 
 // NewWSClient connects to Mainnet Trader API
@@ -61,9 +69,16 @@ func NewWSClientWithOpts(opts RPCOpts) (*WSClient, error) {
 	}
 
 	client := &WSClient{
-		addr:       opts.Endpoint,
-		conn:       conn,
-		privateKey: opts.PrivateKey,
+		addr:                 opts.Endpoint,
+		conn:                 conn,
+		privateKey:           opts.PrivateKey,
+		keyring:              newKeyring(opts),
+		computeUnitEstimator: newComputeUnitEstimator(opts.SolanaRPCEndpoint),
+		signingPolicy:        opts.SigningPolicy,
+		lookupTableResolver:  newLookupTableResolver(opts.SolanaRPCEndpoint),
+		dryRun:               newDryRunner(opts),
+		nonceProvider:        newNonceProvider(opts.SolanaRPCEndpoint),
+		mintProvider:         newMintProvider(opts.SolanaRPCEndpoint),
 	}
 	client.recentBlockHashStore = newRecentBlockHashStore(
 		func(ctx context.Context) (*pb.GetRecentBlockHashResponse, error) {
@@ -75,6 +90,8 @@ func NewWSClientWithOpts(opts RPCOpts) (*WSClient, error) {
 	if opts.CacheBlockHash {
 		go client.recentBlockHashStore.run(context.Background())
 	}
+	client.priorityFeeStore = newPriorityFeeStore(client.GetPriorityFee, client.GetPriorityFeeStream, opts)
+	client.bundleTipStore = newBundleTipStore(client.GetBundleTipStream)
 	return client, nil
 }
 
@@ -556,7 +573,7 @@ func (w *WSClient) PostOrder(ctx context.Context, owner, payer, market string, s
 // PostSubmit posts the transaction string to the Solana network.
 func (w *WSClient) PostSubmit(ctx context.Context, txBase64 string, skipPreFlight bool,
 	frontRunningProtection bool, useStakedRPCs bool) (*pb.PostSubmitResponse, error) {
-	if w.privateKey == nil {
+	if w.keyring.Len() == 0 {
 		return &pb.PostSubmitResponse{}, ErrPrivateKeyNotFound
 	}
 
@@ -589,11 +606,11 @@ func (w *WSClient) PostSubmitBatch(ctx context.Context, request *pb.PostSubmitBa
 // PostSubmitV2 posts the transaction string to the Solana network.
 func (w *WSClient) PostSubmitV2(ctx context.Context, txBase64 string, skipPreFlight bool,
 	useBundle bool, useStakedRPCs bool) (*pb.PostSubmitResponse, error) {
-	if w.privateKey == nil {
+	if w.keyring.Len() == 0 {
 		return &pb.PostSubmitResponse{}, ErrPrivateKeyNotFound
 	}
 
-	txBase64, err := transaction.SignTxWithPrivateKey(txBase64, *w.privateKey)
+	txBase64, err := w.txSigner().sign(ctx, txBase64)
 	if err != nil {
 		return &pb.PostSubmitResponse{}, err
 	}
@@ -624,18 +641,24 @@ func (w *WSClient) PostSubmitBatchV2(ctx context.Context, request *pb.PostSubmit
 	return &response, nil
 }
 
-// SignAndSubmit signs the given transaction and submits it.
+// SignAndSubmit signs the given transaction and submits it. If RPCOpts.DryRun is set, the transaction is simulated
+// instead and a simulated failure is returned as an error; SignAndSimulate dry-runs a single transaction and returns
+// its SimulationResult.
 func (w *WSClient) SignAndSubmit(ctx context.Context, tx *pb.TransactionMessage,
 	skipPreFlight bool, frontRunningProtection bool, useStakedRPCs bool) (string, error) {
-	if w.privateKey == nil {
+	if w.keyring.Len() == 0 {
 		return "", ErrPrivateKeyNotFound
 	}
 
-	txBase64, err := transaction.SignTxWithPrivateKey(tx.Content, *w.privateKey)
+	txBase64, err := w.txSigner().sign(ctx, tx.Content)
 	if err != nil {
 		return "", err
 	}
 
+	if w.dryRun.enabled {
+		return w.dryRun.simulateSignature(ctx, txBase64)
+	}
+
 	response, err := w.PostSubmit(ctx, txBase64, skipPreFlight, frontRunningProtection, useStakedRPCs)
 	if err != nil {
 		return "", err
@@ -644,12 +667,33 @@ func (w *WSClient) SignAndSubmit(ctx context.Context, tx *pb.TransactionMessage,
 	return response.Signature, nil
 }
 
+// SignAndSimulate signs the given transaction and simulates it instead of submitting it, whether or not
+// RPCOpts.DryRun is set. The result is also passed to RPCOpts.OnSimulation.
+func (w *WSClient) SignAndSimulate(ctx context.Context, tx *pb.TransactionMessage) (*SimulationResult, error) {
+	if w.keyring.Len() == 0 {
+		return nil, ErrPrivateKeyNotFound
+	}
+	txBase64, err := w.txSigner().sign(ctx, tx.Content)
+	if err != nil {
+		return nil, err
+	}
+	return w.dryRun.simulate(ctx, txBase64)
+}
+
 // SignAndSubmitBatch signs the given transactions and submits them.
 func (w *WSClient) SignAndSubmitBatch(ctx context.Context, transactions []*pb.TransactionMessage, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
-	if w.privateKey == nil {
+	if w.keyring.Len() == 0 {
 		return nil, ErrPrivateKeyNotFound
 	}
 
+	if w.dryRun.active(opts) {
+		batchRequest, err := buildBatchRequest(ctx, transactions, w.txSigner(), useBundle, opts)
+		if err != nil {
+			return nil, err
+		}
+		return w.dryRun.simulateBatch(ctx, batchRequest)
+	}
+
 	if len(transactions) == 1 {
 		signature, err := w.SignAndSubmit(ctx, transactions[0], *opts.SkipPreFlight, false, false)
 		if err != nil {
@@ -666,13 +710,18 @@ func (w *WSClient) SignAndSubmitBatch(ctx context.Context, transactions []*pb.Tr
 		}, nil
 	}
 
-	batchRequest, err := buildBatchRequest(transactions, *w.privateKey, useBundle, opts)
+	batchRequest, err := buildBatchRequest(ctx, transactions, w.txSigner(), useBundle, opts)
 	if err != nil {
 		return nil, err
 	}
 	return w.PostSubmitBatch(ctx, batchRequest)
 }
 
+// signAndPostBatch signs the given transactions and submits them as one batch request, even if there is only one
+func (w *WSClient) signAndPostBatch(ctx context.Context, transactions []*pb.TransactionMessage, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
+	return signAndPostBatch(ctx, transactions, w.txSigner(), w.dryRun, w.PostSubmitBatch, useBundle, opts)
+}
+
 // SubmitTradeSwap builds a TradeSwap transaction then signs it, and submits to the network.
 func (w *WSClient) SubmitTradeSwap(ctx context.Context, owner, inToken, outToken string, inAmount, slippage float64, project string, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
 	resp, err := w.PostTradeSwap(ctx, owner, inToken, outToken, inAmount, slippage, project)
@@ -758,120 +807,57 @@ func (w *WSClient) SubmitJupiterSwap(ctx context.Context, request *pb.PostJupite
 
 // SubmitJupiterSwapInstructions builds a Jupiter Swap transaction then signs it, and submits to the network.
 func (w *WSClient) SubmitJupiterSwapInstructions(ctx context.Context, request *pb.PostJupiterSwapInstructionsRequest, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
-	swapInstructions, err := w.PostJupiterSwapInstructions(ctx, request)
-	if err != nil {
-		return nil, err
-	}
-
-	txBuilder := solana.NewTransactionBuilder()
-
-	addressLookupTable, err := utils.ConvertProtoAddressLookupTable(swapInstructions.AddressLookupTableAddresses)
+	opts, err := withOwner(opts, request.OwnerAddress)
 	if err != nil {
 		return nil, err
 	}
 
-	txBuilder.WithOpt(solana.TransactionAddressTables(addressLookupTable))
-
-	instructions, err := utils.ConvertJupiterInstructions(swapInstructions.Instructions)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, inst := range instructions {
-		txBuilder.AddInstruction(inst)
-	}
-
-	txBuilder.SetFeePayer(w.privateKey.PublicKey())
-	blockHash, err := w.RecentBlockHash(ctx)
-
-	if err != nil {
-		panic(fmt.Errorf("server error: could not retrieve block hash: %w", err))
-	}
-
-	hash, err := solana.HashFromBase58(blockHash.BlockHash)
+	swapInstructions, err := w.PostJupiterSwapInstructions(ctx, request)
 	if err != nil {
 		return nil, err
 	}
 
-	txBuilder.SetRecentBlockHash(hash)
-	tx, err := txBuilder.Build()
+	addressLookupTable, err := utils.ConvertProtoAddressLookupTable(swapInstructions.AddressLookupTableAddresses)
 	if err != nil {
 		return nil, err
 	}
 
-	err = transaction.PartialSign(tx, w.privateKey.PublicKey(), make(map[solana.PublicKey]solana.PrivateKey))
+	instructions, err := utils.ConvertJupiterInstructions(swapInstructions.Instructions)
 	if err != nil {
 		return nil, err
 	}
 
-	var txToBeSigned []*pb.TransactionMessage
-
-	txBase64, err := tx.ToBase64()
+	tx, err := w.instructionTxBuilder().build(ctx, instructions, addressLookupTable, pb.Project_P_JUPITER, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	txToBeSigned = append(txToBeSigned, &pb.TransactionMessage{
-		Content:   txBase64,
-		IsCleanup: false,
-	})
-
-	return w.SignAndSubmitBatch(ctx, txToBeSigned, useBundle, opts)
+	return w.signAndPostBatch(ctx, []*pb.TransactionMessage{tx}, useBundle, opts)
 }
 
 // SubmitRaydiumSwapInstructions builds a Raydium Swap transaction then signs it, and submits to the network.
 func (w *WSClient) SubmitRaydiumSwapInstructions(ctx context.Context, request *pb.PostRaydiumSwapInstructionsRequest, useBundle bool, opts SubmitOpts) (*pb.PostSubmitBatchResponse, error) {
-	swapInstructions, err := w.PostRaydiumSwapInstructions(ctx, request)
-	if err != nil {
-		return nil, err
-	}
-
-	instructions, err := utils.ConvertRaydiumInstructions(swapInstructions.Instructions)
+	opts, err := withOwner(opts, request.OwnerAddress)
 	if err != nil {
 		return nil, err
 	}
-	txBuilder := solana.NewTransactionBuilder()
 
-	for _, inst := range instructions {
-		txBuilder.AddInstruction(inst)
-	}
-
-	txBuilder.SetFeePayer(w.privateKey.PublicKey())
-	blockHash, err := w.RecentBlockHash(ctx)
-
-	if err != nil {
-		panic(fmt.Errorf("server error: could not retrieve block hash: %w", err))
-	}
-
-	hash, err := solana.HashFromBase58(blockHash.BlockHash)
-	if err != nil {
-		return nil, err
-	}
-
-	txBuilder.SetRecentBlockHash(hash)
-	tx, err := txBuilder.Build()
+	swapInstructions, err := w.PostRaydiumSwapInstructions(ctx, request)
 	if err != nil {
 		return nil, err
 	}
 
-	err = transaction.PartialSign(tx, w.privateKey.PublicKey(), make(map[solana.PublicKey]solana.PrivateKey))
+	instructions, err := utils.ConvertRaydiumInstructions(swapInstructions.Instructions)
 	if err != nil {
 		return nil, err
 	}
 
-	var txToBeSigned []*pb.TransactionMessage
-
-	txBase64, err := tx.ToBase64()
+	tx, err := w.instructionTxBuilder().build(ctx, instructions, nil, pb.Project_P_RAYDIUM, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	txToBeSigned = append(txToBeSigned, &pb.TransactionMessage{
-		Content:   txBase64,
-		IsCleanup: false,
-	})
-
-	return w.SignAndSubmitBatch(ctx, txToBeSigned, useBundle, opts)
+	return w.signAndPostBatch(ctx, []*pb.TransactionMessage{tx}, useBundle, opts)
 }
 
 // SubmitJupiterRouteSwap builds a Jupiter RouteSwap transaction then signs it, and submits to the network.
@@ -1085,6 +1071,7 @@ func (w *WSClient) SubmitReplaceOrder(ctx context.Context, orderID, owner, payer
 }
 
 func (w *WSClient) Close() error {
+	w.priorityFeeStore.close()
 	return w.conn.Close(errors.New("shutdown requested"))
 }
 
@@ -0,0 +1,251 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bloXroute-Labs/solana-trader-client-go/utils"
+	"github.com/gagliardetto/solana-go"
+)
+
+// MaxTransactionSize is the maximum size of a serialized transaction, including signatures
+const MaxTransactionSize = 1232
+
+var ErrTransactionTooLarge = errors.New("transaction exceeds maximum size")
+
+// BuildStep modifies a transaction under construction before it is compiled. Steps run in the order they were added.
+type BuildStep func(ctx context.Context, b *TxBuilder) error
+
+// TxValidator checks a compiled transaction before it is signed
+type TxValidator func(tx *solana.Transaction) error
+
+// TxBuilder assembles a transaction from instructions through a sequence of pluggable steps, then compiles,
+// validates and signs it
+type TxBuilder struct {
+	feePayer            solana.PublicKey
+	instructions        []solana.Instruction
+	addressLookupTables map[solana.PublicKey]solana.PublicKeySlice
+	signers             map[solana.PublicKey]solana.PrivateKey
+	steps               []BuildStep
+	validators          []TxValidator
+	nonceAccount        *solana.PublicKey
+	nonceAuthority      solana.PublicKey
+	versioned           bool
+}
+
+func NewTxBuilder(feePayer solana.PublicKey) *TxBuilder {
+	return &TxBuilder{
+		feePayer:            feePayer,
+		addressLookupTables: make(map[solana.PublicKey]solana.PublicKeySlice),
+		signers:             make(map[solana.PublicKey]solana.PrivateKey),
+	}
+}
+
+func (b *TxBuilder) FeePayer() solana.PublicKey {
+	return b.feePayer
+}
+
+func (b *TxBuilder) Instructions() []solana.Instruction {
+	return b.instructions
+}
+
+// SetInstructions replaces all instructions of the transaction
+func (b *TxBuilder) SetInstructions(instructions []solana.Instruction) *TxBuilder {
+	b.instructions = instructions
+	return b
+}
+
+func (b *TxBuilder) AddInstructions(instructions ...solana.Instruction) *TxBuilder {
+	b.instructions = append(b.instructions, instructions...)
+	return b
+}
+
+func (b *TxBuilder) PrependInstructions(instructions ...solana.Instruction) *TxBuilder {
+	b.instructions = append(append([]solana.Instruction{}, instructions...), b.instructions...)
+	return b
+}
+
+func (b *TxBuilder) AddressLookupTables() map[solana.PublicKey]solana.PublicKeySlice {
+	return b.addressLookupTables
+}
+
+// AddAddressLookupTables merges lookup tables into the transaction, which compiles it as a versioned transaction
+func (b *TxBuilder) AddAddressLookupTables(tables map[solana.PublicKey]solana.PublicKeySlice) *TxBuilder {
+	for table, addresses := range tables {
+		b.addressLookupTables[table] = addresses
+	}
+	return b
+}
+
+// AddSigners registers private keys that sign the transaction once it is built. Required signers without a key are
+// left with an empty signature to be filled in later.
+func (b *TxBuilder) AddSigners(privateKeys ...solana.PrivateKey) *TxBuilder {
+	for _, privateKey := range privateKeys {
+		b.signers[privateKey.PublicKey()] = privateKey
+	}
+	return b
+}
+
+func (b *TxBuilder) AddStep(steps ...BuildStep) *TxBuilder {
+	b.steps = append(b.steps, steps...)
+	return b
+}
+
+func (b *TxBuilder) AddValidator(validators ...TxValidator) *TxBuilder {
+	b.validators = append(b.validators, validators...)
+	return b
+}
+
+// Build runs all steps, compiles the transaction with the provided blockhash (or nonce, see NonceStep), validates it
+// and signs it with the registered signers
+func (b *TxBuilder) Build(ctx context.Context, recentBlockHash solana.Hash) (*solana.Transaction, error) {
+	for _, step := range b.steps {
+		if err := step(ctx, b); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(b.instructions) == 0 {
+		return nil, errors.New("transaction has no instructions")
+	}
+	if b.nonceAccount != nil {
+		b.placeAdvanceNonce()
+	}
+
+	opts := []solana.TransactionOption{solana.TransactionPayer(b.feePayer)}
+	if len(b.addressLookupTables) != 0 {
+		opts = append(opts, solana.TransactionAddressTables(b.addressLookupTables))
+	}
+	tx, err := solana.NewTransaction(b.instructions, recentBlockHash, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if b.versioned {
+		tx.Message.SetVersion(solana.MessageVersionV0)
+	}
+
+	for _, validator := range b.validators {
+		if err := validator(tx); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := signAvailable(tx, b.signers); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// signAvailable signs the transaction with every available key, leaving empty signatures for the others
+func signAvailable(tx *solana.Transaction, privateKeys map[solana.PublicKey]solana.PrivateKey) error {
+	messageBytes, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	requiredSignatures := int(tx.Message.Header.NumRequiredSignatures)
+	signatures := make([]solana.Signature, 0, requiredSignatures)
+	for _, key := range tx.Message.AccountKeys[0:requiredSignatures] {
+		privateKey, ok := privateKeys[key]
+		if !ok {
+			signatures = append(signatures, solana.Signature{})
+			continue
+		}
+		signature, err := privateKey.Sign(messageBytes)
+		if err != nil {
+			return fmt.Errorf("unable to sign message: %w", err)
+		}
+		signatures = append(signatures, signature)
+	}
+
+	tx.Signatures = signatures
+	return nil
+}
+
+// MemoStep appends the Trader API memo instruction if the transaction does not already contain one
+func MemoStep(msg string) BuildStep {
+	return func(_ context.Context, b *TxBuilder) error {
+		for _, instruction := range b.instructions {
+			if instruction.ProgramID().Equals(TraderAPIMemoProgram) {
+				return nil
+			}
+		}
+		b.AddInstructions(CreateTraderAPIMemoInstruction(msg))
+		return nil
+	}
+}
+
+// ComputeBudgetStep replaces any compute budget instructions with the provided compute unit limit and price
+func ComputeBudgetStep(computeUnitLimit uint32, computeUnitPrice uint64) BuildStep {
+	return func(_ context.Context, b *TxBuilder) error {
+		b.SetInstructions(RemoveComputeBudgetInstructions(b.instructions))
+		b.PrependInstructions(CreateComputeBudgetInstructions(computeUnitLimit, computeUnitPrice)...)
+		return nil
+	}
+}
+
+// TipStep appends a transfer of the bloXroute tip paid by the fee payer
+func TipStep(tipAmount uint64) BuildStep {
+	return func(_ context.Context, b *TxBuilder) error {
+		b.AddInstructions(utils.CreateBloxrouteTipInstruction(b.feePayer, tipAmount))
+		return nil
+	}
+}
+
+// InstructionsStep appends user provided instructions
+func InstructionsStep(instructions ...solana.Instruction) BuildStep {
+	return func(_ context.Context, b *TxBuilder) error {
+		b.AddInstructions(instructions...)
+		return nil
+	}
+}
+
+// AddressLookupTablesStep adds address lookup tables to the transaction
+func AddressLookupTablesStep(tables map[solana.PublicKey]solana.PublicKeySlice) BuildStep {
+	return func(_ context.Context, b *TxBuilder) error {
+		b.AddAddressLookupTables(tables)
+		return nil
+	}
+}
+
+// SignerStep registers private keys that sign the transaction once it is built
+func SignerStep(privateKeys ...solana.PrivateKey) BuildStep {
+	return func(_ context.Context, b *TxBuilder) error {
+		b.AddSigners(privateKeys...)
+		return nil
+	}
+}
+
+// ValidateSize checks that the transaction fits in a single packet once all signatures are present
+func ValidateSize(tx *solana.Transaction) error {
+	size, err := SerializedSize(tx)
+	if err != nil {
+		return err
+	}
+	if size > MaxTransactionSize {
+		return fmt.Errorf("%w: %v bytes, maximum is %v", ErrTransactionTooLarge, size, MaxTransactionSize)
+	}
+	return nil
+}
+
+// SerializedSize returns the size of the transaction once all required signatures are present
+func SerializedSize(tx *solana.Transaction) (int, error) {
+	messageBytes, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	numSignatures := int(tx.Message.Header.NumRequiredSignatures)
+	return compactU16Length(numSignatures) + numSignatures*solana.SignatureLength + len(messageBytes), nil
+}
+
+func compactU16Length(n int) int {
+	switch {
+	case n < 0x80:
+		return 1
+	case n < 0x4000:
+		return 2
+	default:
+		return 3
+	}
+}
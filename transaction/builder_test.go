@@ -0,0 +1,51 @@
+package transaction
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxBuilder(t *testing.T) {
+	privateKey, err := solana.NewRandomPrivateKey()
+	require.NoError(t, err)
+	payer := privateKey.PublicKey()
+	recipient := solana.NewWallet().PublicKey()
+
+	tx, err := NewTxBuilder(payer).
+		AddInstructions(system.NewTransferInstruction(1000, payer, recipient).Build()).
+		AddStep(
+			MemoStep(""),
+			MemoStep(""),
+			ComputeBudgetStep(200_000, 1000),
+			SignerStep(privateKey),
+		).
+		AddValidator(ValidateSize).
+		Build(context.Background(), solana.Hash{})
+	require.NoError(t, err)
+
+	// compute budget instructions are prepended and the memo is only added once
+	require.Equal(t, 4, len(tx.Message.Instructions))
+	for i, expected := range []solana.PublicKey{solana.ComputeBudget, solana.ComputeBudget, solana.SystemProgramID, TraderAPIMemoProgram} {
+		program, err := tx.Message.Program(tx.Message.Instructions[i].ProgramIDIndex)
+		require.NoError(t, err)
+		require.Equal(t, expected, program)
+	}
+
+	require.NoError(t, tx.VerifySignatures())
+}
+
+func TestTxBuilderValidateSize(t *testing.T) {
+	payer := solana.NewWallet().PublicKey()
+
+	builder := NewTxBuilder(payer)
+	for i := 0; i < 40; i++ {
+		builder.AddInstructions(system.NewTransferInstruction(1000, payer, solana.NewWallet().PublicKey()).Build())
+	}
+
+	_, err := builder.AddValidator(ValidateSize).Build(context.Background(), solana.Hash{})
+	require.ErrorIs(t, err, ErrTransactionTooLarge)
+}
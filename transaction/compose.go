@@ -0,0 +1,127 @@
+package transaction
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+)
+
+// MaxTransactionAccounts is the maximum number of accounts a transaction can lock, including accounts loaded from
+// address lookup tables
+const MaxTransactionAccounts = 64
+
+// createIdempotentInstruction is the associated token account program's CreateIdempotent instruction
+const createIdempotentInstruction = 1
+
+var ErrTooManyAccounts = errors.New("transaction exceeds maximum number of accounts")
+
+// ValidateAccounts checks that the transaction doesn't reference more accounts than the runtime can lock
+func ValidateAccounts(tx *solana.Transaction) error {
+	accounts := len(tx.Message.AccountKeys)
+	for _, lookup := range tx.Message.AddressTableLookups {
+		accounts += len(lookup.WritableIndexes) + len(lookup.ReadonlyIndexes)
+	}
+	if accounts > MaxTransactionAccounts {
+		return fmt.Errorf("%w: %v accounts, maximum is %v", ErrTooManyAccounts, accounts, MaxTransactionAccounts)
+	}
+	return nil
+}
+
+// VersionedStep compiles the transaction as a v0 transaction even if it has no address lookup tables
+func VersionedStep() BuildStep {
+	return func(_ context.Context, b *TxBuilder) error {
+		b.versioned = true
+		return nil
+	}
+}
+
+// MergeAddressLookupTables combines the lookup tables of several API responses. Lookup tables are append-only, so
+// copies of the same table fetched at different times are merged into the longest one; copies that differ within
+// their common length are rejected, since compiled indexes would be wrong for one of them.
+func MergeAddressLookupTables(tables ...map[solana.PublicKey]solana.PublicKeySlice) (map[solana.PublicKey]solana.PublicKeySlice, error) {
+	merged := make(map[solana.PublicKey]solana.PublicKeySlice)
+	for _, t := range tables {
+		for table, addresses := range t {
+			existing, ok := merged[table]
+			if !ok {
+				merged[table] = addresses
+				continue
+			}
+
+			shorter, longer := existing, addresses
+			if len(shorter) > len(longer) {
+				shorter, longer = longer, shorter
+			}
+			for i := range shorter {
+				if !shorter[i].Equals(longer[i]) {
+					return nil, fmt.Errorf("conflicting copies of address lookup table %v at index %v", table, i)
+				}
+			}
+			merged[table] = longer
+		}
+	}
+	return merged, nil
+}
+
+// DedupeInstructions drops repeated idempotent associated token account creations, which instructions of several
+// swaps through the same tokens each include
+func DedupeInstructions(instructions []solana.Instruction) ([]solana.Instruction, error) {
+	deduped := make([]solana.Instruction, 0, len(instructions))
+	var seen [][]byte
+	for _, instruction := range instructions {
+		if !instruction.ProgramID().Equals(solana.SPLAssociatedTokenAccountProgramID) {
+			deduped = append(deduped, instruction)
+			continue
+		}
+
+		data, err := instruction.Data()
+		if err != nil {
+			return nil, err
+		}
+		if len(data) != 1 || data[0] != createIdempotentInstruction {
+			deduped = append(deduped, instruction)
+			continue
+		}
+
+		key := append([]byte{}, data...)
+		for _, account := range instruction.Accounts() {
+			key = append(key, account.PublicKey.Bytes()...)
+		}
+		duplicate := false
+		for _, previous := range seen {
+			if bytes.Equal(previous, key) {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			continue
+		}
+		seen = append(seen, key)
+		deduped = append(deduped, instruction)
+	}
+	return deduped, nil
+}
+
+// ComputeUnitLimit returns the limit set by the instructions' compute budget, if they set one
+func ComputeUnitLimit(instructions []solana.Instruction) (uint32, bool, error) {
+	for _, instruction := range instructions {
+		if !instruction.ProgramID().Equals(solana.ComputeBudget) {
+			continue
+		}
+
+		data, err := instruction.Data()
+		if err != nil {
+			return 0, false, err
+		}
+		if len(data) == 5 && data[0] == computebudget.Instruction_SetComputeUnitLimit {
+			return binary.LittleEndian.Uint32(data[1:]), true, nil
+		}
+	}
+	return 0, false, nil
+}
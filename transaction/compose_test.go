@@ -0,0 +1,69 @@
+package transaction
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeAddressLookupTables(t *testing.T) {
+	table := solana.NewWallet().PublicKey()
+	a, b, c := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+
+	merged, err := MergeAddressLookupTables(
+		map[solana.PublicKey]solana.PublicKeySlice{table: {a, b}},
+		map[solana.PublicKey]solana.PublicKeySlice{table: {a, b, c}},
+		map[solana.PublicKey]solana.PublicKeySlice{table: {a}},
+	)
+	require.NoError(t, err)
+	require.Equal(t, solana.PublicKeySlice{a, b, c}, merged[table])
+
+	_, err = MergeAddressLookupTables(
+		map[solana.PublicKey]solana.PublicKeySlice{table: {a, b}},
+		map[solana.PublicKey]solana.PublicKeySlice{table: {a, c}},
+	)
+	require.Error(t, err)
+}
+
+func TestDedupeInstructions(t *testing.T) {
+	payer := solana.NewWallet().PublicKey()
+	createATA := func(mint solana.PublicKey) solana.Instruction {
+		return solana.NewInstruction(solana.SPLAssociatedTokenAccountProgramID, solana.AccountMetaSlice{
+			solana.Meta(payer).WRITE().SIGNER(),
+			solana.Meta(mint),
+		}, []byte{createIdempotentInstruction})
+	}
+	mint1, mint2 := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	transfer := system.NewTransferInstruction(1000, payer, solana.NewWallet().PublicKey()).Build()
+
+	deduped, err := DedupeInstructions([]solana.Instruction{createATA(mint1), transfer, createATA(mint1), createATA(mint2), transfer})
+	require.NoError(t, err)
+	require.Equal(t, []solana.Instruction{createATA(mint1), transfer, createATA(mint2), transfer}, deduped)
+}
+
+func TestComposeLimits(t *testing.T) {
+	payer := solana.NewWallet().PublicKey()
+
+	limit, ok, err := ComputeUnitLimit(CreateComputeBudgetInstructions(300_000, 1000))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, uint32(300_000), limit)
+
+	tx, err := NewTxBuilder(payer).
+		AddInstructions(system.NewTransferInstruction(1000, payer, solana.NewWallet().PublicKey()).Build()).
+		AddStep(VersionedStep()).
+		AddValidator(ValidateAccounts).
+		Build(context.Background(), solana.Hash{})
+	require.NoError(t, err)
+	require.Equal(t, solana.MessageVersionV0, tx.Message.GetVersion())
+
+	builder := NewTxBuilder(payer)
+	for i := 0; i < MaxTransactionAccounts; i++ {
+		builder.AddInstructions(system.NewTransferInstruction(1000, payer, solana.NewWallet().PublicKey()).Build())
+	}
+	_, err = builder.AddValidator(ValidateAccounts).Build(context.Background(), solana.Hash{})
+	require.ErrorIs(t, err, ErrTooManyAccounts)
+}
@@ -0,0 +1,35 @@
+package transaction
+
+import (
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+)
+
+// MaxComputeUnitLimit is the maximum number of compute units a single transaction can request
+const MaxComputeUnitLimit = 1_400_000
+
+// CreateComputeBudgetInstructions generates the instructions setting a transaction's compute unit limit and price (in
+// micro-lamports per compute unit). Zero values are omitted.
+func CreateComputeBudgetInstructions(computeUnitLimit uint32, computeUnitPrice uint64) []solana.Instruction {
+	var instructions []solana.Instruction
+	if computeUnitLimit != 0 {
+		instructions = append(instructions, computebudget.NewSetComputeUnitLimitInstruction(computeUnitLimit).Build())
+	}
+	if computeUnitPrice != 0 {
+		instructions = append(instructions, computebudget.NewSetComputeUnitPriceInstruction(computeUnitPrice).Build())
+	}
+	return instructions
+}
+
+// RemoveComputeBudgetInstructions filters out any existing compute budget instructions, since a transaction with
+// duplicate compute budget instructions is rejected by the runtime
+func RemoveComputeBudgetInstructions(instructions []solana.Instruction) []solana.Instruction {
+	filtered := make([]solana.Instruction, 0, len(instructions))
+	for _, instruction := range instructions {
+		if instruction.ProgramID().Equals(solana.ComputeBudget) {
+			continue
+		}
+		filtered = append(filtered, instruction)
+	}
+	return filtered
+}
@@ -0,0 +1,325 @@
+package transaction
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+	solanarpc "github.com/gagliardetto/solana-go/rpc"
+)
+
+var (
+	Token2022ProgramID = solana.MustPublicKeyFromBase58("TokenzQdBNbLqP5VEhdkAS6EPFLC1PHnBqCXEpPxuEb")
+	MemoProgramID      = solana.MustPublicKeyFromBase58("MemoSq4gqABAXKb96qnH8TysNcWxMyWCqXgDLGmfcHr")
+)
+
+var knownPrograms = map[solana.PublicKey]string{
+	solana.SystemProgramID:                    "System Program",
+	solana.TokenProgramID:                     "Token Program",
+	Token2022ProgramID:                        "Token-2022 Program",
+	solana.SPLAssociatedTokenAccountProgramID: "Associated Token Account Program",
+	solana.ComputeBudget:                      "Compute Budget Program",
+	TraderAPIMemoProgram:                      "Trader API Memo Program",
+	MemoProgramID:                             "Memo Program",
+}
+
+// InspectedInstruction is a decoded instruction of a compiled transaction. Accounts loaded from address lookup
+// tables that could not be resolved have a zero public key.
+type InspectedInstruction struct {
+	Index     int
+	ProgramID solana.PublicKey
+	Program   string
+	Name      string
+	Accounts  []*solana.AccountMeta
+	Data      []byte
+
+	// Lamports is the amount of SOL the instruction moves through the system program
+	Lamports uint64
+
+	// source is the index of the account funding Lamports, and unresolved marks accounts loaded from lookup tables
+	// that were not provided
+	source     int
+	unresolved []bool
+}
+
+// InspectedTransaction is a readable view of a compiled transaction
+type InspectedTransaction struct {
+	Version      solana.MessageVersion
+	FeePayer     solana.PublicKey
+	Signers      []solana.PublicKey
+	Instructions []InspectedInstruction
+
+	// UnresolvedLookups is set if the transaction loads accounts from address lookup tables that were not provided
+	UnresolvedLookups bool
+}
+
+// SOLOutflow returns the total lamports the transaction's signers move through the system program, excluding fees.
+// Transfers funded by other accounts aren't counted. An error is returned if the funding account of a transfer was
+// loaded from a lookup table that was not provided.
+func (t *InspectedTransaction) SOLOutflow() (uint64, error) {
+	var total uint64
+	for _, instruction := range t.Instructions {
+		if instruction.Lamports == 0 {
+			continue
+		}
+		if instruction.source >= len(instruction.Accounts) {
+			return 0, fmt.Errorf("instruction %v: missing funding account", instruction.Index)
+		}
+		if instruction.unresolved[instruction.source] {
+			return 0, fmt.Errorf("instruction %v: funding account is loaded from an address lookup table that was not provided", instruction.Index)
+		}
+		if instruction.Accounts[instruction.source].IsSigner {
+			total += instruction.Lamports
+		}
+	}
+	return total, nil
+}
+
+func (t *InspectedTransaction) String() string {
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "fee payer: %v\n", t.FeePayer)
+	_, _ = fmt.Fprintf(&sb, "signers: %v\n", t.Signers)
+	for _, instruction := range t.Instructions {
+		_, _ = fmt.Fprintf(&sb, "#%v %v: %v", instruction.Index, instruction.Program, instruction.Name)
+		if instruction.Lamports != 0 {
+			_, _ = fmt.Fprintf(&sb, " (%v lamports)", instruction.Lamports)
+		}
+		sb.WriteString("\n")
+		for _, account := range instruction.Accounts {
+			_, _ = fmt.Fprintf(&sb, "    %v signer=%v writable=%v\n", account.PublicKey, account.IsSigner, account.IsWritable)
+		}
+	}
+	return sb.String()
+}
+
+// InspectTransactionBase64 decodes a base64 transaction and inspects it
+func InspectTransactionBase64(txBase64 string, lookupTables map[solana.PublicKey]solana.PublicKeySlice) (*InspectedTransaction, error) {
+	tx, err := DecodeTransaction(txBase64)
+	if err != nil {
+		return nil, err
+	}
+	return InspectTransaction(tx, lookupTables)
+}
+
+// InspectTransaction decodes the instructions of a transaction. Accounts of v0 messages are resolved against the
+// provided lookup tables, which may be nil.
+func InspectTransaction(tx *solana.Transaction, lookupTables map[solana.PublicKey]solana.PublicKeySlice) (*InspectedTransaction, error) {
+	message := &tx.Message
+	keys, unresolved, err := messageAccountKeys(message, lookupTables)
+	if err != nil {
+		return nil, err
+	}
+
+	numSigners := int(message.Header.NumRequiredSignatures)
+	if numSigners == 0 || numSigners > len(message.AccountKeys) {
+		return nil, fmt.Errorf("invalid number of required signatures %v", numSigners)
+	}
+
+	inspected := &InspectedTransaction{
+		Version:           message.GetVersion(),
+		FeePayer:          message.AccountKeys[0],
+		Signers:           message.AccountKeys[:numSigners],
+		UnresolvedLookups: len(unresolved) != 0,
+	}
+
+	for i, compiled := range message.Instructions {
+		if int(compiled.ProgramIDIndex) >= len(message.AccountKeys) {
+			return nil, fmt.Errorf("instruction %v: program index %v out of range", i, compiled.ProgramIDIndex)
+		}
+		programID := message.AccountKeys[compiled.ProgramIDIndex]
+
+		accounts := make([]*solana.AccountMeta, 0, len(compiled.Accounts))
+		unresolvedAccounts := make([]bool, 0, len(compiled.Accounts))
+		for _, index := range compiled.Accounts {
+			if int(index) >= len(keys) {
+				return nil, fmt.Errorf("instruction %v: account index %v out of range", i, index)
+			}
+			accounts = append(accounts, &solana.AccountMeta{
+				PublicKey:  keys[index],
+				IsSigner:   int(index) < numSigners,
+				IsWritable: isWritableIndex(message, int(index)),
+			})
+			unresolvedAccounts = append(unresolvedAccounts, unresolved[int(index)])
+		}
+
+		instruction := InspectedInstruction{
+			Index:      i,
+			ProgramID:  programID,
+			Program:    programName(programID),
+			Name:       "Unknown",
+			Accounts:   accounts,
+			Data:       compiled.Data,
+			unresolved: unresolvedAccounts,
+		}
+		decodeInstruction(&instruction)
+		inspected.Instructions = append(inspected.Instructions, instruction)
+	}
+
+	return inspected, nil
+}
+
+// DecodeTransaction decodes a base64 encoded legacy or versioned transaction
+func DecodeTransaction(txBase64 string) (*solana.Transaction, error) {
+	txBytes, err := solanarpc.DataBytesOrJSONFromBase64(txBase64)
+	if err != nil {
+		return nil, err
+	}
+	return solanarpc.TransactionWithMeta{Transaction: txBytes}.GetTransaction()
+}
+
+// messageAccountKeys returns the static keys followed by the writable and readonly keys loaded from lookup tables,
+// in the order instructions index them. Keys from missing lookup tables are left as zero keys, and their indexes
+// are returned as unresolved.
+func messageAccountKeys(message *solana.Message, lookupTables map[solana.PublicKey]solana.PublicKeySlice) (solana.PublicKeySlice, map[int]bool, error) {
+	keys := append(solana.PublicKeySlice{}, message.AccountKeys...)
+	if !message.IsVersioned() {
+		return keys, nil, nil
+	}
+
+	var writable, readonly solana.PublicKeySlice
+	var unresolvedWritable, unresolvedReadonly []int
+	for _, lookup := range message.AddressTableLookups {
+		table, ok := lookupTables[lookup.AccountKey]
+		lookupKey := func(index uint8) (solana.PublicKey, error) {
+			if !ok {
+				return solana.PublicKey{}, nil
+			}
+			if int(index) >= len(table) {
+				return solana.PublicKey{}, fmt.Errorf("index %v out of range for lookup table %v", index, lookup.AccountKey)
+			}
+			return table[index], nil
+		}
+
+		for _, index := range lookup.WritableIndexes {
+			key, err := lookupKey(index)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !ok {
+				unresolvedWritable = append(unresolvedWritable, len(writable))
+			}
+			writable = append(writable, key)
+		}
+		for _, index := range lookup.ReadonlyIndexes {
+			key, err := lookupKey(index)
+			if err != nil {
+				return nil, nil, err
+			}
+			if !ok {
+				unresolvedReadonly = append(unresolvedReadonly, len(readonly))
+			}
+			readonly = append(readonly, key)
+		}
+	}
+
+	unresolved := make(map[int]bool)
+	for _, i := range unresolvedWritable {
+		unresolved[len(keys)+i] = true
+	}
+	for _, i := range unresolvedReadonly {
+		unresolved[len(keys)+len(writable)+i] = true
+	}
+
+	keys = append(append(keys, writable...), readonly...)
+	return keys, unresolved, nil
+}
+
+func isWritableIndex(message *solana.Message, index int) bool {
+	header := message.Header
+	numStatic := len(message.AccountKeys)
+	numSigners := int(header.NumRequiredSignatures)
+
+	if index >= numStatic {
+		return index-numStatic < message.NumWritableLookups()
+	}
+	if index < numSigners {
+		return index < numSigners-int(header.NumReadonlySignedAccounts)
+	}
+	return index < numStatic-int(header.NumReadonlyUnsignedAccounts)
+}
+
+func programName(programID solana.PublicKey) string {
+	if name, ok := knownPrograms[programID]; ok {
+		return name
+	}
+	return programID.String()
+}
+
+// decodeInstruction fills in the name and moved lamports of instructions of well-known programs
+func decodeInstruction(instruction *InspectedInstruction) {
+	data := instruction.Data
+	switch instruction.ProgramID {
+	case solana.SystemProgramID:
+		if len(data) < 4 {
+			return
+		}
+		if name := system.InstructionIDToName(binary.LittleEndian.Uint32(data)); name != "" {
+			instruction.Name = name
+		}
+
+		decoded, err := system.DecodeInstruction(instruction.Accounts, data)
+		if err != nil {
+			return
+		}
+		switch impl := decoded.Impl.(type) {
+		case *system.Transfer:
+			instruction.Lamports = valueOrZero(impl.Lamports)
+		case *system.TransferWithSeed:
+			// the funding account is derived from the base account, which signs for it
+			instruction.Lamports = valueOrZero(impl.Lamports)
+			instruction.source = 1
+		case *system.CreateAccount:
+			instruction.Lamports = valueOrZero(impl.Lamports)
+		case *system.CreateAccountWithSeed:
+			instruction.Lamports = valueOrZero(impl.Lamports)
+		}
+	case solana.TokenProgramID, Token2022ProgramID:
+		if len(data) < 1 {
+			return
+		}
+		if name := token.InstructionIDToName(data[0]); name != "" {
+			instruction.Name = name
+		}
+	case solana.ComputeBudget:
+		if len(data) < 1 {
+			return
+		}
+		if name := computebudget.InstructionIDToName(data[0]); name != "" {
+			instruction.Name = name
+		}
+	case TraderAPIMemoProgram, MemoProgramID:
+		instruction.Name = fmt.Sprintf("Memo %q", string(data))
+	}
+}
+
+func valueOrZero(v *uint64) uint64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
+// WritableAccounts returns the accounts the transaction may modify, including accounts loaded from the provided
+// lookup tables
+func WritableAccounts(tx *solana.Transaction, lookupTables map[solana.PublicKey]solana.PublicKeySlice) (solana.PublicKeySlice, error) {
+	keys, unresolved, err := messageAccountKeys(&tx.Message, lookupTables)
+	if err != nil {
+		return nil, err
+	}
+	if len(unresolved) != 0 {
+		return nil, errors.New("address lookup tables of the transaction were not provided")
+	}
+
+	var writable solana.PublicKeySlice
+	for i, key := range keys {
+		if isWritableIndex(&tx.Message, i) {
+			writable = append(writable, key)
+		}
+	}
+	return writable, nil
+}
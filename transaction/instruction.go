@@ -0,0 +1,187 @@
+package transaction
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+var ErrLookupTablesRequired = errors.New("address lookup tables are required to resolve accounts of a versioned transaction")
+
+// AppendInstruction compiles an instruction into an existing transaction message. New account keys are inserted at
+// the end of the static header section matching their privileges and existing account indexes, including indexes of
+// accounts loaded from lookup tables, are shifted accordingly. The message changes, so any existing signatures must be
+// replaced afterwards.
+//
+// Versioned messages with lookup tables are supported as long as all accounts of the instruction besides its program
+// are static keys; otherwise use AppendInstructionWithLookupTables.
+func AppendInstruction(tx *solana.Transaction, instruction solana.Instruction) error {
+	return AppendInstructionWithLookupTables(tx, instruction, nil)
+}
+
+// AppendInstructionWithLookupTables is like AppendInstruction, but accounts already loaded through the message's
+// address table lookups are referenced by their lookup index instead of being duplicated as static keys. The lookup
+// tables must contain every table the message references.
+func AppendInstructionWithLookupTables(tx *solana.Transaction, instruction solana.Instruction, lookupTables map[solana.PublicKey]solana.PublicKeySlice) error {
+	compiled, err := compileInstruction(&tx.Message, instruction, lookupTables)
+	if err != nil {
+		return err
+	}
+
+	tx.Message.Instructions = append(tx.Message.Instructions, compiled)
+	return nil
+}
+
+// compileInstruction adds any missing account keys of the instruction to the message and returns the instruction
+// compiled against the updated account keys
+func compileInstruction(message *solana.Message, instruction solana.Instruction, lookupTables map[solana.PublicKey]solana.PublicKeySlice) (solana.CompiledInstruction, error) {
+	data, err := instruction.Data()
+	if err != nil {
+		return solana.CompiledInstruction{}, err
+	}
+
+	accounts := instruction.Accounts()
+	for _, account := range accounts {
+		if _, ok := staticAccountIndex(message, account.PublicKey); !ok {
+			loaded, err := findLoadedAccount(message, account.PublicKey, lookupTables)
+			if err != nil {
+				return solana.CompiledInstruction{}, err
+			}
+			if loaded != nil {
+				if account.IsSigner || (account.IsWritable && !loaded.writable) {
+					return solana.CompiledInstruction{}, fmt.Errorf("account %v is loaded from a lookup table with fewer privileges", account.PublicKey)
+				}
+				continue
+			}
+		}
+		if err := addAccountKey(message, account.PublicKey, account.IsSigner, account.IsWritable); err != nil {
+			return solana.CompiledInstruction{}, err
+		}
+	}
+
+	// programs can't be loaded from lookup tables, so they are always static
+	if err := addAccountKey(message, instruction.ProgramID(), false, false); err != nil {
+		return solana.CompiledInstruction{}, err
+	}
+
+	compiled := solana.CompiledInstruction{
+		Accounts: make([]uint16, 0, len(accounts)),
+		Data:     data,
+	}
+	compiled.ProgramIDIndex, _ = staticAccountIndex(message, instruction.ProgramID())
+	for _, account := range accounts {
+		index, ok := staticAccountIndex(message, account.PublicKey)
+		if !ok {
+			loaded, err := findLoadedAccount(message, account.PublicKey, lookupTables)
+			if err != nil {
+				return solana.CompiledInstruction{}, err
+			}
+			index = loaded.index
+		}
+		compiled.Accounts = append(compiled.Accounts, index)
+	}
+	return compiled, nil
+}
+
+func staticAccountIndex(message *solana.Message, key solana.PublicKey) (uint16, bool) {
+	for i, existing := range message.AccountKeys {
+		if existing.Equals(key) {
+			return uint16(i), true
+		}
+	}
+	return 0, false
+}
+
+type loadedAccount struct {
+	index    uint16
+	writable bool
+}
+
+// findLoadedAccount looks up a key among the accounts the message loads from address lookup tables. Loaded accounts
+// are indexed after the static keys, writable accounts of all lookups first, then readonly accounts.
+func findLoadedAccount(message *solana.Message, key solana.PublicKey, lookupTables map[solana.PublicKey]solana.PublicKeySlice) (*loadedAccount, error) {
+	if !message.IsVersioned() || len(message.AddressTableLookups) == 0 {
+		return nil, nil
+	}
+
+	numStatic := len(message.AccountKeys)
+	numWritable := message.NumWritableLookups()
+	writableOffset, readonlyOffset := 0, 0
+	var readonlyMatch *loadedAccount
+	for _, lookup := range message.AddressTableLookups {
+		table, ok := lookupTables[lookup.AccountKey]
+		if !ok {
+			return nil, ErrLookupTablesRequired
+		}
+
+		for _, tableIndex := range lookup.WritableIndexes {
+			if int(tableIndex) < len(table) && table[tableIndex].Equals(key) {
+				return &loadedAccount{index: uint16(numStatic + writableOffset), writable: true}, nil
+			}
+			writableOffset++
+		}
+		for _, tableIndex := range lookup.ReadonlyIndexes {
+			if readonlyMatch == nil && int(tableIndex) < len(table) && table[tableIndex].Equals(key) {
+				readonlyMatch = &loadedAccount{index: uint16(numStatic + numWritable + readonlyOffset)}
+			}
+			readonlyOffset++
+		}
+	}
+	return readonlyMatch, nil
+}
+
+// addAccountKey inserts the key into the static account keys if it's not already present. Keys that are already
+// present must have at least the requested privileges.
+func addAccountKey(message *solana.Message, key solana.PublicKey, isSigner bool, isWritable bool) error {
+	header := &message.Header
+	numSigners := int(header.NumRequiredSignatures)
+	numWritableSigners := numSigners - int(header.NumReadonlySignedAccounts)
+	numWritableNonSigners := len(message.AccountKeys) - numSigners - int(header.NumReadonlyUnsignedAccounts)
+
+	for i, existing := range message.AccountKeys {
+		if !existing.Equals(key) {
+			continue
+		}
+		existingSigner := i < numSigners
+		existingWritable := i < numWritableSigners || (i >= numSigners && i < numSigners+numWritableNonSigners)
+		if (isSigner && !existingSigner) || (isWritable && !existingWritable) {
+			return fmt.Errorf("account %v is already present in the transaction with fewer privileges", key)
+		}
+		return nil
+	}
+
+	if isSigner {
+		return fmt.Errorf("cannot add new signer %v to a compiled transaction", key)
+	}
+
+	position := len(message.AccountKeys)
+	if isWritable {
+		position = numSigners + numWritableNonSigners
+	} else {
+		header.NumReadonlyUnsignedAccounts++
+	}
+
+	message.AccountKeys = append(message.AccountKeys, solana.PublicKey{})
+	copy(message.AccountKeys[position+1:], message.AccountKeys[position:])
+	message.AccountKeys[position] = key
+
+	shiftAccountIndexes(message, uint16(position))
+	return nil
+}
+
+// shiftAccountIndexes increments every account index in the compiled instructions at or after position. Indexes of
+// accounts loaded from lookup tables follow the static keys, so they are always shifted.
+func shiftAccountIndexes(message *solana.Message, position uint16) {
+	for i := range message.Instructions {
+		instruction := &message.Instructions[i]
+		if instruction.ProgramIDIndex >= position {
+			instruction.ProgramIDIndex++
+		}
+		for j, index := range instruction.Accounts {
+			if index >= position {
+				instruction.Accounts[j] = index + 1
+			}
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package transaction
+
+import (
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendInstruction(t *testing.T) {
+	privateKey, err := solana.NewRandomPrivateKey()
+	require.NoError(t, err)
+	payer := privateKey.PublicKey()
+
+	readonlyAccount := solana.NewWallet().PublicKey()
+	program := solana.NewWallet().PublicKey()
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		solana.NewInstruction(program, solana.AccountMetaSlice{
+			solana.NewAccountMeta(payer, true, true),
+			solana.NewAccountMeta(readonlyAccount, false, false),
+		}, []byte{1}),
+	}, solana.Hash{}, solana.TransactionPayer(payer))
+	require.NoError(t, err)
+
+	recipient := solana.NewWallet().PublicKey()
+	err = AppendInstruction(tx, system.NewTransferInstruction(1000, payer, recipient).Build())
+	require.NoError(t, err)
+
+	require.Equal(t, 2, len(tx.Message.Instructions))
+	require.Equal(t, uint8(3), tx.Message.Header.NumReadonlyUnsignedAccounts)
+
+	// writable recipient is inserted before the readonly accounts
+	writable, err := tx.Message.IsWritable(recipient)
+	require.NoError(t, err)
+	require.True(t, writable)
+
+	// existing instruction still references the same accounts
+	first, err := tx.Message.Instructions[0].ResolveInstructionAccounts(&tx.Message)
+	require.NoError(t, err)
+	require.Equal(t, payer, first[0].PublicKey)
+	require.Equal(t, readonlyAccount, first[1].PublicKey)
+	firstProgram, err := tx.Message.Program(tx.Message.Instructions[0].ProgramIDIndex)
+	require.NoError(t, err)
+	require.Equal(t, program, firstProgram)
+
+	transfer, err := tx.Message.Instructions[1].ResolveInstructionAccounts(&tx.Message)
+	require.NoError(t, err)
+	require.Equal(t, payer, transfer[0].PublicKey)
+	require.Equal(t, recipient, transfer[1].PublicKey)
+	transferProgram, err := tx.Message.Program(tx.Message.Instructions[1].ProgramIDIndex)
+	require.NoError(t, err)
+	require.Equal(t, solana.SystemProgramID, transferProgram)
+}
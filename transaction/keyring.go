@@ -0,0 +1,138 @@
+package transaction
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+var ErrNoLocalSigner = errors.New("none of the transaction's required signers are in the keyring")
+
+// Keyring holds the private keys of several wallets and signs transactions with every key the transaction requires.
+// It is safe for concurrent use, so keys can be added while transactions are being signed.
+type Keyring struct {
+	mu   sync.RWMutex
+	keys map[solana.PublicKey]solana.PrivateKey
+}
+
+// NewKeyring creates a keyring holding the private keys
+func NewKeyring(privateKeys ...solana.PrivateKey) *Keyring {
+	k := &Keyring{keys: make(map[solana.PublicKey]solana.PrivateKey, len(privateKeys))}
+	for _, privateKey := range privateKeys {
+		k.Add(privateKey)
+	}
+	return k
+}
+
+// Add adds a private key to the keyring, replacing any existing key for the same wallet
+func (k *Keyring) Add(privateKey solana.PrivateKey) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.keys[privateKey.PublicKey()] = privateKey
+}
+
+// Clone returns a new keyring holding the same private keys
+func (k *Keyring) Clone() *Keyring {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	clone := &Keyring{keys: make(map[solana.PublicKey]solana.PrivateKey, len(k.keys))}
+	for publicKey, privateKey := range k.keys {
+		clone.keys[publicKey] = privateKey
+	}
+	return clone
+}
+
+// Remove removes the private key of a wallet from the keyring
+func (k *Keyring) Remove(publicKey solana.PublicKey) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	delete(k.keys, publicKey)
+}
+
+// Get returns the private key of a wallet
+func (k *Keyring) Get(publicKey solana.PublicKey) (solana.PrivateKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	privateKey, ok := k.keys[publicKey]
+	return privateKey, ok
+}
+
+// PublicKeys returns the wallets of the keyring in no particular order
+func (k *Keyring) PublicKeys() []solana.PublicKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	publicKeys := make([]solana.PublicKey, 0, len(k.keys))
+	for publicKey := range k.keys {
+		publicKeys = append(publicKeys, publicKey)
+	}
+	return publicKeys
+}
+
+// Len returns the number of keys in the keyring
+func (k *Keyring) Len() int {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return len(k.keys)
+}
+
+// SignTxWithKeyring signs the transaction with every required signer that has a key in the keyring. Signatures of
+// other signers are kept, so partially signed transactions can be completed.
+func SignTxWithKeyring(unsignedTxBase64 string, keyring *Keyring) (string, error) {
+	tx, err := DecodeTransaction(unsignedTxBase64)
+	if err != nil {
+		return "", err
+	}
+
+	err = signTxWithKeyring(tx, keyring)
+	if err != nil {
+		return "", err
+	}
+	return tx.ToBase64()
+}
+
+func signTxWithKeyring(tx *solana.Transaction, keyring *Keyring) error {
+	signaturesRequired := int(tx.Message.Header.NumRequiredSignatures)
+	if signaturesRequired > len(tx.Message.AccountKeys) {
+		return fmt.Errorf("transaction requires %v signatures but has %v accounts", signaturesRequired, len(tx.Message.AccountKeys))
+	}
+	if len(tx.Signatures) > signaturesRequired {
+		return fmt.Errorf("transaction requires %v signatures and has %v signatures", signaturesRequired, len(tx.Signatures))
+	}
+
+	messageContent, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("unable to encode message for signing: %w", err)
+	}
+
+	for len(tx.Signatures) < signaturesRequired {
+		tx.Signatures = append(tx.Signatures, solana.Signature{})
+	}
+
+	signed := 0
+	for i, signer := range tx.Message.AccountKeys[:signaturesRequired] {
+		privateKey, ok := keyring.Get(signer)
+		if !ok {
+			continue
+		}
+
+		signature, err := privateKey.Sign(messageContent)
+		if err != nil {
+			return fmt.Errorf("unable to sign message: %v", err)
+		}
+		tx.Signatures[i] = signature
+		signed++
+	}
+
+	if signed == 0 {
+		return ErrNoLocalSigner
+	}
+	return nil
+}
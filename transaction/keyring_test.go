@@ -0,0 +1,53 @@
+package transaction
+
+import (
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignTxWithKeyring(t *testing.T) {
+	payer := solana.NewWallet().PrivateKey
+	sender := solana.NewWallet().PrivateKey
+	remote := solana.NewWallet().PrivateKey
+
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		system.NewTransferInstruction(1000, sender.PublicKey(), solana.NewWallet().PublicKey()).Build(),
+		system.NewTransferInstruction(1000, remote.PublicKey(), solana.NewWallet().PublicKey()).Build(),
+	}, solana.Hash{1}, solana.TransactionPayer(payer.PublicKey()))
+	require.NoError(t, err)
+	require.Equal(t, uint8(3), tx.Message.Header.NumRequiredSignatures)
+
+	// keys unrelated to the transaction can't sign it
+	_, err = SignTxWithKeyring(tx.MustToBase64(), NewKeyring(solana.NewWallet().PrivateKey))
+	require.ErrorIs(t, err, ErrNoLocalSigner)
+
+	signedBase64, err := SignTxWithKeyring(tx.MustToBase64(), NewKeyring(payer, sender))
+	require.NoError(t, err)
+
+	// the remote signer completes the transaction without touching the local signatures
+	signedBase64, err = SignTxWithKeyring(signedBase64, NewKeyring(remote))
+	require.NoError(t, err)
+
+	signed, err := DecodeTransaction(signedBase64)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(signed.Signatures))
+	require.NoError(t, signed.VerifySignatures())
+}
+
+func TestKeyringClone(t *testing.T) {
+	first := solana.NewWallet().PrivateKey
+	second := solana.NewWallet().PrivateKey
+
+	keyring := NewKeyring(first)
+	clone := keyring.Clone()
+	clone.Add(second)
+
+	// keys added to the clone don't change the original
+	require.Equal(t, 1, keyring.Len())
+	require.Equal(t, 2, clone.Len())
+	_, ok := clone.Get(first.PublicKey())
+	require.True(t, ok)
+}
@@ -5,7 +5,6 @@ import (
 	"encoding/base64"
 	"fmt"
 	"github.com/gagliardetto/solana-go"
-	solanarpc "github.com/gagliardetto/solana-go/rpc"
 )
 
 const BxMemoMarkerMsg = "Powered by bloXroute Trader Api"
@@ -30,56 +29,53 @@ func CreateTraderAPIMemoInstruction(msg string) solana.Instruction {
 	return instruction
 }
 
-func addMemo(tx *solana.Transaction) error {
-	memoInstruction := CreateTraderAPIMemoInstruction("")
-	memoData, err := memoInstruction.Data()
-	if err != nil {
-		return err
-	}
-
-	cutoff := uint16(len(tx.Message.AccountKeys))
+// HasTraderAPIMemo reports whether the transaction already contains a Trader API memo instruction
+func HasTraderAPIMemo(tx *solana.Transaction) bool {
 	for _, instruction := range tx.Message.Instructions {
-		for i, accountIdx := range instruction.Accounts {
-			if accountIdx >= cutoff {
-				instruction.Accounts[i] = accountIdx + 1
-			}
+		if int(instruction.ProgramIDIndex) < len(tx.Message.AccountKeys) &&
+			tx.Message.AccountKeys[instruction.ProgramIDIndex].Equals(TraderAPIMemoProgram) {
+			return true
 		}
 	}
+	return false
+}
 
-	tx.Message.AccountKeys = append(tx.Message.AccountKeys, memoInstruction.ProgramID())
-	tx.Message.Instructions = append(tx.Message.Instructions, solana.CompiledInstruction{
-		ProgramIDIndex: cutoff,
-		Accounts:       nil,
-		Data:           memoData,
-	})
+// AddMemo appends a Trader API memo instruction with the provided text to a legacy or v0 transaction. If the
+// transaction already has a Trader API memo it is left unchanged and false is returned.
+func AddMemo(tx *solana.Transaction, msg string) (bool, error) {
+	if HasTraderAPIMemo(tx) {
+		return false, nil
+	}
 
-	return nil
+	// the memo has no accounts, so lookup tables are never needed to compile it
+	err := AppendInstruction(tx, CreateTraderAPIMemoInstruction(msg))
+	if err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
 // AddMemoAndSign adds memo instruction to a serialized transaction, it's primarily used if the user
-// doesn't want to interact with Trader-API directly
+// doesn't want to interact with Trader-API directly. A transaction that already has a Trader API memo is signed
+// without adding another one; previously this returned an error.
 func AddMemoAndSign(txBase64 string, privateKey solana.PrivateKey) (string, error) {
-	signedTxBytes, err := solanarpc.DataBytesOrJSONFromBase64(txBase64)
+	return AddMemoWithTextAndSign(txBase64, "", privateKey)
+}
+
+// AddMemoWithTextAndSign adds a memo instruction with custom text to a serialized legacy or v0 transaction, unless it
+// already has one, and signs it. Adding the memo invalidates existing signatures, so it fails if the transaction was
+// already signed by other keys.
+func AddMemoWithTextAndSign(txBase64 string, msg string, privateKey solana.PrivateKey) (string, error) {
+	solanaTx, err := DecodeTransaction(txBase64)
 	if err != nil {
 		return "", err
 	}
-	unsignedTx := solanarpc.TransactionWithMeta{Transaction: signedTxBytes}
-	solanaTx, err := unsignedTx.GetTransaction()
+
+	added, err := AddMemo(solanaTx, msg)
 	if err != nil {
 		return "", err
 	}
-
-	if len(solanaTx.Message.AccountKeys) >= 32 {
-		return "", fmt.Errorf("transaction has too many account keys")
-	}
-
-	for _, key := range solanaTx.Message.AccountKeys {
-		if key == TraderAPIMemoProgram {
-			return "", fmt.Errorf("transaction already has bloXroute memo instruction")
-		}
-	}
-
-	err = addMemo(solanaTx)
+	err = clearSignature(solanaTx, privateKey.PublicKey(), added)
 	if err != nil {
 		return "", err
 	}
@@ -95,5 +91,22 @@ func AddMemoAndSign(txBase64 string, privateKey solana.PrivateKey) (string, erro
 	}
 
 	return base64.StdEncoding.EncodeToString(txnBytes), nil
+}
 
+// clearSignature zeroes the signer's signature so it can be signed again. If the message changed, signatures of other
+// signers are no longer valid and it fails instead.
+func clearSignature(tx *solana.Transaction, signer solana.PublicKey, messageChanged bool) error {
+	for i, signature := range tx.Signatures {
+		if i < len(tx.Message.AccountKeys) && tx.Message.AccountKeys[i].Equals(signer) {
+			tx.Signatures[i] = solana.Signature{}
+			continue
+		}
+		if messageChanged && !signature.IsZero() {
+			if i >= len(tx.Message.AccountKeys) {
+				return fmt.Errorf("adding memo invalidates existing signature %v", i)
+			}
+			return fmt.Errorf("adding memo invalidates existing signature of %v", tx.Message.AccountKeys[i])
+		}
+	}
+	return nil
 }
@@ -57,3 +57,68 @@ func TestAddMemoToSerializedTxn(t *testing.T) {
 	require.Equal(t, TraderAPIMemoProgram, program)
 
 }
+
+func TestAddMemoToVersionedTxn(t *testing.T) {
+	privateKey, err := solana.NewRandomPrivateKey()
+	require.NoError(t, err)
+	payer := privateKey.PublicKey()
+
+	// enough static accounts to exceed the previous limit, plus accounts loaded from a lookup table
+	var accounts solana.AccountMetaSlice
+	accounts = append(accounts, solana.NewAccountMeta(payer, true, true))
+	for i := 0; i < 35; i++ {
+		accounts = append(accounts, solana.NewAccountMeta(solana.NewWallet().PublicKey(), i%2 == 0, false))
+	}
+	table := solana.NewWallet().PublicKey()
+	tableAccounts := solana.PublicKeySlice{solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()}
+	accounts = append(accounts, solana.NewAccountMeta(tableAccounts[0], true, false), solana.NewAccountMeta(tableAccounts[1], false, false))
+	lookupTables := map[solana.PublicKey]solana.PublicKeySlice{table: tableAccounts}
+
+	program := solana.NewWallet().PublicKey()
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		solana.NewInstruction(program, accounts, []byte{1}),
+	}, solana.Hash{}, solana.TransactionPayer(payer), solana.TransactionAddressTables(lookupTables))
+	require.NoError(t, err)
+	require.True(t, tx.Message.IsVersioned())
+	readonlyUnsigned := tx.Message.Header.NumReadonlyUnsignedAccounts
+
+	encoded, err := AddMemoWithTextAndSign(tx.MustToBase64(), "custom memo", privateKey)
+	require.NoError(t, err)
+
+	// adding the memo again is a no-op
+	encoded, err = AddMemoWithTextAndSign(encoded, "custom memo", privateKey)
+	require.NoError(t, err)
+
+	solanaTx, err := DecodeTransaction(encoded)
+	require.NoError(t, err)
+	require.NoError(t, solanaTx.VerifySignatures())
+	require.Equal(t, 2, len(solanaTx.Message.Instructions))
+	require.Equal(t, readonlyUnsigned+1, solanaTx.Message.Header.NumReadonlyUnsignedAccounts)
+
+	inspected, err := InspectTransaction(solanaTx, lookupTables)
+	require.NoError(t, err)
+	require.Equal(t, TraderAPIMemoProgram, inspected.Instructions[1].ProgramID)
+	require.Equal(t, []byte("custom memo"), inspected.Instructions[1].Data)
+
+	// the original instruction still resolves to the same static and lookup accounts
+	require.Equal(t, program, inspected.Instructions[0].ProgramID)
+	require.Equal(t, len(accounts), len(inspected.Instructions[0].Accounts))
+	for i, account := range accounts {
+		resolved := inspected.Instructions[0].Accounts[i]
+		require.Equal(t, account.PublicKey, resolved.PublicKey)
+		require.Equal(t, account.IsWritable, resolved.IsWritable)
+	}
+}
+
+func TestClearSignatureMalformed(t *testing.T) {
+	signer := solana.NewWallet().PublicKey()
+	tx := &solana.Transaction{
+		Signatures: []solana.Signature{{}, {1}},
+		Message:    solana.Message{AccountKeys: solana.PublicKeySlice{signer}},
+	}
+
+	// signatures without a matching account key are reported instead of indexing past the keys
+	err := clearSignature(tx, signer, true)
+	require.Error(t, err)
+	require.NoError(t, clearSignature(tx, signer, false))
+}
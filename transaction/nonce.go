@@ -0,0 +1,168 @@
+package transaction
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	solanarpc "github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	// NonceAccountSize is the size of a system program nonce account
+	NonceAccountSize = 80
+
+	// NonceAccountRentExemption is the minimum balance in lamports for a nonce account to be rent exempt
+	NonceAccountRentExemption = 1_447_680
+
+	nonceStateInitialized = 1
+)
+
+var ErrNonceAccountNotInitialized = errors.New("nonce account is not initialized")
+
+// NonceAccount is the decoded state of an initialized nonce account
+type NonceAccount struct {
+	Authority            solana.PublicKey
+	Nonce                solana.Hash
+	LamportsPerSignature uint64
+}
+
+// DecodeNonceAccount decodes the data of a system program nonce account
+func DecodeNonceAccount(data []byte) (*NonceAccount, error) {
+	if len(data) < NonceAccountSize {
+		return nil, fmt.Errorf("nonce account data has %v bytes, expected %v", len(data), NonceAccountSize)
+	}
+
+	// layout: version (u32), state (u32), authority (32 bytes), nonce (32 bytes), lamports per signature (u64)
+	if binary.LittleEndian.Uint32(data[4:8]) != nonceStateInitialized {
+		return nil, ErrNonceAccountNotInitialized
+	}
+	return &NonceAccount{
+		Authority:            solana.PublicKeyFromBytes(data[8:40]),
+		Nonce:                solana.HashFromBytes(data[40:72]),
+		LamportsPerSignature: binary.LittleEndian.Uint64(data[72:80]),
+	}, nil
+}
+
+// GetNonceAccount fetches and decodes a nonce account from a Solana RPC node
+func GetNonceAccount(ctx context.Context, client *solanarpc.Client, nonceAccount solana.PublicKey) (*NonceAccount, error) {
+	account, err := client.GetAccountInfoWithOpts(ctx, nonceAccount, &solanarpc.GetAccountInfoOpts{
+		Encoding:   solana.EncodingBase64,
+		Commitment: solanarpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve nonce account %v: %w", nonceAccount, err)
+	}
+	if account == nil || account.Value == nil {
+		return nil, fmt.Errorf("nonce account %v not found", nonceAccount)
+	}
+	if !account.Value.Owner.Equals(solana.SystemProgramID) {
+		return nil, fmt.Errorf("account %v is not owned by the system program", nonceAccount)
+	}
+	return DecodeNonceAccount(account.Value.Data.GetBinary())
+}
+
+// CreateNonceAccountInstructions generates the instructions that create and initialize a nonce account. The nonce
+// account must sign the transaction.
+func CreateNonceAccountInstructions(payer solana.PublicKey, nonceAccount solana.PublicKey, authority solana.PublicKey, lamports uint64) []solana.Instruction {
+	if lamports < NonceAccountRentExemption {
+		lamports = NonceAccountRentExemption
+	}
+	return []solana.Instruction{
+		system.NewCreateAccountInstruction(lamports, NonceAccountSize, solana.SystemProgramID, payer, nonceAccount).Build(),
+		system.NewInitializeNonceAccountInstruction(authority, nonceAccount, solana.SysVarRecentBlockHashesPubkey, solana.SysVarRentPubkey).Build(),
+	}
+}
+
+// CreateAdvanceNonceInstruction generates the instruction that advances a nonce account. It must be the first
+// instruction of a transaction that uses the nonce in place of a recent blockhash.
+func CreateAdvanceNonceInstruction(nonceAccount solana.PublicKey, authority solana.PublicKey) solana.Instruction {
+	return system.NewAdvanceNonceAccountInstruction(nonceAccount, solana.SysVarRecentBlockHashesPubkey, authority).Build()
+}
+
+// NonceStep makes the transaction use a durable nonce. The AdvanceNonce instruction is placed first once all other
+// steps have run, and the nonce must be passed to Build in place of the recent blockhash.
+func NonceStep(nonceAccount solana.PublicKey, authority solana.PublicKey) BuildStep {
+	return func(_ context.Context, b *TxBuilder) error {
+		b.nonceAccount = &nonceAccount
+		b.nonceAuthority = authority
+		return nil
+	}
+}
+
+// placeAdvanceNonce removes any existing AdvanceNonce instruction and inserts one for the builder's nonce account as
+// the first instruction
+func (b *TxBuilder) placeAdvanceNonce() {
+	instructions := make([]solana.Instruction, 0, len(b.instructions)+1)
+	instructions = append(instructions, CreateAdvanceNonceInstruction(*b.nonceAccount, b.nonceAuthority))
+	for _, instruction := range b.instructions {
+		if !isAdvanceNonceInstruction(instruction) {
+			instructions = append(instructions, instruction)
+		}
+	}
+	b.instructions = instructions
+}
+
+func isAdvanceNonceInstruction(instruction solana.Instruction) bool {
+	if !instruction.ProgramID().Equals(solana.SystemProgramID) {
+		return false
+	}
+	data, err := instruction.Data()
+	if err != nil || len(data) < 4 {
+		return false
+	}
+	return binary.LittleEndian.Uint32(data) == system.Instruction_AdvanceNonceAccount
+}
+
+// SetNonce converts a compiled transaction to use a durable nonce: an AdvanceNonce instruction is inserted as the
+// first instruction (unless already present) and the nonce replaces the recent blockhash. The nonce authority must
+// already be a signer of the transaction, and for v0 messages the nonce account must not be loaded from a lookup
+// table. Existing signatures are invalidated.
+func SetNonce(tx *solana.Transaction, nonceAccount solana.PublicKey, authority solana.PublicKey, nonce solana.Hash) error {
+	if !hasAdvanceNonceFirst(&tx.Message, nonceAccount) {
+		compiled, err := compileInstruction(&tx.Message, CreateAdvanceNonceInstruction(nonceAccount, authority), nil)
+		if err != nil {
+			return err
+		}
+		tx.Message.Instructions = append([]solana.CompiledInstruction{compiled}, tx.Message.Instructions...)
+	}
+
+	tx.Message.RecentBlockhash = nonce
+	return nil
+}
+
+func hasAdvanceNonceFirst(message *solana.Message, nonceAccount solana.PublicKey) bool {
+	if len(message.Instructions) == 0 {
+		return false
+	}
+	first := message.Instructions[0]
+	if int(first.ProgramIDIndex) >= len(message.AccountKeys) || !message.AccountKeys[first.ProgramIDIndex].Equals(solana.SystemProgramID) {
+		return false
+	}
+	if len(first.Data) < 4 || binary.LittleEndian.Uint32(first.Data) != system.Instruction_AdvanceNonceAccount {
+		return false
+	}
+	return len(first.Accounts) != 0 && int(first.Accounts[0]) < len(message.AccountKeys) &&
+		message.AccountKeys[first.Accounts[0]].Equals(nonceAccount)
+}
+
+// usesDurableNonce returns true if the first instruction of the message advances a nonce account
+func usesDurableNonce(message *solana.Message) bool {
+	if len(message.Instructions) == 0 || len(message.Instructions[0].Accounts) == 0 {
+		return false
+	}
+	nonceIndex := message.Instructions[0].Accounts[0]
+	return int(nonceIndex) < len(message.AccountKeys) && hasAdvanceNonceFirst(message, message.AccountKeys[nonceIndex])
+}
+
+// PartialSignWithNonce sets a durable nonce on the transaction with SetNonce, then partially signs it like PartialSign.
+// The owner is the nonce authority.
+func PartialSignWithNonce(tx *solana.Transaction, ownerPk solana.PublicKey, privateKeys map[solana.PublicKey]solana.PrivateKey, nonceAccount solana.PublicKey, nonce solana.Hash) error {
+	if err := SetNonce(tx, nonceAccount, ownerPk, nonce); err != nil {
+		return err
+	}
+	return PartialSign(tx, ownerPk, privateKeys)
+}
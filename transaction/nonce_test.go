@@ -0,0 +1,61 @@
+package transaction
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNonceStep(t *testing.T) {
+	payer := solana.NewWallet().PublicKey()
+	nonceAccount := solana.NewWallet().PublicKey()
+	nonce := solana.Hash{1}
+
+	tx, err := NewTxBuilder(payer).
+		AddInstructions(system.NewTransferInstruction(1000, payer, solana.NewWallet().PublicKey()).Build()).
+		AddStep(NonceStep(nonceAccount, payer), ComputeBudgetStep(200_000, 1000)).
+		Build(context.Background(), nonce)
+	require.NoError(t, err)
+
+	require.Equal(t, nonce, tx.Message.RecentBlockhash)
+	require.True(t, hasAdvanceNonceFirst(&tx.Message, nonceAccount))
+	require.Equal(t, 4, len(tx.Message.Instructions))
+}
+
+func TestPartialSignWithNonce(t *testing.T) {
+	owner := solana.NewWallet().PublicKey()
+	nonceAccount := solana.NewWallet().PublicKey()
+	nonce := solana.Hash{2}
+
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		system.NewTransferInstruction(1000, owner, solana.NewWallet().PublicKey()).Build(),
+	}, solana.Hash{}, solana.TransactionPayer(owner))
+	require.NoError(t, err)
+
+	err = PartialSignWithNonce(tx, owner, map[solana.PublicKey]solana.PrivateKey{}, nonceAccount, nonce)
+	require.NoError(t, err)
+
+	require.Equal(t, nonce, tx.Message.RecentBlockhash)
+	require.True(t, hasAdvanceNonceFirst(&tx.Message, nonceAccount))
+	require.Equal(t, 1, len(tx.Signatures))
+
+	accounts, err := tx.Message.Instructions[0].ResolveInstructionAccounts(&tx.Message)
+	require.NoError(t, err)
+	require.Equal(t, nonceAccount, accounts[0].PublicKey)
+	require.Equal(t, solana.SysVarRecentBlockHashesPubkey, accounts[1].PublicKey)
+	require.Equal(t, owner, accounts[2].PublicKey)
+
+	// the original transfer still resolves after the accounts were shifted
+	transfer, err := tx.Message.Instructions[1].ResolveInstructionAccounts(&tx.Message)
+	require.NoError(t, err)
+	require.Equal(t, owner, transfer[0].PublicKey)
+	require.Equal(t, uint32(system.Instruction_Transfer), binary.LittleEndian.Uint32(tx.Message.Instructions[1].Data))
+
+	// setting the nonce again doesn't add a second AdvanceNonce instruction
+	require.NoError(t, SetNonce(tx, nonceAccount, owner, nonce))
+	require.Equal(t, 2, len(tx.Message.Instructions))
+}
@@ -0,0 +1,268 @@
+package transaction
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+const (
+	// OfflineFileVersion is the version of the offline file format written by this package
+	OfflineFileVersion = 1
+
+	// BlockHashValidity is a conservative estimate of how long a transaction with a recent blockhash can land,
+	// about 150 blocks
+	BlockHashValidity = 60 * time.Second
+)
+
+var (
+	ErrOfflineFileExpired  = errors.New("offline transactions have expired")
+	ErrOfflineFileUnsigned = errors.New("offline transactions are not signed")
+	ErrOfflineFileTampered = errors.New("offline transaction summary does not match its content")
+)
+
+// OfflineTransaction is a single transaction of an offline file with a readable summary of its contents
+type OfflineTransaction struct {
+	Content   string `json:"content"`
+	IsCleanup bool   `json:"isCleanup,omitempty"`
+	Signed    bool   `json:"signed"`
+
+	FeePayer        string   `json:"feePayer"`
+	RecentBlockHash string   `json:"recentBlockHash"`
+	DurableNonce    bool     `json:"durableNonce,omitempty"`
+	Instructions    []string `json:"instructions"`
+	SOLOutflow      uint64   `json:"solOutflow"`
+}
+
+// OfflineFile is a portable set of transactions that can be signed on another machine and submitted later. Metadata
+// holds a readable description of where the transactions came from, such as the API response that generated them.
+type OfflineFile struct {
+	Version     int             `json:"version"`
+	Description string          `json:"description"`
+	Metadata    json.RawMessage `json:"metadata,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+
+	// ExpiresAt is unset if every transaction uses a durable nonce. Neither it nor CreatedAt is part of the signed
+	// transactions, so Verify only checks that they are consistent with each other; a file whose times were both edited
+	// is not detected.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+
+	Transactions []OfflineTransaction `json:"transactions"`
+}
+
+// NewOfflineFile creates an offline file from base64 transactions. The transactions' blockhashes are assumed to be
+// fetched at createdAt, so they expire BlockHashValidity later unless they use durable nonces.
+func NewOfflineFile(description string, metadata json.RawMessage, createdAt time.Time, transactions []OfflineTransaction) (*OfflineFile, error) {
+	if len(transactions) == 0 {
+		return nil, errors.New("offline file requires at least one transaction")
+	}
+
+	file := &OfflineFile{
+		Version:     OfflineFileVersion,
+		Description: description,
+		Metadata:    metadata,
+		CreatedAt:   createdAt.UTC(),
+	}
+
+	expires := false
+	for i, offlineTx := range transactions {
+		summarized, err := summarizeOfflineTransaction(offlineTx.Content, offlineTx.IsCleanup)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %v: %w", i, err)
+		}
+		if !summarized.DurableNonce {
+			expires = true
+		}
+		file.Transactions = append(file.Transactions, *summarized)
+	}
+
+	if expires {
+		expiresAt := file.CreatedAt.Add(BlockHashValidity)
+		file.ExpiresAt = &expiresAt
+	}
+	return file, nil
+}
+
+func summarizeOfflineTransaction(txBase64 string, isCleanup bool) (*OfflineTransaction, error) {
+	tx, err := DecodeTransaction(txBase64)
+	if err != nil {
+		return nil, err
+	}
+	inspected, err := InspectTransaction(tx, nil)
+	if err != nil {
+		return nil, err
+	}
+	outflow, err := inspected.SOLOutflow()
+	if err != nil {
+		return nil, err
+	}
+
+	summarized := &OfflineTransaction{
+		Content:         txBase64,
+		IsCleanup:       isCleanup,
+		Signed:          isFullySigned(tx),
+		FeePayer:        inspected.FeePayer.String(),
+		RecentBlockHash: tx.Message.RecentBlockhash.String(),
+		DurableNonce:    usesDurableNonce(&tx.Message),
+		SOLOutflow:      outflow,
+	}
+	for _, instruction := range inspected.Instructions {
+		summary := fmt.Sprintf("%v: %v", instruction.Program, instruction.Name)
+		if instruction.Lamports != 0 {
+			summary += fmt.Sprintf(" (%v lamports)", instruction.Lamports)
+		}
+		summarized.Instructions = append(summarized.Instructions, summary)
+	}
+	return summarized, nil
+}
+
+func (t OfflineTransaction) summaryEquals(other OfflineTransaction) bool {
+	return t.Signed == other.Signed &&
+		t.FeePayer == other.FeePayer &&
+		t.RecentBlockHash == other.RecentBlockHash &&
+		t.DurableNonce == other.DurableNonce &&
+		t.SOLOutflow == other.SOLOutflow &&
+		slices.Equal(t.Instructions, other.Instructions)
+}
+
+func isFullySigned(tx *solana.Transaction) bool {
+	if len(tx.Signatures) < int(tx.Message.Header.NumRequiredSignatures) {
+		return false
+	}
+	for _, signature := range tx.Signatures {
+		if signature.IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckExpiry returns ErrOfflineFileExpired if the transactions' blockhashes are no longer valid at the given time
+func (f *OfflineFile) CheckExpiry(now time.Time) error {
+	if f.ExpiresAt != nil && !now.Before(*f.ExpiresAt) {
+		return fmt.Errorf("%w at %v", ErrOfflineFileExpired, f.ExpiresAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Verify recomputes the summary of every transaction from its content and returns ErrOfflineFileTampered if a stored
+// summary differs, so that the summary shown before signing describes what is actually signed
+func (f *OfflineFile) Verify() error {
+	expires := false
+	for i, offlineTx := range f.Transactions {
+		summarized, err := summarizeOfflineTransaction(offlineTx.Content, offlineTx.IsCleanup)
+		if err != nil {
+			return fmt.Errorf("transaction %v: %w", i, err)
+		}
+		if !summarized.summaryEquals(offlineTx) {
+			return fmt.Errorf("%w: transaction %v", ErrOfflineFileTampered, i)
+		}
+		if !summarized.DurableNonce {
+			expires = true
+		}
+	}
+	if expires && f.ExpiresAt == nil {
+		return fmt.Errorf("%w: expiry missing for transactions with recent blockhashes", ErrOfflineFileTampered)
+	}
+	if f.ExpiresAt != nil && !f.ExpiresAt.Equal(f.CreatedAt.Add(BlockHashValidity)) {
+		return fmt.Errorf("%w: expiry %v is not %v after creation", ErrOfflineFileTampered, f.ExpiresAt.Format(time.RFC3339), BlockHashValidity)
+	}
+	return nil
+}
+
+// Signed returns true if every transaction of the file is fully signed
+func (f *OfflineFile) Signed() bool {
+	for _, offlineTx := range f.Transactions {
+		if !offlineTx.Signed {
+			return false
+		}
+	}
+	return true
+}
+
+// Sign signs every transaction of the file that requires the private key, verifying each against the policy if
+// provided. Transactions that are still waiting on other signers are left partially signed, and those that don't
+// require the key are left as they are. ErrNoLocalSigner is returned if no unsigned transaction requires the key.
+func (f *OfflineFile) Sign(privateKey solana.PrivateKey, policy *Policy, now time.Time) error {
+	if err := f.CheckExpiry(now); err != nil {
+		return err
+	}
+
+	keyring := NewKeyring(privateKey)
+	unsigned, signed := 0, 0
+	for i := range f.Transactions {
+		offlineTx := &f.Transactions[i]
+
+		// the stored flag may not match the content, so the transaction itself decides whether it needs signing
+		tx, err := DecodeTransaction(offlineTx.Content)
+		if err != nil {
+			return fmt.Errorf("transaction %v: %w", i, err)
+		}
+		if isFullySigned(tx) {
+			offlineTx.Signed = true
+			continue
+		}
+
+		unsigned++
+
+		var signedTxBase64 string
+		if policy != nil {
+			signedTxBase64, err = SignTxWithKeyringAndPolicy(offlineTx.Content, keyring, *policy, nil)
+		} else {
+			signedTxBase64, err = SignTxWithKeyring(offlineTx.Content, keyring)
+		}
+		if errors.Is(err, ErrNoLocalSigner) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("transaction %v: %w", i, err)
+		}
+		signed++
+
+		tx, err = DecodeTransaction(signedTxBase64)
+		if err != nil {
+			return fmt.Errorf("transaction %v: %w", i, err)
+		}
+		offlineTx.Content = signedTxBase64
+		offlineTx.Signed = isFullySigned(tx)
+	}
+
+	if unsigned > 0 && signed == 0 {
+		return ErrNoLocalSigner
+	}
+	return nil
+}
+
+// ReadOfflineFile reads an offline file from disk, rejecting it if its summaries don't match its transactions
+func ReadOfflineFile(path string) (*OfflineFile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file OfflineFile
+	if err := json.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("could not parse offline file %v: %w", path, err)
+	}
+	if file.Version != OfflineFileVersion {
+		return nil, fmt.Errorf("unsupported offline file version %v", file.Version)
+	}
+	if err := file.Verify(); err != nil {
+		return nil, fmt.Errorf("offline file %v: %w", path, err)
+	}
+	return &file, nil
+}
+
+// WriteOfflineFile writes an offline file to disk in a readable format
+func WriteOfflineFile(path string, file *OfflineFile) error {
+	b, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0600)
+}
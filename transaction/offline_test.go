@@ -0,0 +1,120 @@
+package transaction
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOfflineFile(t *testing.T) {
+	privateKey, err := solana.NewRandomPrivateKey()
+	require.NoError(t, err)
+	owner := privateKey.PublicKey()
+
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		system.NewTransferInstruction(1000, owner, solana.NewWallet().PublicKey()).Build(),
+	}, solana.Hash{1}, solana.TransactionPayer(owner))
+	require.NoError(t, err)
+
+	createdAt := time.Now()
+	file, err := NewOfflineFile("transfer", nil, createdAt, []OfflineTransaction{{Content: tx.MustToBase64()}})
+	require.NoError(t, err)
+	require.False(t, file.Signed())
+	require.NotNil(t, file.ExpiresAt)
+	require.Equal(t, uint64(1000), file.Transactions[0].SOLOutflow)
+
+	path := filepath.Join(t.TempDir(), "offline.json")
+	require.NoError(t, WriteOfflineFile(path, file))
+	file, err = ReadOfflineFile(path)
+	require.NoError(t, err)
+
+	require.NoError(t, file.Sign(privateKey, nil, createdAt))
+	require.True(t, file.Signed())
+
+	signed, err := DecodeTransaction(file.Transactions[0].Content)
+	require.NoError(t, err)
+	require.NoError(t, signed.VerifySignatures())
+
+	// signing or submitting after the blockhash expired fails
+	err = file.CheckExpiry(createdAt.Add(BlockHashValidity))
+	require.True(t, errors.Is(err, ErrOfflineFileExpired))
+}
+
+func TestOfflineFileTampered(t *testing.T) {
+	privateKey, err := solana.NewRandomPrivateKey()
+	require.NoError(t, err)
+	owner := privateKey.PublicKey()
+
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		system.NewTransferInstruction(5_000_000_000, owner, solana.NewWallet().PublicKey()).Build(),
+	}, solana.Hash{1}, solana.TransactionPayer(owner))
+	require.NoError(t, err)
+
+	createdAt := time.Now()
+	tamper := func(edit func(file *OfflineFile)) error {
+		file, err := NewOfflineFile("transfer", nil, createdAt, []OfflineTransaction{{Content: tx.MustToBase64()}})
+		require.NoError(t, err)
+		edit(file)
+
+		path := filepath.Join(t.TempDir(), "offline.json")
+		require.NoError(t, WriteOfflineFile(path, file))
+		_, err = ReadOfflineFile(path)
+		return err
+	}
+
+	require.NoError(t, tamper(func(file *OfflineFile) {}))
+	require.ErrorIs(t, tamper(func(file *OfflineFile) { file.Transactions[0].SOLOutflow = 1000 }), ErrOfflineFileTampered)
+	require.ErrorIs(t, tamper(func(file *OfflineFile) { file.Transactions[0].FeePayer = solana.NewWallet().PublicKey().String() }), ErrOfflineFileTampered)
+	require.ErrorIs(t, tamper(func(file *OfflineFile) { file.Transactions[0].Instructions = []string{"Memo: Memo"} }), ErrOfflineFileTampered)
+	require.ErrorIs(t, tamper(func(file *OfflineFile) { file.Transactions[0].Signed = true }), ErrOfflineFileTampered)
+	require.ErrorIs(t, tamper(func(file *OfflineFile) { file.ExpiresAt = nil }), ErrOfflineFileTampered)
+	require.ErrorIs(t, tamper(func(file *OfflineFile) {
+		expiresAt := file.ExpiresAt.Add(time.Hour)
+		file.ExpiresAt = &expiresAt
+	}), ErrOfflineFileTampered)
+
+	// signing ignores a stored flag claiming the transaction is already signed
+	file, err := NewOfflineFile("transfer", nil, createdAt, []OfflineTransaction{{Content: tx.MustToBase64()}})
+	require.NoError(t, err)
+	file.Transactions[0].Signed = true
+	require.NoError(t, file.Sign(privateKey, nil, createdAt))
+	signed, err := DecodeTransaction(file.Transactions[0].Content)
+	require.NoError(t, err)
+	require.NoError(t, signed.VerifySignatures())
+}
+
+func TestOfflineFileMultipleSigners(t *testing.T) {
+	payer, err := solana.NewRandomPrivateKey()
+	require.NoError(t, err)
+	sender, err := solana.NewRandomPrivateKey()
+	require.NoError(t, err)
+
+	// the payer pays the fee for a transfer from the sender, so both have to sign
+	tx, err := solana.NewTransaction([]solana.Instruction{
+		system.NewTransferInstruction(1000, sender.PublicKey(), solana.NewWallet().PublicKey()).Build(),
+	}, solana.Hash{1}, solana.TransactionPayer(payer.PublicKey()))
+	require.NoError(t, err)
+	require.Equal(t, uint8(2), tx.Message.Header.NumRequiredSignatures)
+
+	createdAt := time.Now()
+	file, err := NewOfflineFile("transfer", nil, createdAt, []OfflineTransaction{{Content: tx.MustToBase64()}})
+	require.NoError(t, err)
+
+	require.ErrorIs(t, file.Sign(solana.NewWallet().PrivateKey, nil, createdAt), ErrNoLocalSigner)
+
+	require.NoError(t, file.Sign(payer, nil, createdAt))
+	require.False(t, file.Signed())
+	require.NoError(t, file.Verify())
+
+	require.NoError(t, file.Sign(sender, nil, createdAt))
+	require.True(t, file.Signed())
+	require.NoError(t, file.Verify())
+	signed, err := DecodeTransaction(file.Transactions[0].Content)
+	require.NoError(t, err)
+	require.NoError(t, signed.VerifySignatures())
+}
@@ -0,0 +1,178 @@
+package transaction
+
+import (
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/token"
+)
+
+// PolicyViolationError is returned when a transaction does not satisfy a signing policy
+type PolicyViolationError struct {
+	// Rule names the violated policy field, e.g. "AllowedPrograms"
+	Rule string
+
+	// Instruction is the index of the offending instruction, or -1 if the violation concerns the whole transaction
+	Instruction int
+
+	Reason string
+}
+
+func (e *PolicyViolationError) Error() string {
+	if e.Instruction < 0 {
+		return fmt.Sprintf("transaction violates signing policy %v: %v", e.Rule, e.Reason)
+	}
+	return fmt.Sprintf("transaction violates signing policy %v at instruction %v: %v", e.Rule, e.Instruction, e.Reason)
+}
+
+// Policy restricts which transactions may be signed. The zero value allows everything except SetAuthority and
+// CloseAccount instructions that send rent to an unexpected account.
+type Policy struct {
+	// AllowedPrograms restricts the programs the transaction may invoke. Empty allows any program.
+	AllowedPrograms []solana.PublicKey
+
+	// FeePayer is the expected fee payer, if set
+	FeePayer *solana.PublicKey
+
+	// Owner is the expected owner of the transaction, if set. It must be a signer, and CloseAccount instructions may
+	// only return rent to the owner or the fee payer.
+	Owner *solana.PublicKey
+
+	// MaxSOLOutflow caps the lamports the signers move through the system program (transfers and account creation), if
+	// set. See InspectedTransaction.SOLOutflow.
+	MaxSOLOutflow *uint64
+
+	// AllowSetAuthority permits token SetAuthority instructions
+	AllowSetAuthority bool
+
+	// AllowCloseAccount permits token CloseAccount instructions that return rent to any account
+	AllowCloseAccount bool
+}
+
+// Check verifies an inspected transaction against the policy, returning a *PolicyViolationError on failure
+func (p Policy) Check(tx *InspectedTransaction) error {
+	if p.FeePayer != nil && !tx.FeePayer.Equals(*p.FeePayer) {
+		return violation("FeePayer", -1, "fee payer is %v, expected %v", tx.FeePayer, *p.FeePayer)
+	}
+	if p.Owner != nil && !containsKey(tx.Signers, *p.Owner) {
+		return violation("Owner", -1, "owner %v is not a signer", *p.Owner)
+	}
+
+	for _, instruction := range tx.Instructions {
+		if len(p.AllowedPrograms) != 0 && !containsKey(p.AllowedPrograms, instruction.ProgramID) {
+			return violation("AllowedPrograms", instruction.Index, "program %v is not allowed", instruction.Program)
+		}
+
+		if instruction.ProgramID != solana.TokenProgramID && instruction.ProgramID != Token2022ProgramID {
+			continue
+		}
+		if len(instruction.Data) == 0 {
+			continue
+		}
+		switch instruction.Data[0] {
+		case token.Instruction_SetAuthority:
+			if !p.AllowSetAuthority {
+				return violation("AllowSetAuthority", instruction.Index, "unexpected SetAuthority instruction")
+			}
+		case token.Instruction_CloseAccount:
+			if p.AllowCloseAccount {
+				continue
+			}
+			if len(instruction.Accounts) < 2 {
+				return violation("AllowCloseAccount", instruction.Index, "malformed CloseAccount instruction")
+			}
+			destination := instruction.Accounts[1].PublicKey
+			if !p.expectedRecipient(tx, destination) {
+				return violation("AllowCloseAccount", instruction.Index, "CloseAccount returns rent to unexpected account %v", destination)
+			}
+		}
+	}
+
+	if p.MaxSOLOutflow != nil {
+		outflow, err := tx.SOLOutflow()
+		if err != nil {
+			return violation("MaxSOLOutflow", -1, "%v", err)
+		}
+		if outflow > *p.MaxSOLOutflow {
+			return violation("MaxSOLOutflow", -1, "transaction moves %v lamports, maximum is %v", outflow, *p.MaxSOLOutflow)
+		}
+	}
+	return nil
+}
+
+func (p Policy) expectedRecipient(tx *InspectedTransaction, account solana.PublicKey) bool {
+	if p.Owner != nil && account.Equals(*p.Owner) {
+		return true
+	}
+	return account.Equals(tx.FeePayer)
+}
+
+// CheckPolicy decodes and inspects a base64 transaction and verifies it against the policy
+func CheckPolicy(txBase64 string, policy Policy, lookupTables map[solana.PublicKey]solana.PublicKeySlice) error {
+	inspected, err := InspectTransactionBase64(txBase64, lookupTables)
+	if err != nil {
+		return err
+	}
+	return policy.Check(inspected)
+}
+
+// SignTxWithPolicy signs the transaction like SignTxWithPrivateKey, but only if it satisfies the policy
+func SignTxWithPolicy(unsignedTxBase64 string, privateKey solana.PrivateKey, policy Policy, lookupTables map[solana.PublicKey]solana.PublicKeySlice) (string, error) {
+	tx, err := DecodeTransaction(unsignedTxBase64)
+	if err != nil {
+		return "", err
+	}
+
+	inspected, err := InspectTransaction(tx, lookupTables)
+	if err != nil {
+		return "", err
+	}
+	if err := policy.Check(inspected); err != nil {
+		return "", err
+	}
+
+	err = signTx(tx, privateKey)
+	if err != nil {
+		return "", err
+	}
+	return tx.ToBase64()
+}
+
+// SignTxWithKeyringAndPolicy signs the transaction like SignTxWithKeyring, but only if it satisfies the policy
+func SignTxWithKeyringAndPolicy(unsignedTxBase64 string, keyring *Keyring, policy Policy, lookupTables map[solana.PublicKey]solana.PublicKeySlice) (string, error) {
+	tx, err := DecodeTransaction(unsignedTxBase64)
+	if err != nil {
+		return "", err
+	}
+
+	inspected, err := InspectTransaction(tx, lookupTables)
+	if err != nil {
+		return "", err
+	}
+	if err := policy.Check(inspected); err != nil {
+		return "", err
+	}
+
+	err = signTxWithKeyring(tx, keyring)
+	if err != nil {
+		return "", err
+	}
+	return tx.ToBase64()
+}
+
+func violation(rule string, instruction int, format string, args ...interface{}) error {
+	return &PolicyViolationError{
+		Rule:        rule,
+		Instruction: instruction,
+		Reason:      fmt.Sprintf(format, args...),
+	}
+}
+
+func containsKey(keys []solana.PublicKey, key solana.PublicKey) bool {
+	for _, k := range keys {
+		if k.Equals(key) {
+			return true
+		}
+	}
+	return false
+}
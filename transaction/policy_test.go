@@ -0,0 +1,99 @@
+package transaction
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/programs/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignTxWithPolicy(t *testing.T) {
+	privateKey, err := solana.NewRandomPrivateKey()
+	require.NoError(t, err)
+	owner := privateKey.PublicKey()
+
+	tokenAccount := solana.NewWallet().PublicKey()
+	attacker := solana.NewWallet().PublicKey()
+	table := solana.NewWallet().PublicKey()
+	lookupTables := map[solana.PublicKey]solana.PublicKeySlice{
+		table: {tokenAccount, attacker},
+	}
+
+	buildTx := func(instructions ...solana.Instruction) string {
+		tx, err := solana.NewTransaction(instructions, solana.Hash{}, solana.TransactionPayer(owner), solana.TransactionAddressTables(lookupTables))
+		require.NoError(t, err)
+		tx.Signatures = []solana.Signature{{}}
+		txBase64, err := tx.ToBase64()
+		require.NoError(t, err)
+		return txBase64
+	}
+	transfer := system.NewTransferInstruction(1000, owner, attacker).Build()
+	closeAccount := token.NewCloseAccountInstruction(tokenAccount, attacker, owner, nil).Build()
+
+	maxOutflow := uint64(500)
+	policy := Policy{
+		AllowedPrograms: []solana.PublicKey{solana.SystemProgramID, solana.TokenProgramID},
+		FeePayer:        &owner,
+		Owner:           &owner,
+	}
+
+	// accounts loaded from lookup tables are resolved
+	inspected, err := InspectTransactionBase64(buildTx(transfer), lookupTables)
+	require.NoError(t, err)
+	require.False(t, inspected.UnresolvedLookups)
+	require.Equal(t, "Transfer", inspected.Instructions[0].Name)
+	require.Equal(t, attacker, inspected.Instructions[0].Accounts[1].PublicKey)
+	outflow, err := inspected.SOLOutflow()
+	require.NoError(t, err)
+	require.Equal(t, uint64(1000), outflow)
+
+	// transfers funded by accounts other than the signers aren't outflow, unless the funding account can't be resolved
+	transferData, err := transfer.Data()
+	require.NoError(t, err)
+	foreignTransfer := buildTx(solana.NewInstruction(solana.SystemProgramID, solana.AccountMetaSlice{
+		solana.Meta(tokenAccount).WRITE(),
+		solana.Meta(attacker).WRITE(),
+	}, transferData))
+	inspected, err = InspectTransactionBase64(foreignTransfer, lookupTables)
+	require.NoError(t, err)
+	outflow, err = inspected.SOLOutflow()
+	require.NoError(t, err)
+	require.Zero(t, outflow)
+	inspected, err = InspectTransactionBase64(foreignTransfer, nil)
+	require.NoError(t, err)
+	require.True(t, inspected.UnresolvedLookups)
+	_, err = inspected.SOLOutflow()
+	require.Error(t, err)
+
+	signed, err := SignTxWithPolicy(buildTx(transfer), privateKey, policy, lookupTables)
+	require.NoError(t, err)
+	signedTx, err := DecodeTransaction(signed)
+	require.NoError(t, err)
+	require.NoError(t, signedTx.VerifySignatures())
+
+	testCases := []struct {
+		name        string
+		policy      Policy
+		instruction solana.Instruction
+		rule        string
+	}{
+		{"outflow", Policy{MaxSOLOutflow: &maxOutflow}, transfer, "MaxSOLOutflow"},
+		{"program", Policy{AllowedPrograms: []solana.PublicKey{solana.TokenProgramID}}, transfer, "AllowedPrograms"},
+		{"close account", policy, closeAccount, "AllowCloseAccount"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := SignTxWithPolicy(buildTx(tc.instruction), privateKey, tc.policy, lookupTables)
+			var violation *PolicyViolationError
+			require.True(t, errors.As(err, &violation))
+			require.Equal(t, tc.rule, violation.Rule)
+		})
+	}
+
+	policy.AllowCloseAccount = true
+	_, err = SignTxWithPolicy(buildTx(closeAccount), privateKey, policy, lookupTables)
+	require.NoError(t, err)
+}
@@ -14,10 +14,8 @@ const (
 // CreateBloxrouteTipTransactionToUseBundles creates a transaction you can use to when using PostSubmitBundle endpoints.
 // This transaction should be the LAST transaction in your submission bundle
 func CreateBloxrouteTipTransactionToUseBundles(privateKey solana.PrivateKey, tipAmount uint64, recentBlockHash solana.Hash) (*solana.Transaction, error) {
-	recipient := solana.MustPublicKeyFromBase58(BloxrouteTipAddress)
-
 	tx, err := solana.NewTransaction([]solana.Instruction{
-		system.NewTransferInstruction(tipAmount, privateKey.PublicKey(), recipient).Build()}, recentBlockHash)
+		CreateBloxrouteTipInstruction(privateKey.PublicKey(), tipAmount)}, recentBlockHash)
 	if err != nil {
 		return nil, err
 	}
@@ -36,3 +34,10 @@ func CreateBloxrouteTipTransactionToUseBundles(privateKey solana.PrivateKey, tip
 
 	return tx, nil
 }
+
+// CreateBloxrouteTipInstruction creates a transfer instruction paying the bloXroute tip. It can be added to the LAST
+// transaction of a bundle instead of submitting a separate tip transaction.
+func CreateBloxrouteTipInstruction(from solana.PublicKey, tipAmount uint64) solana.Instruction {
+	recipient := solana.MustPublicKeyFromBase58(BloxrouteTipAddress)
+	return system.NewTransferInstruction(tipAmount, from, recipient).Build()
+}